@@ -1,12 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/thomas-maurice/api/go-vue/pkg/cmd"
 )
 
 func main() {
-	err := cmd.Execute()
-	if err != nil {
-		panic(err)
+	// cobra already printed the error
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
 	}
 }
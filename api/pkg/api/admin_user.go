@@ -1,7 +1,6 @@
 package api
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,15 +29,16 @@ type UserListAdmin struct {
 //	@Tags			Admin
 //	@Produce		json
 //	@Success		200	{object}	[]UserListAdmin
-//	@Failure		400	{object}	Error
-//	@Failure		500	{object}	Error
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		jwt
 //	@Security		apikey
 //	@Router			/admin/users [get]
 func (a *Api) AdminListUsers(ctx *gin.Context) {
-	users, err := a.UserService.ListUsers()
+	users, err := a.UserService.ListUsers(ctx.Request.Context())
 	if err != nil {
-		ctx.AbortWithStatusJSON(500, gin.H{"error": fmt.Sprintf("failed to list users: %s", err)})
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -61,20 +61,24 @@ func (a *Api) AdminListUsers(ctx *gin.Context) {
 //	@Produce		json
 //	@Param			id	path		string	true	"Id of the user"
 //	@Success		200	{object}	UserAdmin
-//	@Failure		400	{object}	Error
-//	@Failure		500	{object}	Error
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		jwt
 //	@Security		apikey
 //	@Router			/admin/user/{id} [get]
 func (a *Api) AdminGetUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": "no id provided"})
+		abortWithError(ctx, errBadRequest("no id provided", nil))
+		return
 	}
 
-	u, err := a.UserService.GetUserById(id)
+	u, err := a.UserService.GetUserById(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.AbortWithStatusJSON(500, gin.H{"error": fmt.Sprintf("failed to get user: %s", err)})
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -0,0 +1,32 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestAdminUsers(t *testing.T) {
+	h := apitest.New(t, nil)
+	admin := h.LoginAsAdmin(t)
+	user, u := h.LoginAsUser(t, "alice")
+
+	var users []api.UserListAdmin
+	h.Get(t, "/api/v1/admin/users", admin).AssertJSON(t, http.StatusOK, &users)
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %+v", users)
+	}
+
+	var out api.UserAdmin
+	h.Get(t, "/api/v1/admin/user/"+u.Id, admin).AssertJSON(t, http.StatusOK, &out)
+	if out.Username != "alice" || out.Email != "alice@example.com" || !out.Active || out.Admin {
+		t.Fatalf("unexpected user: %+v", out)
+	}
+
+	h.Get(t, "/api/v1/admin/user/unknown", admin).AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+	h.Get(t, "/api/v1/admin/users", user).AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+	h.Get(t, "/api/v1/admin/user/"+u.Id, user).AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+	h.Get(t, "/api/v1/admin/users", "").AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
@@ -1,30 +1,44 @@
 package api
 
 import (
+	"context"
 	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/pem"
-	"io/fs"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	_ "github.com/thomas-maurice/api/go-vue/docs"
 	"github.com/thomas-maurice/api/go-vue/pkg/config"
-	"github.com/thomas-maurice/api/go-vue/pkg/embeded"
+	"github.com/thomas-maurice/api/go-vue/pkg/deviceauth"
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
+	"github.com/thomas-maurice/api/go-vue/pkg/ratelimit"
+	"github.com/thomas-maurice/api/go-vue/pkg/scheduler"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
 	sqlconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
 	sqluserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql"
 	"github.com/thomas-maurice/api/go-vue/pkg/store"
+	"github.com/thomas-maurice/api/go-vue/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gorm.io/gorm"
 )
 
+// shutdownTimeout is how long the requests in flight and the background
+// workers are given to finish when the server stops
+const shutdownTimeout = 15 * time.Second
+
 type Api struct {
 	Router           *gin.Engine
 	Config           *config.Config
@@ -35,86 +49,208 @@ type Api struct {
 	UserService      userservice.UserService
 	ConfigService    configservice.ConfigService
 	OidcProvider     *oidc.Provider
+	TracerProvider   *sdktrace.TracerProvider
+	HTTPClient       *http.Client
+	Logger           *slog.Logger
+	RateLimitStore   ratelimit.Store
+	DeviceAuthStore  deviceauth.Store
+	// Scheduler runs the maintenance jobs, it is nil when disabled
+	Scheduler *scheduler.Scheduler
+	// SessionCache wraps the UserService when the cache is enabled
+	SessionCache *cacheuserservice.UserService
+	// Clock returns the current time, time.Now unless testing
+	Clock func() time.Time
+	// Console is where the messages meant for the operator only, kept
+	// out of the logs, are printed, os.Stderr unless testing
+	Console io.Writer
+
+	oidcCheck *cachedCheck
 }
 
+// NewAPI creates the API described by the configuration file, with its
+// services backed by the configured database
 func NewAPI(cfgFile string) (*Api, error) {
 	cfg, err := config.LoadFromFile(cfgFile)
 	if err != nil {
 		return nil, err
 	}
 
-	decodedPem, _ := pem.Decode([]byte(cfg.Security.SigninigKey))
-	pKey, err := x509.ParseECPrivateKey(decodedPem.Bytes)
-	if err != nil {
-		return nil, err
+	return New(cfg)
+}
+
+// New creates the API described by the configuration, the options
+// replacing the parts it would otherwise build from it. The database is
+// only opened when some service is not provided, without it the readiness
+// checks skip the database and the rate limits cannot use the sql store.
+func New(cfg *config.Config, opts ...Option) (*Api, error) {
+	a := &Api{
+		Config:  cfg,
+		Clock:   time.Now,
+		Console: os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.SigninigKey == nil {
+		pKey, err := cfg.Security.ParseSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		a.SigninigKey = pKey
+	}
+	a.SigningPublicKey = &a.SigninigKey.PublicKey
+
+	if a.Logger == nil {
+		logger, err := logging.New(cfg.Log)
+		if err != nil {
+			return nil, err
+		}
+
+		slog.SetDefault(logger)
+		a.Logger = logger
 	}
 
-	var a Api
+	if err := checkSessionCookieConfig(cfg.Security.SessionCookie); err != nil {
+		return nil, err
+	}
 
 	a.Debug = cfg.Debug
 	if !a.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	db, err := store.NewSqlStore(cfg.Storage.Driver, cfg.Storage.URL)
-	if err != nil {
-		return nil, err
+	if a.TracerProvider == nil && cfg.Tracing.Enabled {
+		tp, err := tracing.NewTracerProvider(context.Background(), cfg.Tracing)
+		if err != nil {
+			return nil, err
+		}
+		a.TracerProvider = tp
 	}
 
-	if cfg.Debug {
-		db = db.Debug()
+	if a.HTTPClient == nil {
+		a.HTTPClient = http.DefaultClient
+		if a.TracerProvider != nil {
+			a.HTTPClient = tracing.HTTPClient(a.TracerProvider)
+		}
 	}
 
-	a.DB = db
+	var err error
+	if a.UserService == nil || a.ConfigService == nil {
+		db, err := store.NewSqlStore(context.Background(), cfg.Storage, a.Logger)
+		if err != nil {
+			return nil, err
+		}
 
-	us, err := sqluserservice.NewUserService(db, pKey)
-	if err != nil {
-		return nil, err
+		if cfg.Debug {
+			db = db.Debug()
+		}
+
+		if a.TracerProvider != nil {
+			if err = db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithTracerProvider(a.TracerProvider))); err != nil {
+				return nil, err
+			}
+		}
+
+		a.DB = db
 	}
 
-	a.UserService = us
+	us := a.UserService
+	if us == nil {
+		sqlUs, err := sqluserservice.NewUserService(a.DB, a.SigninigKey)
+		if err != nil {
+			return nil, err
+		}
+		sqlUs.Now = a.Clock
+		us = sqlUs
+	}
 
-	cs, err := sqlconfigservice.NewConfigService(db)
-	if err != nil {
-		return nil, err
+	cs := a.ConfigService
+	if cs == nil {
+		cs, err = sqlconfigservice.NewConfigService(a.DB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.SessionCache.Enabled {
+		a.SessionCache, err = a.newSessionCache(cfg.SessionCache, us)
+		if err != nil {
+			return nil, err
+		}
+		us = a.SessionCache
 	}
 
+	if a.TracerProvider != nil {
+		us = userservice.NewTracingUserService(us, a.TracerProvider)
+		cs = configservice.NewTracingConfigService(cs, a.TracerProvider)
+	}
+
+	a.UserService = us
 	a.ConfigService = cs
 
-	/*var admin models.User
-	if err = db.First(&admin, &models.User{Username: "admin"}).Error; errors.Is(gorm.ErrRecordNotFound, err) {
-		fmt.Println("creating admin user")
-		if err = db.Create(&models.User{
-			Username: "admin",
-			Email:    "admin@localhost",
-			Password: cfg.Security.AdminPassword,
-			Admin:    true,
-			Kind:     "local",
-			Created:  time.Now(),
-		}).Error; err != nil {
+	deviceAuthStore := deviceauth.NewMemoryStore()
+	deviceAuthStore.Now = a.Clock
+	a.DeviceAuthStore = deviceAuthStore
+
+	if cfg.HTTP.RateLimit.Enabled {
+		if err := checkRateLimitConfig(cfg.HTTP.RateLimit); err != nil {
 			return nil, err
 		}
-	}*/
 
-	_, err = us.GetUserByUsername("admin")
-	if err == userservice.ErrUserNotFound {
-		_, err = us.CreateUser("admin", "admin@localhost", cfg.Security.AdminPassword, "local", true, "Admin")
+		a.RateLimitStore, err = newRateLimitStore(cfg.HTTP.RateLimit, a.DB, a.Clock)
 		if err != nil {
 			return nil, err
 		}
-	} else if err != nil {
+	}
+
+	if !cfg.Scheduler.Disabled {
+		a.Scheduler, err = a.newScheduler(cfg.Scheduler)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = a.bootstrapAdmin(context.Background()); err != nil {
 		return nil, err
 	}
 
-	router := gin.Default()
+	registerValidators()
+
+	cors, err := a.CORSMiddleware()
+	if err != nil {
+		return nil, err
+	}
+
+	router := gin.New()
+	// without trusted proxies the client ip is the address of the peer,
+	// the forwarding headers being set by the client
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(gin.Recovery())
+
+	if a.TracerProvider != nil {
+		serviceName := cfg.Tracing.ServiceName
+		if serviceName == "" {
+			serviceName = tracing.DefaultServiceName
+		}
+		router.Use(otelgin.Middleware(serviceName, otelgin.WithTracerProvider(a.TracerProvider)))
+	}
 
 	router.Use(
-		a.DebugCORSMiddleware,
+		a.RequestIDMiddleware,
+		a.LoggerMiddleware,
+		a.ErrorMiddleware,
+		a.SecurityHeadersMiddleware(),
+		cors,
+		a.BodyLimitMiddleware,
 	)
 
 	if cfg.Security.OIDC != nil {
 		for name, config := range cfg.Security.OIDC {
-			_, err := cs.UpsertOIDCProvider(&configservice.OIDCProvider{
+			_, err := cs.UpsertOIDCProvider(context.Background(), &configservice.OIDCProvider{
 				Name:         name,
 				Active:       true,
 				ClientID:     config.ClientID,
@@ -130,85 +266,41 @@ func NewAPI(cfgFile string) (*Api, error) {
 		}
 	}
 
-	apiGroup := router.Group("/api")
-	authGroup := apiGroup.Group("/auth")
-	{
-		authGroup.GET("/oidc/:provider", a.GenerateOIDCRedirectURL)
-		authGroup.GET("/callback/:provider", a.OIDCCallback)
-		authGroup.GET("/oidc/providers", a.GetAvailableOIDCProviders)
-		authGroup.POST("/login", a.AuthPassword)
-		authGroup.POST("/logout", a.Logout)
-	}
+	a.oidcCheck = a.newOIDCCheck(cfg.Health)
+	router.GET("/healthz", a.Healthz)
+	router.GET("/readyz", a.Readyz)
 
-	userGroup := apiGroup.Group("/user", a.RequiresUserLogin(false))
-	{
-		userGroup.GET("/profile", a.ProfileSelf)
-	}
-
-	adminGroup := apiGroup.Group("/admin", a.RequiresUserLogin(true))
-	{
-		adminGroup.GET("/users", a.AdminListUsers)
-		adminGroup.GET("/user/:id", a.AdminGetUser)
-	}
-
-	configGroup := apiGroup.Group("/config", a.RequiresUserLogin(true))
-	{
-		configGroup.POST("/oidc/provider", a.CreateOIDCProvider)
-	}
-
-	apiGroup.GET("/ping", a.UserTokenMiddleware, func(ctx *gin.Context) {
-		ctx.JSON(200, gin.H{"pong": time.Now()})
-	})
-
-	apiGroup.GET("/uuid", func(ctx *gin.Context) {
-		ctx.JSON(200, gin.H{"uuid": uuid.NewString()})
-	})
-
-	subbed, err := fs.Sub(embeded.UserInterfaceFS, "ui/dist")
+	apiGroup := router.Group("/api", a.RateLimit(RateLimitGroupGlobal))
+	openAPI, err := openAPIHandler()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	assets, err := fs.Sub(subbed, "assets")
+	apiGroup.GET("/openapi.json", openAPI)
+	a.registerAPI(apiGroup)
+
+	ui, err := a.registerUI(router)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	assetsGroup := router.Group("/assets", func(ctx *gin.Context) {
-		ctx.Writer.Header().Set("Cache-Control", "public, max-age=604800, immutable")
-	})
-	assetsGroup.StaticFS("", http.FS(assets))
-
-	router.GET("/", func(ctx *gin.Context) {
-		ctx.FileFromFS("/", http.FS(subbed))
-	})
-
-	router.GET("/index.html", func(ctx *gin.Context) {
-		ctx.FileFromFS("/", http.FS(subbed))
-	})
-
-	router.GET("/favicon.ico", func(ctx *gin.Context) {
-		ctx.FileFromFS("/favicon.ico", http.FS(subbed))
-	})
+	router.GET("/config.json", a.UIConfig)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// SPA fallback for client-side routing
 	router.NoRoute(func(ctx *gin.Context) {
 		if len(ctx.Request.URL.Path) >= len("/api") && ctx.Request.URL.Path[:len("/api")] == "/api" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "API route not found"})
+			abortWithError(ctx, errNotFound("API route not found"))
 			return
 		}
 
-		ctx.FileFromFS("/", http.FS(subbed))
+		ui(ctx)
 	})
 
 	a.Router = router
-	a.SigningPublicKey = &pKey.PublicKey
-	a.SigninigKey = pKey
-	a.Config = cfg
 
-	return &a, nil
+	return a, nil
 }
 
 type PingOutput struct {
@@ -221,15 +313,79 @@ type PingOutput struct {
 //	@Description	Pings the server and check authentication
 //	@Produce		json
 //	@Success		200	{object}	PingOutput
-//	@Failure		400	{object}	Error
-//	@Failure		500	{object}	Error
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		jwt
 //	@Security		apikey
 //	@Router			/ping [get]
 func (a *Api) Ping(ctx *gin.Context) {
-	ctx.JSON(200, &PingOutput{Pong: time.Now().Format(time.RFC1123Z)})
+	ctx.JSON(200, &PingOutput{Pong: a.Clock().Format(time.RFC1123Z)})
 }
 
+// Run serves the API until the process is interrupted or terminated,
+// then lets the requests in flight finish and shuts the API down
 func (a *Api) Run() error {
-	return a.Router.Run(a.Config.HTTP.Listen)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if a.SessionCache != nil {
+		a.SessionCache.Start(context.Background())
+	}
+
+	if a.Scheduler != nil {
+		a.Scheduler.Start(context.Background())
+	}
+
+	srv := &http.Server{
+		Addr:    a.Config.HTTP.Listen,
+		Handler: a.Router.Handler(),
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		a.Logger.Info("listening", "address", srv.Addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		a.Logger.Info("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		a.Logger.Error("could not stop the http server", "error", err)
+	}
+
+	if err := a.Shutdown(shutdownCtx); err != nil {
+		a.Logger.Error("could not shut the api down", "error", err)
+	}
+
+	return err
+}
+
+// Shutdown stops the scheduler and the session cache, and flushes the
+// pending spans, if tracing is enabled
+func (a *Api) Shutdown(ctx context.Context) error {
+	if a.Scheduler != nil {
+		if err := a.Scheduler.Stop(ctx); err != nil {
+			a.Logger.Error("could not stop the scheduler", "error", err)
+		}
+	}
+
+	if a.SessionCache != nil {
+		if err := a.SessionCache.Stop(ctx); err != nil {
+			a.Logger.Error("could not stop the session cache", "error", err)
+		}
+	}
+
+	if a.TracerProvider != nil {
+		return a.TracerProvider.Shutdown(ctx)
+	}
+
+	return nil
 }
@@ -0,0 +1,250 @@
+// Package apitest runs the router of the API in isolation, on top of the
+// in-memory services, a fixed clock and a generated signing key, with
+// helpers to log in and to check the responses. It is meant to be called
+// from tests:
+//
+//	func TestProfile(t *testing.T) {
+//		h := apitest.New(t, nil)
+//		token, _ := h.LoginAsUser(t, "alice")
+//
+//		var profile api.ProfileOutput
+//		h.Get(t, "/api/v1/user/profile", token).AssertJSON(t, http.StatusOK, &profile)
+//	}
+//
+// The tests of the handlers, in pkg/api, are written with it.
+package apitest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	memoryconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/memory"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	memoryuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/memory"
+)
+
+const (
+	AdminUsername = "admin"
+	AdminPassword = "admin-password"
+
+	// UserPassword is the password of the users created by CreateUser
+	UserPassword = "user-password"
+)
+
+// Harness is an API running on top of in-memory services
+type Harness struct {
+	API    *api.Api
+	Users  *memoryuserservice.UserService
+	Config *memoryconfigservice.ConfigService
+	Key    *ecdsa.PrivateKey
+	Clock  *Clock
+}
+
+// DefaultConfig is the configuration New uses when given none, with the
+// password of the built-in admin set to AdminPassword
+func DefaultConfig() *config.Config {
+	return &config.Config{
+		Security: config.SecurityConfig{
+			AdminPassword: AdminPassword,
+		},
+		Log: config.LogConfig{Level: "error"},
+	}
+}
+
+// New builds the API described by cfg, DefaultConfig when nil, the
+// options overriding the ones of the harness.
+func New(t testing.TB, cfg *config.Config, opts ...api.Option) *Harness {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+
+	h := &Harness{
+		Users:  memoryuserservice.NewUserService(key),
+		Config: memoryconfigservice.NewConfigService(),
+		Key:    key,
+		Clock:  NewClock(),
+	}
+	h.Users.Now = h.Clock.Now
+
+	opts = append([]api.Option{
+		api.WithUserService(h.Users),
+		api.WithConfigService(h.Config),
+		api.WithSigningKey(key),
+		api.WithClock(h.Clock.Now),
+		api.WithLogger(slog.New(slog.DiscardHandler)),
+	}, opts...)
+
+	h.API, err = api.New(cfg, opts...)
+	if err != nil {
+		t.Fatalf("could not create the api: %s", err)
+	}
+	t.Cleanup(func() { _ = h.API.Shutdown(t.Context()) })
+
+	return h
+}
+
+// NewRequest returns a request to the API, with body encoded as json
+// unless nil and authenticated with the session token unless empty.
+func NewRequest(t testing.TB, method string, path string, body any, token string) *http.Request {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("could not encode the body: %s", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-AUTH-TOKEN", token)
+	}
+
+	return req
+}
+
+// Serve runs the request through the router
+func (h *Harness) Serve(t testing.TB, req *http.Request) *Response {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.API.Router.ServeHTTP(rec, req)
+
+	return &Response{ResponseRecorder: rec, request: req.Method + " " + req.URL.String()}
+}
+
+func (h *Harness) Get(t testing.TB, path string, token string) *Response {
+	t.Helper()
+	return h.Serve(t, NewRequest(t, http.MethodGet, path, nil, token))
+}
+
+func (h *Harness) Post(t testing.TB, path string, token string, body any) *Response {
+	t.Helper()
+	return h.Serve(t, NewRequest(t, http.MethodPost, path, body, token))
+}
+
+func (h *Harness) Delete(t testing.TB, path string, token string) *Response {
+	t.Helper()
+	return h.Serve(t, NewRequest(t, http.MethodDelete, path, nil, token))
+}
+
+// CreateUser creates a local user with UserPassword as password
+func (h *Harness) CreateUser(t testing.TB, username string, admin bool) *userservice.User {
+	t.Helper()
+
+	u, err := h.Users.CreateUser(t.Context(), username, username+"@example.com", UserPassword, string(userservice.UserKindLocal), admin, "")
+	if err != nil {
+		t.Fatalf("could not create user %s: %s", username, err)
+	}
+
+	return u
+}
+
+// Login logs in with a password and returns the session token
+func (h *Harness) Login(t testing.TB, username string, password string) string {
+	t.Helper()
+
+	var out api.LoginOutput
+	h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{Username: username, Password: password}).AssertJSON(t, http.StatusOK, &out)
+	if out.Token == "" {
+		t.Fatal("the login did not return a token")
+	}
+
+	return out.Token
+}
+
+// LoginAsAdmin logs in as the built-in admin
+func (h *Harness) LoginAsAdmin(t testing.TB) string {
+	t.Helper()
+	return h.Login(t, AdminUsername, AdminPassword)
+}
+
+// LoginAsUser creates a user, not an admin, and logs in as it
+func (h *Harness) LoginAsUser(t testing.TB, username string) (string, *userservice.User) {
+	t.Helper()
+
+	u := h.CreateUser(t, username, false)
+	return h.Login(t, username, UserPassword), u
+}
+
+// Response is the recorded response of the API
+type Response struct {
+	*httptest.ResponseRecorder
+	request string
+}
+
+// AssertStatus fails the test unless the response has the given status
+func (r *Response) AssertStatus(t testing.TB, status int) *Response {
+	t.Helper()
+
+	if r.Code != status {
+		t.Fatalf("%s: expected status %d, got %d: %s", r.request, status, r.Code, r.Body.String())
+	}
+
+	return r
+}
+
+// AssertHeader fails the test unless the response has the given header
+func (r *Response) AssertHeader(t testing.TB, name string, value string) *Response {
+	t.Helper()
+
+	if got := r.Header().Get(name); got != value {
+		t.Fatalf("%s: expected header %s to be %q, got %q", r.request, name, value, got)
+	}
+
+	return r
+}
+
+// AssertJSON checks the status and the json content type of the response
+// and decodes it into out, failing on unknown fields
+func (r *Response) AssertJSON(t testing.TB, status int, out any) {
+	t.Helper()
+
+	r.AssertStatus(t, status)
+	if ct := r.Header().Get("Content-Type"); !strings.Contains(ct, "json") {
+		t.Fatalf("%s: expected a json response, got %q", r.request, ct)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(r.Body.Bytes()))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		t.Fatalf("%s: could not decode the response: %s: %s", r.request, err, r.Body.String())
+	}
+}
+
+// AssertProblem checks the response is a problem with the given status
+// and code, and returns it
+func (r *Response) AssertProblem(t testing.TB, status int, code api.ErrorCode) *api.Problem {
+	t.Helper()
+
+	var problem api.Problem
+	r.AssertJSON(t, status, &problem)
+	if problem.Code != code {
+		t.Fatalf("%s: expected error code %s, got %s: %s", r.request, code, problem.Code, problem.Detail)
+	}
+
+	return &problem
+}
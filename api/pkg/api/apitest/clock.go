@@ -0,0 +1,33 @@
+package apitest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock that only moves when told to
+type Clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// NewClock returns a clock set to the current time, truncated to the
+// second so that it survives the round trips through the JSON web tokens
+func NewClock() *Clock {
+	return &Clock{now: time.Now().Truncate(time.Second)}
+}
+
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,138 @@
+package apitest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const oidcKeyID = "apitest"
+
+// OIDCProvider is a minimal OIDC provider, enough for the discovery and
+// the code exchange of the API. Any code is exchanged for a token of the
+// identity set with SetIdentity.
+type OIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *ecdsa.PrivateKey
+	lock  sync.Mutex
+	email string
+	name  string
+}
+
+// NewOIDCProvider starts a provider, closed at the end of the test
+func NewOIDCProvider(t testing.TB) *OIDCProvider {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the provider key: %s", err)
+	}
+
+	p := &OIDCProvider{
+		ClientID:     "apitest-client",
+		ClientSecret: "apitest-secret",
+		key:          key,
+		email:        "oidc-user@example.com",
+		name:         "OIDC User",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /keys", p.keys)
+	mux.HandleFunc("POST /token", p.token)
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+
+	return p
+}
+
+// Issuer is the issuer url of the provider
+func (p *OIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+// SetIdentity sets who the next logins are done as
+func (p *OIDCProvider) SetIdentity(email string, name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.email = email
+	p.name = name
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+	})
+}
+
+func (p *OIDCProvider) keys(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, map[string]any{
+		"keys": []map[string]any{{
+			"kty": "EC",
+			"crv": "P-256",
+			"alg": "ES256",
+			"use": "sig",
+			"kid": oidcKeyID,
+			"x":   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32))),
+		}},
+	})
+}
+
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.lock.Lock()
+	email, name := p.email, p.name
+	p.lock.Unlock()
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss":   p.Issuer(),
+		"aud":   p.ClientID,
+		"sub":   email,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"email": email,
+		"name":  name,
+	})
+	token.Header["kid"] = oidcKeyID
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// the API verifies the access token as an id token
+	writeJSON(w, map[string]any{
+		"access_token": signed,
+		"id_token":     signed,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
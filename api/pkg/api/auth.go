@@ -3,25 +3,22 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"regexp"
 	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
 	"golang.org/x/oauth2"
 )
 
-type Error struct {
-	Error string `json:"error"`
-}
-
 type LoginInput struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" binding:"required,max=255"`
+	Password string `json:"password" binding:"required,max=1024"`
 }
 
 type LogoutOutput struct {
@@ -32,9 +29,12 @@ type OIDCURLOutput struct {
 	Url string `json:"url"`
 }
 
+// OIDCCallbackOutput holds either the session token, or the CSRF token
+// of the session cookie when the login asked for one
 type OIDCCallbackOutput struct {
-	Token    string `json:"token"`
-	Username string `json:"username"`
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrf_token,omitempty"`
+	Username  string `json:"username"`
 }
 
 type OIDCProvider struct {
@@ -43,16 +43,19 @@ type OIDCProvider struct {
 }
 
 type NewOIDCProvider struct {
-	Name         string   `json:"name"`
-	DisplayName  string   `json:"display_name"`
-	ClientID     string   `json:"client_id"`
-	ClientSecret string   `json:"client_secret"`
-	Issuer       string   `json:"issuer"`
-	Scopes       []string `json:"scopes"`
+	Name         string   `json:"name" binding:"required,alphanum,max=64"`
+	DisplayName  string   `json:"display_name" binding:"max=255"`
+	ClientID     string   `json:"client_id" binding:"required,printascii,max=255"`
+	ClientSecret string   `json:"client_secret" binding:"required,printascii,max=1024"`
+	Issuer       string   `json:"issuer" binding:"required,max=2048,oidc_issuer"`
+	Scopes       []string `json:"scopes" binding:"omitempty,max=32,oidc_scopes"`
 }
 
+// LoginOutput holds either the session token, or the CSRF token of the
+// session cookie when the login asked for one
 type LoginOutput struct {
-	Token string `json:"token"`
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrf_token,omitempty"`
 }
 
 // AuthPassword
@@ -63,32 +66,44 @@ type LoginOutput struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		LoginInput	true	"Input data for the login"
+//	@Param			X-Session-Mode	header	string	false	"cookie to get the session in a cookie"
 //	@Success		200		{object}	LoginOutput
-//	@Failure		400		{object}	Error
-//	@Failure		500		{object}	Error
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		413		{object}	Problem
+//	@Failure		422		{object}	Problem
+//	@Failure		500		{object}	Problem
 //	@Router			/auth/login [post]
 func (a *Api) AuthPassword(ctx *gin.Context) {
+	if a.Config.UI.DisablePasswordLogin {
+		abortWithError(ctx, NewAPIError(http.StatusForbidden, CodeForbidden, "password login is disabled", nil))
+		return
+	}
+
 	var login LoginInput
 
-	if err := ctx.BindJSON(&login); err != nil {
-		ctx.JSON(400, gin.H{"error": fmt.Errorf("could not bind the request to object: %w", err).Error()})
+	if err := bindJSON(ctx, &login); err != nil {
+		abortWithError(ctx, err)
 		return
 	}
 
-	var user *userservice.User
-	var err error
-	if user, err = a.UserService.Authenticate(login.Username, login.Password); err == nil {
-		token, err := a.UserService.GenerateSessionToken(user)
-		if err != nil {
-			ctx.JSON(500, gin.H{"error": "failed to generate session token"})
-		}
-		ctx.JSON(200, &LoginOutput{
-			Token: token,
-		})
+	user, err := a.UserService.Authenticate(ctx.Request.Context(), login.Username, login.Password)
+	if err != nil {
+		abortWithError(ctx, err)
 		return
 	}
 
-	ctx.JSON(401, gin.H{"error": "invalid credentials"})
+	token, csrf, err := a.newSession(ctx, user)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, &LoginOutput{
+		Token:     token,
+		CSRFToken: csrf,
+	})
 }
 
 // GenerateOIDCRedirectURL
@@ -98,20 +113,21 @@ func (a *Api) AuthPassword(ctx *gin.Context) {
 //	@Tags			Authentication
 //	@Accept			json
 //	@Produce		json
-//	@Param			name	path		int	true	"Name of the provider"
+//	@Param			name	path		string	true	"Name of the provider"
 //	@Success		200		{object}	OIDCURLOutput
-//	@Failure		400		{object}	Error
-//	@Failure		500		{object}	Error
+//	@Failure		404		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Failure		502		{object}	Problem
 //	@Router			/auth/oidc/{name} [get]
 func (a *Api) GenerateOIDCRedirectURL(ctx *gin.Context) {
 	if a.Config.Security.OIDC == nil {
-		ctx.JSON(404, gin.H{"error": "not found"})
+		abortWithError(ctx, errNotFound("oidc is not configured"))
 		return
 	}
 
-	provider, err := a.ConfigService.GetOIDCProvider(ctx.Param("provider"))
+	provider, err := a.ConfigService.GetOIDCProvider(ctx.Request.Context(), ctx.Param("provider"))
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -128,9 +144,11 @@ func (a *Api) GenerateOIDCRedirectURL(ctx *gin.Context) {
 		redirect = fmt.Sprintf("%s/auth/callback/%s", os.Getenv("OIDC_REDIRECT_BASE_URL"), provider.Name)
 	}
 
-	prv, err := oidc.NewProvider(ctx, provider.Issuer)
+	oidcCtx := oidc.ClientContext(ctx.Request.Context(), a.HTTPClient)
+	prv, err := oidc.NewProvider(oidcCtx, provider.Issuer)
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, errOIDC("failed to discover the oidc provider", err))
+		return
 	}
 
 	oauthConfig := oauth2.Config{
@@ -151,30 +169,36 @@ func (a *Api) GenerateOIDCRedirectURL(ctx *gin.Context) {
 //	@Tags			Authentication
 //	@Accept			json
 //	@Produce		json
-//	@Param			name	path		int		true	"Name of the provider"
+//	@Param			name	path		string	true	"Name of the provider"
 //	@Param			state	query		string	true	"State OIDC parameter"
 //	@Param			code	query		string	true	"Code OIDC parameter"
+//	@Param			X-Session-Mode	header	string	false	"cookie to get the session in a cookie"
 //	@Success		200		{object}	OIDCCallbackOutput
-//	@Failure		400		{object}	Error
-//	@Failure		500		{object}	Error
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		409		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Failure		502		{object}	Problem
 //	@Router			/auth/callback/{name} [get]
 func (a *Api) OIDCCallback(ctx *gin.Context) {
 	if !a.Debug {
 		state, err := ctx.Cookie("oidc-state")
 		if err != nil {
-			ctx.JSON(400, gin.H{"error": fmt.Errorf("missing state cookie: %w", err)})
+			abortWithError(ctx, errBadRequest("missing state cookie", err))
 			return
 		}
 
 		if ctx.Query("state") != state {
-			ctx.JSON(400, gin.H{"error": fmt.Errorf("bad state cookie: %w", err)})
+			abortWithError(ctx, errBadRequest("bad state cookie", nil))
 			return
 		}
 	}
 
-	provider, err := a.ConfigService.GetOIDCProvider(ctx.Param("provider"))
+	provider, err := a.ConfigService.GetOIDCProvider(ctx.Request.Context(), ctx.Param("provider"))
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, err)
+		return
 	}
 
 	scheme := "http"
@@ -187,18 +211,20 @@ func (a *Api) OIDCCallback(ctx *gin.Context) {
 		redirect = fmt.Sprintf("%s/auth/callback/%s", os.Getenv("OIDC_REDIRECT_BASE_URL"), provider.Name)
 	}
 
-	prvd, err := a.ConfigService.GetOIDCProvider(ctx.Param("provider"))
+	prvd, err := a.ConfigService.GetOIDCProvider(ctx.Request.Context(), ctx.Param("provider"))
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, err)
 		return
 	}
 
-	prv, err := oidc.NewProvider(ctx, prvd.Issuer)
+	oidcCtx := oidc.ClientContext(ctx.Request.Context(), a.HTTPClient)
+	prv, err := oidc.NewProvider(oidcCtx, prvd.Issuer)
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, errOIDC("failed to discover the oidc provider", err))
+		return
 	}
 
-	fmt.Println(provider.Scopes)
+	logging.FromContext(ctx.Request.Context()).Debug("exchanging oidc code", "provider", provider.Name, "scopes", provider.Scopes)
 
 	oauthConfig := oauth2.Config{
 		ClientID:     provider.ClientID,
@@ -208,9 +234,9 @@ func (a *Api) OIDCCallback(ctx *gin.Context) {
 		Scopes:       provider.Scopes,
 	}
 
-	oauth2Token, err := oauthConfig.Exchange(ctx, ctx.Query("code"))
+	oauth2Token, err := oauthConfig.Exchange(oidcCtx, ctx.Query("code"))
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": fmt.Sprintf("failed to exchange token: %s", err)})
+		abortWithError(ctx, errOIDC("failed to exchange token", err))
 		return
 	}
 
@@ -218,9 +244,9 @@ func (a *Api) OIDCCallback(ctx *gin.Context) {
 		ClientID: provider.ClientID,
 	})
 
-	idToken, err := verifier.Verify(ctx, oauth2Token.AccessToken)
+	idToken, err := verifier.Verify(oidcCtx, oauth2Token.AccessToken)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err})
+		abortWithError(ctx, NewAPIError(http.StatusUnauthorized, CodeOIDCError, "failed to verify the id token", err))
 		return
 
 	}
@@ -235,60 +261,61 @@ func (a *Api) OIDCCallback(ctx *gin.Context) {
 
 	err = idToken.Claims(&c)
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err})
+		abortWithError(ctx, errOIDC("failed to read the id token claims", err))
 		return
 
 	}
 
-	if user, err := a.UserService.GetUserByUsername(c.Email); errors.Is(err, userservice.ErrUserNotFound) {
-		user, err := a.UserService.CreateUser(c.Email, c.Email, "", "oidc", false, c.Name)
+	if user, err := a.UserService.GetUserByUsername(ctx.Request.Context(), c.Email); errors.Is(err, userservice.ErrUserNotFound) {
+		user, err := a.UserService.CreateUser(ctx.Request.Context(), c.Email, c.Email, "", "oidc", false, c.Name)
 		if err != nil {
-			fmt.Println(err)
-			ctx.JSON(500, gin.H{"error": fmt.Errorf("failed to create new user: %w", err)})
+			logging.FromContext(ctx.Request.Context()).Error("failed to create oidc user", "error", err, "username", c.Email)
+			abortWithError(ctx, errInternal("failed to create new user", err))
 			return
 		}
 
-		token, err := a.UserService.GenerateSessionToken(user)
+		token, csrf, err := a.newSession(ctx, user)
 		if err != nil {
-			ctx.JSON(500, gin.H{"error": fmt.Errorf("failed to generate session token: %w", err)})
+			abortWithError(ctx, err)
 			return
 		}
 
 		ctx.JSON(200, &OIDCCallbackOutput{
-			Token:    token,
-			Username: c.Email,
+			Token:     token,
+			CSRFToken: csrf,
+			Username:  c.Email,
 		})
 		return
 	} else if user != nil {
 		if user.Kind != "oidc" {
-			ctx.JSON(400, gin.H{"error": "user already exists and isn't of kind oidc"})
+			abortWithError(ctx, NewAPIError(http.StatusConflict, CodeConflict, "user already exists and isn't of kind oidc", nil))
 			return
 		}
 
-		err = a.UserService.UpdateUser(user.Id, c.Email, false, c.Name)
+		err = a.UserService.UpdateUser(ctx.Request.Context(), user.Id, c.Email, user.Admin, c.Name)
 		if err != nil {
-			ctx.JSON(500, gin.H{"error": fmt.Sprintf("failed to update user %s", err)})
+			abortWithError(ctx, errInternal("failed to update user", err))
 			return
 		}
 
-		token, err := a.UserService.GenerateSessionToken(user)
+		token, csrf, err := a.newSession(ctx, user)
 		if err != nil {
-			ctx.JSON(500, gin.H{"error": fmt.Errorf("failed to generate session token: %w", err)})
+			abortWithError(ctx, err)
 			return
 		}
 
 		ctx.JSON(200, &OIDCCallbackOutput{
-			Token:    token,
-			Username: c.Email,
+			Token:     token,
+			CSRFToken: csrf,
+			Username:  c.Email,
 		})
 		return
 	} else if err != nil {
-		ctx.JSON(500, gin.H{"error": "something failed, not sure why"})
+		abortWithError(ctx, err)
 		return
 	}
 
-	ctx.JSON(500, gin.H{"error": "not sure what happened but we failed to log you in"})
-	return
+	abortWithError(ctx, errInternal("failed to log you in", nil))
 }
 
 // Logout
@@ -299,25 +326,35 @@ func (a *Api) OIDCCallback(ctx *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	LoginOutput
-//	@Failure		400	{object}	Error
-//	@Failure		500	{object}	Error
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		jwt
 //	@Router			/auth/logout [post]
 func (a *Api) Logout(ctx *gin.Context) {
 	token := ctx.Request.Header.Get("X-AUTH-TOKEN")
-	if token == "" {
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
-		return
+	fromCookie := token == ""
+	if fromCookie {
+		var err error
+		token, _, _, err = a.cookieSession(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
 	}
 
-	if err := a.UserService.LogoutFromToken(token); err == nil {
-		ctx.JSON(200, &LogoutOutput{
-			Ok: true,
-		})
+	if err := a.UserService.LogoutFromToken(ctx.Request.Context(), token); err != nil {
+		abortWithError(ctx, err)
 		return
-	} else {
-		ctx.JSON(401, gin.H{"error": fmt.Sprintf("failed to logout: %s", err)})
 	}
+
+	if fromCookie {
+		a.setSessionCookies(ctx, "", "", -1)
+	}
+
+	ctx.JSON(200, &LogoutOutput{
+		Ok: true,
+	})
 }
 
 // GetAvailableOIDCProviders
@@ -328,14 +365,14 @@ func (a *Api) Logout(ctx *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	[]OIDCProvider
-//	@Failure		500	{object}	Error
+//	@Failure		500	{object}	Problem
 //	@Router			/auth/oidc/providers [get]
 //	@Security		jwt
 //	@Security		apikey
 func (a *Api) GetAvailableOIDCProviders(ctx *gin.Context) {
-	providers, err := a.ConfigService.GetOIDCProviders()
+	providers, err := a.ConfigService.GetOIDCProviders(ctx.Request.Context())
 	if err != nil {
-		ctx.AbortWithStatusJSON(500, gin.H{"error": err.Error()})
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -359,23 +396,26 @@ func (a *Api) GetAvailableOIDCProviders(ctx *gin.Context) {
 //	@Produce		json
 //	@Param			request	body		NewOIDCProvider	true	"New OIDC provider config"
 //	@Success		200		{object}	OIDCProvider
-//	@Failure		500		{object}	Error
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		413		{object}	Problem
+//	@Failure		422		{object}	Problem
+//	@Failure		500		{object}	Problem
 //	@Router			/config/oidc/provider [post]
 func (a *Api) CreateOIDCProvider(ctx *gin.Context) {
 	var input NewOIDCProvider
 
-	if err := ctx.BindJSON(&input); err != nil {
-		ctx.JSON(400, gin.H{"error": fmt.Errorf("could not bind the request to object: %w", err).Error()})
+	if err := bindJSON(ctx, &input); err != nil {
+		abortWithError(ctx, err)
 		return
 	}
 
-	if match, _ := regexp.MatchString("^[a-zA-z0-9]+$", input.Name); !match {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": "invalid name"})
-	}
-
 	prov, err := a.ConfigService.CreateOIDCProvider(
+		ctx.Request.Context(),
 		&configservice.OIDCProvider{
 			Name:         input.Name,
+			Active:       true,
 			DisplayName:  input.DisplayName,
 			ClientID:     input.ClientID,
 			ClientSecret: input.ClientSecret,
@@ -384,8 +424,22 @@ func (a *Api) CreateOIDCProvider(ctx *gin.Context) {
 		},
 	)
 	if err != nil {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, &OIDCProvider{
+		Name:        prov.Name,
+		DisplayName: prov.DisplayName,
+	})
+}
+
+// sessionTokenError tells the deactivated users apart from the failures
+// to generate a session token
+func sessionTokenError(err error) error {
+	if errors.Is(err, userservice.ErrUserDeactivated) {
+		return err
 	}
 
-	ctx.JSON(200, prov)
+	return errInternal("failed to generate session token", err)
 }
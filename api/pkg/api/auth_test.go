@@ -0,0 +1,210 @@
+package api_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+func TestLogin(t *testing.T) {
+	h := apitest.New(t, nil)
+	token := h.LoginAsAdmin(t)
+
+	if _, _, err := h.Users.VerifySessionToken(t.Context(), token); err != nil {
+		t.Fatalf("the token is not valid: %s", err)
+	}
+
+	h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{Username: apitest.AdminUsername, Password: "wrong"}).
+		AssertProblem(t, http.StatusUnauthorized, api.CodeInvalidCredentials)
+	h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{Username: "nobody", Password: "wrong"}).
+		AssertProblem(t, http.StatusUnauthorized, api.CodeInvalidCredentials)
+
+	problem := h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{}).
+		AssertProblem(t, http.StatusUnprocessableEntity, api.CodeValidationFailed)
+	if len(problem.Errors) != 2 {
+		t.Fatalf("expected 2 field errors, got %+v", problem.Errors)
+	}
+
+	u := h.CreateUser(t, "alice", false)
+	if err := h.Users.SetActive(t.Context(), u.Id, false); err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+	h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{Username: "alice", Password: apitest.UserPassword}).
+		AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+}
+
+func TestLoginDisabled(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.UI.DisablePasswordLogin = true
+	h := apitest.New(t, cfg)
+
+	h.Post(t, "/api/v1/auth/login", "", &api.LoginInput{Username: apitest.AdminUsername, Password: apitest.AdminPassword}).
+		AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+}
+
+func TestLogout(t *testing.T) {
+	h := apitest.New(t, nil)
+	token := h.LoginAsAdmin(t)
+
+	var out api.LogoutOutput
+	h.Post(t, "/api/v1/auth/logout", token, nil).AssertJSON(t, http.StatusOK, &out)
+	if !out.Ok {
+		t.Fatal("the logout did not succeed")
+	}
+
+	h.Get(t, "/api/v1/user/profile", token).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	h.Post(t, "/api/v1/auth/logout", "", nil).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
+
+// oidcConfig returns a configuration with the provider registered as
+// `fake`
+func oidcConfig(idp *apitest.OIDCProvider) *config.Config {
+	cfg := apitest.DefaultConfig()
+	cfg.Security.OIDC = map[string]config.OIDCConfig{
+		"fake": {
+			DisplayName:  "Fake",
+			Issuer:       idp.Issuer(),
+			ClientID:     idp.ClientID,
+			ClientSecret: idp.ClientSecret,
+		},
+	}
+
+	return cfg
+}
+
+func TestOIDCProviders(t *testing.T) {
+	h := apitest.New(t, oidcConfig(apitest.NewOIDCProvider(t)))
+
+	var out []api.OIDCProvider
+	h.Get(t, "/api/v1/auth/oidc/providers", "").AssertJSON(t, http.StatusOK, &out)
+	if len(out) != 1 || out[0].Name != "fake" || out[0].DisplayName != "Fake" {
+		t.Fatalf("unexpected providers: %+v", out)
+	}
+}
+
+func TestOIDCRedirect(t *testing.T) {
+	idp := apitest.NewOIDCProvider(t)
+	h := apitest.New(t, oidcConfig(idp))
+
+	var out api.OIDCURLOutput
+	res := h.Get(t, "/api/v1/auth/oidc/fake", "")
+	res.AssertJSON(t, http.StatusOK, &out)
+
+	u, err := url.Parse(out.Url)
+	if err != nil || !strings.HasPrefix(out.Url, idp.Issuer()+"/authorize") {
+		t.Fatalf("unexpected url: %s", out.Url)
+	}
+	if u.Query().Get("client_id") != idp.ClientID {
+		t.Fatalf("unexpected client id: %s", out.Url)
+	}
+
+	var state string
+	for _, c := range res.Result().Cookies() {
+		if c.Name == "oidc-state" {
+			state = c.Value
+		}
+	}
+	if state == "" || u.Query().Get("state") != state {
+		t.Fatalf("the state %q does not match the one of the url %s", state, out.Url)
+	}
+
+	h.Get(t, "/api/v1/auth/oidc/nope", "").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	// without any provider configured, oidc is disabled
+	apitest.New(t, nil).Get(t, "/api/v1/auth/oidc/fake", "").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+}
+
+// oidcCallback calls the callback of the provider with a matching state
+func oidcCallback(t *testing.T, h *apitest.Harness, provider string) *apitest.Response {
+	t.Helper()
+
+	req := apitest.NewRequest(t, http.MethodGet, "/api/v1/auth/callback/"+provider+"?state=state&code=code", nil, "")
+	req.AddCookie(&http.Cookie{Name: "oidc-state", Value: "state"})
+
+	return h.Serve(t, req)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	idp := apitest.NewOIDCProvider(t)
+	h := apitest.New(t, oidcConfig(idp))
+	idp.SetIdentity("carol@example.com", "Carol")
+
+	// the first login creates the user
+	var out api.OIDCCallbackOutput
+	oidcCallback(t, h, "fake").AssertJSON(t, http.StatusOK, &out)
+	if out.Username != "carol@example.com" {
+		t.Fatalf("unexpected username: %s", out.Username)
+	}
+
+	var profile api.ProfileOutput
+	h.Get(t, "/api/v1/user/profile", out.Token).AssertJSON(t, http.StatusOK, &profile)
+	if profile.Kind != "oidc" || profile.DisplayName != "Carol" || profile.Admin {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	// the next ones update it
+	idp.SetIdentity("carol@example.com", "Carol Doe")
+	oidcCallback(t, h, "fake").AssertJSON(t, http.StatusOK, &out)
+	h.Get(t, "/api/v1/user/profile", out.Token).AssertJSON(t, http.StatusOK, &profile)
+	if profile.DisplayName != "Carol Doe" {
+		t.Fatalf("the user was not updated: %+v", profile)
+	}
+
+	// local users cannot be taken over
+	h.CreateUser(t, "dave@example.com", false)
+	idp.SetIdentity("dave@example.com", "Dave")
+	oidcCallback(t, h, "fake").AssertProblem(t, http.StatusConflict, api.CodeConflict)
+
+	oidcCallback(t, h, "nope").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	// the state must match the cookie
+	h.Get(t, "/api/v1/auth/callback/fake?state=state&code=code", "").AssertProblem(t, http.StatusBadRequest, api.CodeInvalidRequest)
+
+	req := apitest.NewRequest(t, http.MethodGet, "/api/v1/auth/callback/fake?state=other&code=code", nil, "")
+	req.AddCookie(&http.Cookie{Name: "oidc-state", Value: "state"})
+	h.Serve(t, req).AssertProblem(t, http.StatusBadRequest, api.CodeInvalidRequest)
+}
+
+func TestCreateOIDCProvider(t *testing.T) {
+	h := apitest.New(t, nil)
+	admin := h.LoginAsAdmin(t)
+	user, _ := h.LoginAsUser(t, "alice")
+
+	input := &api.NewOIDCProvider{
+		Name:         "google",
+		DisplayName:  "Google",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Issuer:       "https://accounts.google.com",
+	}
+
+	var out api.OIDCProvider
+	h.Post(t, "/api/v1/config/oidc/provider", admin, input).AssertJSON(t, http.StatusOK, &out)
+	if out.Name != "google" || out.DisplayName != "Google" {
+		t.Fatalf("unexpected provider: %+v", out)
+	}
+
+	prov, err := h.Config.GetOIDCProvider(t.Context(), "google")
+	if err != nil {
+		t.Fatalf("the provider was not created: %s", err)
+	}
+	if !prov.Active || len(prov.Scopes) == 0 {
+		t.Fatalf("unexpected provider: %+v", prov)
+	}
+
+	h.Post(t, "/api/v1/config/oidc/provider", admin, input).AssertProblem(t, http.StatusConflict, api.CodeConflict)
+	h.Post(t, "/api/v1/config/oidc/provider", user, input).AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+	h.Post(t, "/api/v1/config/oidc/provider", "", input).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+
+	input.Name = "insecure"
+	input.Issuer = "http://accounts.example.com"
+	problem := h.Post(t, "/api/v1/config/oidc/provider", admin, input).AssertProblem(t, http.StatusUnprocessableEntity, api.CodeValidationFailed)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "issuer" {
+		t.Fatalf("unexpected field errors: %+v", problem.Errors)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAdminUsername = "admin"
+
+	// generatedPasswordBytes is the entropy of the generated admin
+	// password, 144 bits
+	generatedPasswordBytes = 18
+)
+
+// bootstrapAdmin creates the built-in admin on the first start, or
+// deactivates it when it is disabled. An existing admin is otherwise left
+// untouched, its password included, so that changing the configuration
+// does not reset it.
+func (a *Api) bootstrapAdmin(ctx context.Context) error {
+	cfg := a.Config.Security.Admin
+
+	username := cfg.Username
+	if username == "" {
+		username = defaultAdminUsername
+	}
+
+	admin, err := a.UserService.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, userservice.ErrUserNotFound) {
+		return err
+	}
+
+	if cfg.Disabled {
+		return a.disableAdmin(ctx, admin)
+	}
+
+	if admin != nil {
+		return nil
+	}
+
+	email := cfg.Email
+	if email == "" {
+		email = username + "@localhost"
+	}
+
+	password := a.Config.Security.AdminPassword
+	generated := password == ""
+	if generated {
+		password, err = generatePassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	// a password hashed with hashpass is set as is, the user service
+	// hashing whatever it is given
+	hash := ""
+	if _, err := bcrypt.Cost([]byte(password)); err == nil {
+		hash, password = password, ""
+	}
+
+	admin, err = a.UserService.CreateUser(ctx, username, email, password, string(userservice.UserKindLocal), true, "Admin")
+	if err != nil {
+		return fmt.Errorf("could not create the admin: %w", err)
+	}
+
+	if hash != "" {
+		if err := a.UserService.SetPasswordHash(ctx, admin.Id, hash); err != nil {
+			// the next start creates it again rather than leaving an
+			// admin nobody can log in as
+			_ = a.UserService.DeleteUser(ctx, admin.Id)
+			return fmt.Errorf("could not set the password of the admin: %w", err)
+		}
+	}
+
+	a.Logger.Info("created the built-in admin", "username", admin.Username, "generated_password", generated)
+	if generated {
+		// printed outside of the logs, which tend to be kept and shipped
+		// elsewhere, it is not shown again
+		fmt.Fprintf(a.Console, "\nGenerated the password of the built-in admin %q, it will not be shown again:\n\n    %s\n\n", admin.Username, password)
+	}
+
+	return nil
+}
+
+// disableAdmin deactivates the built-in admin, provided another active
+// admin can take over
+func (a *Api) disableAdmin(ctx context.Context, admin *userservice.User) error {
+	if admin == nil || !admin.Active {
+		return nil
+	}
+
+	users, err := a.UserService.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if u.Id != admin.Id && u.Admin && u.Active {
+			if err := a.UserService.SetActive(ctx, admin.Id, false); err != nil {
+				return err
+			}
+			if _, err := a.UserService.RevokeAllSessions(ctx, admin.Id); err != nil {
+				return err
+			}
+
+			a.Logger.Info("disabled the built-in admin", "username", admin.Username)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the built-in admin %q cannot be disabled, no other active admin exists", admin.Username)
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, generatedPasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
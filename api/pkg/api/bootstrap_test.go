@@ -0,0 +1,226 @@
+package api_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	memoryconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/memory"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	memoryuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/memory"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newUsers returns the user service kept across the starts of a test
+func newUsers(t *testing.T) *memoryuserservice.UserService {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+
+	return memoryuserservice.NewUserService(key)
+}
+
+// start starts the API on the users, returning what it printed on the
+// console
+func start(t *testing.T, cfg *config.Config, users userservice.UserService) (string, error) {
+	t.Helper()
+
+	var console bytes.Buffer
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+
+	a, err := api.New(cfg,
+		api.WithUserService(users),
+		api.WithConfigService(memoryconfigservice.NewConfigService()),
+		api.WithSigningKey(key),
+		api.WithLogger(slog.New(slog.DiscardHandler)),
+		api.WithConsole(&console),
+	)
+	if err == nil {
+		t.Cleanup(func() { _ = a.Shutdown(t.Context()) })
+	}
+
+	return console.String(), err
+}
+
+// mustStart starts the API, failing the test on error
+func mustStart(t *testing.T, cfg *config.Config, users userservice.UserService) string {
+	t.Helper()
+
+	console, err := start(t, cfg, users)
+	if err != nil {
+		t.Fatalf("could not start the api: %s", err)
+	}
+
+	return console
+}
+
+func TestBootstrapHashedPassword(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("s3cr3t"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("could not hash the password: %s", err)
+	}
+
+	cfg := apitest.DefaultConfig()
+	cfg.Security.AdminPassword = string(hashed)
+	users := newUsers(t)
+
+	if console := mustStart(t, cfg, users); console != "" {
+		t.Fatalf("nothing should be printed for a configured password: %q", console)
+	}
+
+	admin, err := users.Authenticate(t.Context(), "admin", "s3cr3t")
+	if err != nil {
+		t.Fatalf("could not log in with the password of the hash: %s", err)
+	}
+	if !admin.Admin || !admin.Active || admin.Kind != userservice.UserKindLocal || admin.Email != "admin@localhost" {
+		t.Fatalf("unexpected admin: %+v", admin)
+	}
+
+	if _, err := users.Authenticate(t.Context(), "admin", string(hashed)); !errors.Is(err, userservice.ErrInvalidCredentials) {
+		t.Fatalf("expected the hash itself to be rejected, got %v", err)
+	}
+}
+
+func TestBootstrapGeneratedPassword(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.Security.AdminPassword = ""
+	users := newUsers(t)
+
+	console := mustStart(t, cfg, users)
+	match := regexp.MustCompile(`(?s)^\nGenerated the password of the built-in admin "admin", it will not be shown again:\n\n    ([A-Za-z0-9_-]{24})\n\n$`).FindStringSubmatch(console)
+	if match == nil {
+		t.Fatalf("the generated password was not printed: %q", console)
+	}
+
+	if _, err := users.Authenticate(t.Context(), "admin", match[1]); err != nil {
+		t.Fatalf("could not log in with the generated password: %s", err)
+	}
+
+	// the password is neither generated nor printed again
+	if console := mustStart(t, cfg, users); console != "" {
+		t.Fatalf("the password was printed again: %q", console)
+	}
+	if _, err := users.Authenticate(t.Context(), "admin", match[1]); err != nil {
+		t.Fatalf("the password changed on restart: %s", err)
+	}
+
+	// nor shared between databases
+	other := newUsers(t)
+	if console := mustStart(t, cfg, other); console == "" || console == match[0] {
+		t.Fatalf("expected a new password to be generated: %q", console)
+	}
+}
+
+func TestBootstrapRestart(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.Security.Admin = config.AdminConfig{Username: "root", Email: "root@example.com"}
+	users := newUsers(t)
+
+	mustStart(t, cfg, users)
+	root, err := users.Authenticate(t.Context(), "root", apitest.AdminPassword)
+	if err != nil {
+		t.Fatalf("could not log in as the configured admin: %s", err)
+	}
+	if root.Email != "root@example.com" {
+		t.Fatalf("unexpected admin: %+v", root)
+	}
+
+	// changing the configuration neither resets the password nor
+	// creates another admin
+	cfg.Security.AdminPassword = "changed"
+	cfg.Security.Admin.Email = "changed@example.com"
+	for range 2 {
+		mustStart(t, cfg, users)
+	}
+
+	if _, err := users.Authenticate(t.Context(), "root", apitest.AdminPassword); err != nil {
+		t.Fatalf("the password was reset on restart: %s", err)
+	}
+
+	list, err := users.ListUsers(t.Context())
+	if err != nil {
+		t.Fatalf("could not list the users: %s", err)
+	}
+	if len(list) != 1 || list[0].Id != root.Id || list[0].Email != "root@example.com" {
+		t.Fatalf("expected the admin to be left untouched, got %+v", list)
+	}
+}
+
+func TestBootstrapDisabled(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	users := newUsers(t)
+
+	// disabled from the first start, the admin is never created
+	cfg.Security.Admin.Disabled = true
+	mustStart(t, cfg, users)
+	if _, err := users.GetUserByUsername(t.Context(), "admin"); !errors.Is(err, userservice.ErrUserNotFound) {
+		t.Fatalf("expected the admin not to be created, got %v", err)
+	}
+
+	cfg.Security.Admin.Disabled = false
+	mustStart(t, cfg, users)
+	admin, err := users.GetUserByUsername(t.Context(), "admin")
+	if err != nil {
+		t.Fatalf("could not get the admin: %s", err)
+	}
+	token, err := users.GenerateSessionToken(t.Context(), admin)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+
+	// neither a user nor an inactive admin can take over
+	if _, err := users.CreateUser(t.Context(), "alice", "alice@example.com", "", string(userservice.UserKindOIDC), false, ""); err != nil {
+		t.Fatalf("could not create the user: %s", err)
+	}
+	bob, err := users.CreateUser(t.Context(), "bob", "bob@example.com", "", string(userservice.UserKindOIDC), true, "")
+	if err != nil {
+		t.Fatalf("could not create the admin: %s", err)
+	}
+	if err := users.SetActive(t.Context(), bob.Id, false); err != nil {
+		t.Fatalf("could not deactivate the admin: %s", err)
+	}
+
+	cfg.Security.Admin.Disabled = true
+	if _, err := start(t, cfg, users); err == nil {
+		t.Fatal("expected the only active admin not to be disabled")
+	}
+	if _, _, err := users.VerifySessionToken(t.Context(), token); err != nil {
+		t.Fatalf("the admin was disabled anyway: %s", err)
+	}
+
+	if err := users.SetActive(t.Context(), bob.Id, true); err != nil {
+		t.Fatalf("could not activate the admin: %s", err)
+	}
+	for range 2 {
+		mustStart(t, cfg, users)
+	}
+
+	if _, _, err := users.VerifySessionToken(t.Context(), token); !errors.Is(err, userservice.ErrInvalidSession) {
+		t.Fatalf("expected the sessions of the disabled admin to be revoked, got %v", err)
+	}
+	if _, err := users.Authenticate(t.Context(), "admin", apitest.AdminPassword); !errors.Is(err, userservice.ErrUserDeactivated) {
+		t.Fatalf("expected the disabled admin not to log in, got %v", err)
+	}
+
+	// enabling it again is done by hand, the configuration only ever
+	// disables it
+	cfg.Security.Admin.Disabled = false
+	mustStart(t, cfg, users)
+	if _, err := users.Authenticate(t.Context(), "admin", apitest.AdminPassword); !errors.Is(err, userservice.ErrUserDeactivated) {
+		t.Fatalf("expected the admin to stay disabled, got %v", err)
+	}
+}
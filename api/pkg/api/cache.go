@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+)
+
+type SessionCacheOutput struct {
+	Enabled       bool    `json:"enabled"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Sessions      int     `json:"sessions"`
+	Users         int     `json:"users"`
+}
+
+// newSessionCache wraps the user service in the cache described by the
+// configuration
+func (a *Api) newSessionCache(cfg config.SessionCacheConfig, next userservice.UserService) (*cacheuserservice.UserService, error) {
+	if cfg.Size < 0 || cfg.TTL < 0 || cfg.PollInterval < 0 {
+		return nil, fmt.Errorf("invalid session cache settings: size, ttl and poll interval cannot be negative")
+	}
+
+	notifierName := cfg.Notifier
+	if notifierName == "" {
+		notifierName = cacheuserservice.NotifierNone
+		if a.DB != nil {
+			notifierName = cacheuserservice.NotifierSQL
+		}
+	}
+
+	var notifier cacheuserservice.Notifier
+	switch notifierName {
+	case cacheuserservice.NotifierNone:
+		notifier = cacheuserservice.NopNotifier{}
+	case cacheuserservice.NotifierSQL:
+		if a.DB == nil {
+			return nil, fmt.Errorf("the sql session cache notifier needs a database")
+		}
+		sqlNotifier, err := cacheuserservice.NewSQLNotifier(a.DB)
+		if err != nil {
+			return nil, err
+		}
+		sqlNotifier.Logger = a.Logger
+		if cfg.PollInterval > 0 {
+			sqlNotifier.Interval = cfg.PollInterval
+		}
+		notifier = sqlNotifier
+	default:
+		return nil, fmt.Errorf("invalid session cache notifier provided: %s", cfg.Notifier)
+	}
+
+	size := cfg.Size
+	if size == 0 {
+		size = cacheuserservice.DefaultSize
+	}
+
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = cacheuserservice.DefaultTTL
+	}
+
+	cache := cacheuserservice.NewUserService(next, notifier, size, ttl)
+	cache.Now = a.Clock
+
+	return cache, nil
+}
+
+// AdminSessionCache Statistics of the session cache
+//
+//	@Summary		Returns the statistics of the session cache
+//	@Description	Returns the hits, misses, evictions and invalidations of the session cache of the instance answering since it started, along with how many sessions and users it holds
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	SessionCacheOutput
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		jwt
+//	@Security		apikey
+//	@Router			/admin/cache [get]
+func (a *Api) AdminSessionCache(ctx *gin.Context) {
+	if a.SessionCache == nil {
+		ctx.JSON(200, &SessionCacheOutput{})
+		return
+	}
+
+	stats := a.SessionCache.Stats()
+	ctx.JSON(200, &SessionCacheOutput{
+		Enabled:       true,
+		Hits:          stats.Hits,
+		Misses:        stats.Misses,
+		HitRatio:      stats.HitRatio(),
+		Evictions:     stats.Evictions,
+		Invalidations: stats.Invalidations,
+		Sessions:      stats.Sessions,
+		Users:         stats.Users,
+	})
+}
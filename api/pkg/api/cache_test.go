@@ -0,0 +1,41 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestSessionCache(t *testing.T) {
+	h := apitest.New(t, nil)
+	var out api.SessionCacheOutput
+	h.Get(t, "/api/v1/admin/cache", h.LoginAsAdmin(t)).AssertJSON(t, http.StatusOK, &out)
+	if out.Enabled {
+		t.Fatalf("the session cache is enabled by default: %+v", out)
+	}
+
+	cfg := apitest.DefaultConfig()
+	cfg.SessionCache.Enabled = true
+	h = apitest.New(t, cfg)
+	admin := h.LoginAsAdmin(t)
+	user, u := h.LoginAsUser(t, "alice")
+
+	for range 3 {
+		h.Get(t, "/api/v1/user/profile", user).AssertStatus(t, http.StatusOK)
+	}
+
+	h.Get(t, "/api/v1/admin/cache", admin).AssertJSON(t, http.StatusOK, &out)
+	if !out.Enabled || out.Hits != 2 || out.Misses != 2 || out.Sessions != 2 || out.Users != 2 {
+		t.Fatalf("unexpected statistics: %+v", out)
+	}
+
+	// the changes made through the api drop the cached entries
+	if err := h.API.UserService.SetActive(t.Context(), u.Id, false); err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+	h.Get(t, "/api/v1/user/profile", user).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+
+	h.Get(t, "/api/v1/admin/cache", user).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
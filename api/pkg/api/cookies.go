@@ -0,0 +1,156 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+const (
+	SessionModeHeader = "X-Session-Mode"
+	SessionModeCookie = "cookie"
+	CSRFHeader        = "X-CSRF-Token"
+
+	defaultSessionCookieName = "session"
+	// the CSRF cookie is named after the session one
+	csrfCookieSuffix = "_csrf"
+)
+
+// checkSessionCookieConfig rejects the settings browsers would ignore
+func checkSessionCookieConfig(cfg config.SessionCookieConfig) error {
+	switch strings.ToLower(cfg.SameSite) {
+	case "", "lax", "strict":
+	case "none":
+		if cfg.Insecure {
+			return fmt.Errorf("session cookies with SameSite none must be secure")
+		}
+	default:
+		return fmt.Errorf("invalid session cookie SameSite provided: %s", cfg.SameSite)
+	}
+
+	return nil
+}
+
+func (a *Api) sessionCookieName() string {
+	if name := a.Config.Security.SessionCookie.Name; name != "" {
+		return name
+	}
+
+	return defaultSessionCookieName
+}
+
+func (a *Api) sessionCookieSameSite() http.SameSite {
+	switch strings.ToLower(a.Config.Security.SessionCookie.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// wantsSessionCookie tells whether the login should hand the session
+// over in a cookie
+func (a *Api) wantsSessionCookie(ctx *gin.Context) bool {
+	return a.Config.Security.SessionCookie.Enabled && strings.EqualFold(ctx.GetHeader(SessionModeHeader), SessionModeCookie)
+}
+
+// csrfToken derives the CSRF token of a session from the signing key, so
+// that a token cannot be forged nor reused with another session
+func (a *Api) csrfToken(sessionId string) string {
+	key := sha256.Sum256(append([]byte("csrf:"), a.SigninigKey.D.Bytes()...))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(sessionId))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setSessionCookies sets the HttpOnly session cookie, and the CSRF one
+// that the UI reads to send it back as a header. maxAge is in seconds, a
+// negative one deleting the cookies.
+func (a *Api) setSessionCookies(ctx *gin.Context, token string, csrf string, maxAge int) {
+	cfg := a.Config.Security.SessionCookie
+	for _, c := range []*http.Cookie{
+		{Name: a.sessionCookieName(), Value: token, HttpOnly: true},
+		{Name: a.sessionCookieName() + csrfCookieSuffix, Value: csrf},
+	} {
+		c.Path = "/"
+		c.Domain = cfg.Domain
+		c.MaxAge = maxAge
+		c.Secure = !cfg.Insecure
+		c.SameSite = a.sessionCookieSameSite()
+		http.SetCookie(ctx.Writer, c)
+	}
+}
+
+// newSession logs the user in. The token is returned, unless the request
+// asked for a session cookie, in which case it is only set in the cookie
+// and the CSRF token of the session is returned instead.
+func (a *Api) newSession(ctx *gin.Context, user *userservice.User) (token string, csrf string, err error) {
+	token, err = a.UserService.GenerateSessionToken(ctx.Request.Context(), user)
+	if err != nil {
+		return "", "", sessionTokenError(err)
+	}
+
+	if !a.wantsSessionCookie(ctx) {
+		return token, "", nil
+	}
+
+	claims, err := userservice.ParseSessionToken(a.SigninigKey, token, a.Clock())
+	if err != nil {
+		return "", "", errInternal("failed to read the new session", err)
+	}
+
+	csrf = a.csrfToken(claims.SessionId)
+	a.setSessionCookies(ctx, token, csrf, int(userservice.SessionDuration.Seconds()))
+
+	return "", csrf, nil
+}
+
+// safeMethod tells whether a request cannot change any state, and thus
+// needs no CSRF token
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// cookieSession verifies the session cookie of the request, and that the
+// requests changing state send the CSRF token back both as a cookie and
+// as a header, the token being the one of the session. The header based
+// authentications need no CSRF token, browsers only sending custom
+// headers to other origins allowed by CORS.
+func (a *Api) cookieSession(ctx *gin.Context) (string, *userservice.Session, *userservice.User, error) {
+	if !a.Config.Security.SessionCookie.Enabled {
+		return "", nil, nil, errUnauthenticated()
+	}
+
+	token, err := ctx.Cookie(a.sessionCookieName())
+	if err != nil || token == "" {
+		return "", nil, nil, errUnauthenticated()
+	}
+
+	session, user, err := a.UserService.VerifySessionToken(ctx.Request.Context(), token)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if !safeMethod(ctx.Request.Method) {
+		header := ctx.GetHeader(CSRFHeader)
+		cookie, _ := ctx.Cookie(a.sessionCookieName() + csrfCookieSuffix)
+		if header == "" ||
+			subtle.ConstantTimeCompare([]byte(header), []byte(cookie)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(header), []byte(a.csrfToken(session.Id))) != 1 {
+			return "", nil, nil, NewAPIError(http.StatusForbidden, CodeCSRFFailed, "missing or invalid csrf token", nil)
+		}
+	}
+
+	return token, session, user, nil
+}
@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+// cookieLogin logs in asking for a session cookie, and returns the
+// session and CSRF cookies along with the CSRF token of the response
+func cookieLogin(t *testing.T, h *apitest.Harness, username string, password string) ([]*http.Cookie, string) {
+	t.Helper()
+
+	req := apitest.NewRequest(t, http.MethodPost, "/api/v1/auth/login", &api.LoginInput{Username: username, Password: password}, "")
+	req.Header.Set(api.SessionModeHeader, api.SessionModeCookie)
+
+	var out api.LoginOutput
+	res := h.Serve(t, req)
+	res.AssertJSON(t, http.StatusOK, &out)
+	if out.Token != "" || out.CSRFToken == "" {
+		t.Fatalf("expected only a csrf token, got %+v", out)
+	}
+
+	cookies := res.Result().Cookies()
+	if len(cookies) != 2 || !cookies[0].HttpOnly || cookies[1].HttpOnly || cookies[1].Value != out.CSRFToken {
+		t.Fatalf("unexpected cookies: %+v", cookies)
+	}
+	for _, c := range cookies {
+		if !c.Secure || c.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("the cookie %s is not secure: %+v", c.Name, c)
+		}
+	}
+
+	return cookies, out.CSRFToken
+}
+
+// cookieRequest returns a request authenticated with the cookies, and
+// sending csrf as a header unless empty
+func cookieRequest(t *testing.T, method string, path string, cookies []*http.Cookie, csrf string) *http.Request {
+	t.Helper()
+
+	req := apitest.NewRequest(t, method, path, nil, "")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	if csrf != "" {
+		req.Header.Set(api.CSRFHeader, csrf)
+	}
+
+	return req
+}
+
+func TestSessionCookie(t *testing.T) {
+	// the mode is ignored unless enabled
+	h := apitest.New(t, nil)
+	req := apitest.NewRequest(t, http.MethodPost, "/api/v1/auth/login", &api.LoginInput{Username: apitest.AdminUsername, Password: apitest.AdminPassword}, "")
+	req.Header.Set(api.SessionModeHeader, api.SessionModeCookie)
+	var out api.LoginOutput
+	res := h.Serve(t, req)
+	res.AssertJSON(t, http.StatusOK, &out)
+	if out.Token == "" || len(res.Result().Cookies()) != 0 {
+		t.Fatalf("the session cookie is used while disabled: %+v", out)
+	}
+
+	cfg := apitest.DefaultConfig()
+	cfg.Security.SessionCookie.Enabled = true
+	h = apitest.New(t, cfg)
+
+	cookies, csrf := cookieLogin(t, h, apitest.AdminUsername, apitest.AdminPassword)
+	h.Serve(t, cookieRequest(t, http.MethodGet, "/api/v1/user/profile", cookies, "")).AssertStatus(t, http.StatusOK)
+	h.Serve(t, cookieRequest(t, http.MethodGet, "/api/v1/ping", cookies, "")).AssertStatus(t, http.StatusOK)
+
+	// the header authentication keeps working
+	token, _ := h.LoginAsUser(t, "alice")
+	h.Get(t, "/api/v1/user/profile", token).AssertStatus(t, http.StatusOK)
+
+	// the requests changing state need the csrf token of the session
+	other, otherCSRF := cookieLogin(t, h, apitest.AdminUsername, apitest.AdminPassword)
+	path := "/api/v1/user/sessions/" + uuid.NewString()
+	h.Serve(t, cookieRequest(t, http.MethodDelete, path, cookies, "")).
+		AssertProblem(t, http.StatusForbidden, api.CodeCSRFFailed)
+	h.Serve(t, cookieRequest(t, http.MethodDelete, path, cookies, otherCSRF)).
+		AssertProblem(t, http.StatusForbidden, api.CodeCSRFFailed)
+	h.Serve(t, cookieRequest(t, http.MethodDelete, path, []*http.Cookie{cookies[0], other[1]}, otherCSRF)).
+		AssertProblem(t, http.StatusForbidden, api.CodeCSRFFailed)
+	h.Serve(t, cookieRequest(t, http.MethodPost, "/api/v1/auth/logout", cookies, "")).
+		AssertProblem(t, http.StatusForbidden, api.CodeCSRFFailed)
+	h.Serve(t, cookieRequest(t, http.MethodDelete, path, cookies, csrf)).
+		AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	res = h.Serve(t, cookieRequest(t, http.MethodPost, "/api/v1/auth/logout", cookies, csrf)).AssertStatus(t, http.StatusOK)
+	cleared := res.Result().Cookies()
+	if len(cleared) != 2 {
+		t.Fatalf("expected the logout to clear 2 cookies, got %+v", cleared)
+	}
+	for _, c := range cleared {
+		if c.MaxAge >= 0 || c.Value != "" {
+			t.Fatalf("the cookie %s is not cleared: %+v", c.Name, c)
+		}
+	}
+	h.Serve(t, cookieRequest(t, http.MethodGet, "/api/v1/user/profile", cookies, "")).
+		AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	h.Serve(t, cookieRequest(t, http.MethodGet, "/api/v1/user/profile", other, "")).AssertStatus(t, http.StatusOK)
+
+	cfg = apitest.DefaultConfig()
+	cfg.Security.SessionCookie = config.SessionCookieConfig{Enabled: true, SameSite: "none", Insecure: true}
+	_, err := api.New(cfg,
+		api.WithUserService(h.Users),
+		api.WithConfigService(h.Config),
+		api.WithSigningKey(h.Key),
+		api.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	if err == nil || !strings.Contains(err.Error(), "SameSite") {
+		t.Fatalf("expected an insecure SameSite none cookie to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+const CORSPresetDebug = "debug"
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Content-Type", "X-AUTH-TOKEN", "X-API-KEY", SessionModeHeader, CSRFHeader, requestIDHeader}
+	defaultCORSExposed = []string{requestIDHeader, "Deprecation", "Sunset", "Link"}
+)
+
+// corsPolicy is the compiled form of config.CORSConfig
+type corsPolicy struct {
+	allowAll         bool
+	origins          map[string]bool
+	wildcards        []wildcardOrigin
+	methods          []string
+	headers          []string
+	exposed          []string
+	allowCredentials bool
+	maxAge           int
+}
+
+// wildcardOrigin matches `scheme://*.domain[:port]`, that is any
+// subdomain of the domain but not the domain itself.
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+	port   string
+}
+
+// DebugCORSConfig is the permissive policy used when running in debug
+// mode without an explicit CORS configuration, so that the Vite dev
+// server can talk to the API.
+func DebugCORSConfig() config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: defaultCORSHeaders,
+	}
+}
+
+// newCORSPolicy compiles the policy, rejecting the origins that cannot
+// be parsed and credentials allowed for any origin, which would let any
+// site make authenticated requests
+func newCORSPolicy(cfg config.CORSConfig) (*corsPolicy, error) {
+	p := &corsPolicy{
+		origins:          make(map[string]bool),
+		methods:          cfg.AllowedMethods,
+		headers:          cfg.AllowedHeaders,
+		exposed:          cfg.ExposedHeaders,
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           cfg.MaxAge,
+	}
+
+	if len(p.methods) == 0 {
+		p.methods = defaultCORSMethods
+	}
+	if len(p.headers) == 0 {
+		p.headers = defaultCORSHeaders
+	}
+	if len(p.exposed) == 0 {
+		p.exposed = defaultCORSExposed
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			if cfg.AllowCredentials {
+				return nil, fmt.Errorf("invalid CORS policy: credentials cannot be allowed for any origin")
+			}
+			p.allowAll = true
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return nil, fmt.Errorf("invalid CORS origin provided: %s", origin)
+		}
+
+		if strings.HasPrefix(u.Hostname(), "*.") {
+			p.wildcards = append(p.wildcards, wildcardOrigin{
+				scheme: u.Scheme,
+				suffix: strings.TrimPrefix(u.Hostname(), "*"),
+				port:   u.Port(),
+			})
+			continue
+		}
+
+		p.origins[origin] = true
+	}
+
+	return p, nil
+}
+
+func (p *corsPolicy) allows(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	for _, w := range p.wildcards {
+		if u.Scheme == w.scheme && u.Port() == w.port && strings.HasSuffix(u.Hostname(), w.suffix) && len(u.Hostname()) > len(w.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *corsPolicy) allowsMethod(method string) bool {
+	for _, m := range p.methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CORSMiddleware applies the CORS policy from the configuration, or the
+// debug preset. Preflight requests are answered directly, and rejected
+// with a 403 when the origin or the method is not allowed. It fails when
+// the policy is invalid.
+func (a *Api) CORSMiddleware() (gin.HandlerFunc, error) {
+	cfg := a.Config.HTTP.CORS
+	if cfg.Preset == CORSPresetDebug || (a.Debug && cfg.Preset == "" && len(cfg.AllowedOrigins) == 0) {
+		cfg = DebugCORSConfig()
+	}
+
+	policy, err := newCORSPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		origin := ctx.Request.Header.Get("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+
+		ctx.Writer.Header().Add("Vary", "Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.Request.Header.Get("Access-Control-Request-Method") != ""
+
+		if !policy.allows(origin) {
+			if preflight {
+				abortWithError(ctx, errForbidden())
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		if policy.allowAll && !policy.allowCredentials {
+			ctx.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+		}
+
+		if policy.allowCredentials {
+			ctx.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			ctx.Header("Access-Control-Expose-Headers", strings.Join(policy.exposed, ","))
+			ctx.Next()
+			return
+		}
+
+		if !policy.allowsMethod(ctx.Request.Header.Get("Access-Control-Request-Method")) {
+			abortWithError(ctx, errForbidden())
+			return
+		}
+
+		ctx.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+		ctx.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		ctx.Header("Access-Control-Allow-Methods", strings.Join(policy.methods, ","))
+		ctx.Header("Access-Control-Allow-Headers", strings.Join(policy.headers, ","))
+		if policy.maxAge > 0 {
+			ctx.Header("Access-Control-Max-Age", strconv.Itoa(policy.maxAge))
+		}
+
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}, nil
+}
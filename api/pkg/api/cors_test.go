@@ -0,0 +1,142 @@
+package api_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+func corsConfig(cors config.CORSConfig) *config.Config {
+	cfg := apitest.DefaultConfig()
+	cfg.HTTP.CORS = cors
+
+	return cfg
+}
+
+func corsRequest(t *testing.T, method string, origin string, requestMethod string) *http.Request {
+	t.Helper()
+
+	req := apitest.NewRequest(t, method, "/healthz", nil, "")
+	req.Header.Set("Origin", origin)
+	if requestMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", requestMethod)
+	}
+
+	return req
+}
+
+func TestCORSOrigins(t *testing.T) {
+	h := apitest.New(t, corsConfig(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org", "http://*.example.net:8080"},
+	}))
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://other.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"https://a.example.org:8443", false},
+		{"http://a.example.net:8080", true},
+		{"http://a.example.net", false},
+	}
+
+	for _, c := range cases {
+		res := h.Serve(t, corsRequest(t, http.MethodGet, c.origin, "")).AssertStatus(t, http.StatusOK)
+		if got := res.Header().Get("Access-Control-Allow-Origin"); (got == c.origin) != c.allowed {
+			t.Errorf("%s: expected allowed=%v, got Access-Control-Allow-Origin %q", c.origin, c.allowed, got)
+		}
+		if !strings.Contains(strings.Join(res.Header().Values("Vary"), ","), "Origin") {
+			t.Errorf("%s: the response does not vary on the origin", c.origin)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := apitest.New(t, corsConfig(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		MaxAge:         600,
+	}))
+
+	res := h.Serve(t, corsRequest(t, http.MethodOptions, "https://app.example.com", "POST")).
+		AssertStatus(t, http.StatusNoContent).
+		AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com").
+		AssertHeader(t, "Access-Control-Allow-Methods", "GET,POST").
+		AssertHeader(t, "Access-Control-Max-Age", "600").
+		AssertHeader(t, "Access-Control-Allow-Credentials", "")
+	for _, header := range []string{"X-AUTH-TOKEN", "X-API-KEY", api.CSRFHeader, api.SessionModeHeader} {
+		if !strings.Contains(res.Header().Get("Access-Control-Allow-Headers"), header) {
+			t.Errorf("the header %s is not allowed: %s", header, res.Header().Get("Access-Control-Allow-Headers"))
+		}
+	}
+
+	h.Serve(t, corsRequest(t, http.MethodOptions, "https://app.example.com", "DELETE")).
+		AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+	h.Serve(t, corsRequest(t, http.MethodOptions, "https://evil.example.com", "POST")).
+		AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+
+	// the other requests get the headers they may read
+	res = h.Serve(t, corsRequest(t, http.MethodGet, "https://app.example.com", "")).AssertStatus(t, http.StatusOK)
+	if res.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Fatal("the headers of the response are not exposed")
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	h := apitest.New(t, corsConfig(config.CORSConfig{AllowedOrigins: []string{"*"}}))
+	h.Serve(t, corsRequest(t, http.MethodGet, "https://any.example.com", "")).
+		AssertHeader(t, "Access-Control-Allow-Origin", "*").
+		AssertHeader(t, "Access-Control-Allow-Credentials", "")
+
+	h = apitest.New(t, corsConfig(config.CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowCredentials: true,
+	}))
+	h.Serve(t, corsRequest(t, http.MethodGet, "https://app.example.com", "")).
+		AssertHeader(t, "Access-Control-Allow-Origin", "https://app.example.com").
+		AssertHeader(t, "Access-Control-Allow-Credentials", "true")
+	h.Serve(t, corsRequest(t, http.MethodGet, "https://app.example.org", "")).
+		AssertHeader(t, "Access-Control-Allow-Origin", "").
+		AssertHeader(t, "Access-Control-Allow-Credentials", "")
+}
+
+func TestCORSInvalidConfig(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	cases := []struct {
+		name string
+		cors config.CORSConfig
+		err  string
+	}{
+		{"AnyOriginWithCredentials", config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "credentials"},
+		{"NoScheme", config.CORSConfig{AllowedOrigins: []string{"app.example.com"}}, "invalid CORS origin"},
+		{"Path", config.CORSConfig{AllowedOrigins: []string{"https://app.example.com/ui"}}, "invalid CORS origin"},
+		{"Unparsable", config.CORSConfig{AllowedOrigins: []string{"https://app example.com"}}, "invalid CORS origin"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := api.New(corsConfig(c.cors),
+				api.WithUserService(h.Users),
+				api.WithConfigService(h.Config),
+				api.WithSigningKey(h.Key),
+				api.WithLogger(slog.New(slog.DiscardHandler)),
+			)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
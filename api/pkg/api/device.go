@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/deviceauth"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+const (
+	deviceAuthorizationTTL      = 10 * time.Minute
+	deviceAuthorizationInterval = 5 * time.Second
+)
+
+type DeviceAuthorizationOutput struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceTokenInput struct {
+	DeviceCode string `json:"device_code" binding:"required,max=128"`
+}
+
+type DeviceApproveInput struct {
+	UserCode string `json:"user_code" binding:"required,max=16"`
+}
+
+// deviceAuthError maps the errors of the device authorization store to
+// the error codes of RFC 8628
+func deviceAuthError(err error) error {
+	switch {
+	case errors.Is(err, deviceauth.ErrPending):
+		return NewAPIError(http.StatusBadRequest, CodeAuthorizationPending, "the authorization is pending", nil)
+	case errors.Is(err, deviceauth.ErrSlowDown):
+		return NewAPIError(http.StatusBadRequest, CodeSlowDown, "the device is polling too fast", nil)
+	case errors.Is(err, deviceauth.ErrNotFound):
+		return NewAPIError(http.StatusBadRequest, CodeExpiredToken, "unknown or expired device code", nil)
+	default:
+		return err
+	}
+}
+
+// StartDeviceAuthorization
+//
+//	@Summary		Starts a device authorization
+//	@Description	Starts a device authorization, the user approves it in the UI with the user code while the device polls for its token
+//	@Tags			Authentication
+//	@Produce		json
+//	@Success		200	{object}	DeviceAuthorizationOutput
+//	@Failure		500	{object}	Problem
+//	@Router			/auth/device [post]
+func (a *Api) StartDeviceAuthorization(ctx *gin.Context) {
+	auth, err := a.DeviceAuthStore.Create(ctx.Request.Context(), deviceAuthorizationTTL, deviceAuthorizationInterval)
+	if err != nil {
+		abortWithError(ctx, errInternal("failed to start the device authorization", err))
+		return
+	}
+
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	userCode := deviceauth.FormatUserCode(auth.UserCode)
+	verification := fmt.Sprintf("%s://%s/device", scheme, ctx.Request.Host)
+
+	ctx.JSON(200, &DeviceAuthorizationOutput{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verification,
+		VerificationURIComplete: verification + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(auth.Expires.Sub(a.Clock()).Seconds()),
+		Interval:                int(auth.Interval.Seconds()),
+	})
+}
+
+// DeviceToken
+//
+//	@Summary		Polls for the token of a device authorization
+//	@Description	Polls for the token of a device authorization, fails with authorization_pending until the user approved it
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		DeviceTokenInput	true	"Device code"
+//	@Success		200		{object}	LoginOutput
+//	@Failure		400		{object}	Problem
+//	@Failure		413		{object}	Problem
+//	@Failure		422		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Router			/auth/device/token [post]
+func (a *Api) DeviceToken(ctx *gin.Context) {
+	var input DeviceTokenInput
+
+	if err := bindJSON(ctx, &input); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	userId, err := a.DeviceAuthStore.Poll(ctx.Request.Context(), input.DeviceCode)
+	if err != nil {
+		abortWithError(ctx, deviceAuthError(err))
+		return
+	}
+
+	user, err := a.UserService.GetUserById(ctx.Request.Context(), userId)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	token, err := a.UserService.GenerateSessionToken(ctx.Request.Context(), user)
+	if err != nil {
+		abortWithError(ctx, sessionTokenError(err))
+		return
+	}
+
+	ctx.JSON(200, &LoginOutput{
+		Token: token,
+	})
+}
+
+// ApproveDeviceAuthorization
+//
+//	@Summary		Approves a device authorization
+//	@Description	Approves a device authorization, the device then gets a session of the authenticated user
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body	DeviceApproveInput	true	"User code"
+//	@Success		204
+//	@Failure		400	{object}	Problem
+//	@Failure		401	{object}	Problem
+//	@Failure		413	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		jwt
+//	@Security		apikey
+//	@Router			/auth/device/approve [post]
+func (a *Api) ApproveDeviceAuthorization(ctx *gin.Context) {
+	var input DeviceApproveInput
+
+	if err := bindJSON(ctx, &input); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	user, ok := ctx.MustGet("user").(*userservice.User)
+	if !ok {
+		abortWithError(ctx, errUnauthenticated())
+		return
+	}
+
+	if err := a.DeviceAuthStore.Approve(ctx.Request.Context(), input.UserCode, user.Id); err != nil {
+		abortWithError(ctx, deviceAuthError(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
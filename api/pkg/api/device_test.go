@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestDeviceAuthorization(t *testing.T) {
+	h := apitest.New(t, nil)
+	token, _ := h.LoginAsUser(t, "alice")
+
+	var auth api.DeviceAuthorizationOutput
+	h.Post(t, "/api/v1/auth/device", "", nil).AssertJSON(t, http.StatusOK, &auth)
+	if auth.DeviceCode == "" || auth.UserCode == "" || auth.ExpiresIn <= 0 || auth.Interval <= 0 {
+		t.Fatalf("unexpected authorization: %+v", auth)
+	}
+	if !strings.HasSuffix(auth.VerificationURI, "/device") || !strings.Contains(auth.VerificationURIComplete, "user_code=") {
+		t.Fatalf("unexpected verification uris: %+v", auth)
+	}
+
+	poll := &api.DeviceTokenInput{DeviceCode: auth.DeviceCode}
+	h.Post(t, "/api/v1/auth/device/token", "", poll).AssertProblem(t, http.StatusBadRequest, api.CodeAuthorizationPending)
+	h.Post(t, "/api/v1/auth/device/token", "", poll).AssertProblem(t, http.StatusBadRequest, api.CodeSlowDown)
+
+	approve := &api.DeviceApproveInput{UserCode: auth.UserCode}
+	h.Post(t, "/api/v1/auth/device/approve", "", approve).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	h.Post(t, "/api/v1/auth/device/approve", token, &api.DeviceApproveInput{UserCode: "BCDF-GHJK"}).
+		AssertProblem(t, http.StatusBadRequest, api.CodeExpiredToken)
+	h.Post(t, "/api/v1/auth/device/approve", token, approve).AssertStatus(t, http.StatusNoContent)
+
+	var login api.LoginOutput
+	h.Clock.Advance(time.Duration(auth.Interval) * time.Second)
+	h.Post(t, "/api/v1/auth/device/token", "", poll).AssertJSON(t, http.StatusOK, &login)
+
+	var profile api.ProfileOutput
+	h.Get(t, "/api/v1/user/profile", login.Token).AssertJSON(t, http.StatusOK, &profile)
+	if profile.Username != "alice" {
+		t.Fatalf("logged in as %s instead of alice", profile.Username)
+	}
+
+	// the authorization can only be used once
+	h.Post(t, "/api/v1/auth/device/token", "", poll).AssertProblem(t, http.StatusBadRequest, api.CodeExpiredToken)
+}
+
+func TestDeviceAuthorizationExpiry(t *testing.T) {
+	h := apitest.New(t, nil)
+	token, _ := h.LoginAsUser(t, "alice")
+
+	var auth api.DeviceAuthorizationOutput
+	h.Post(t, "/api/v1/auth/device", "", nil).AssertJSON(t, http.StatusOK, &auth)
+
+	h.Clock.Advance(time.Duration(auth.ExpiresIn+1) * time.Second)
+
+	h.Post(t, "/api/v1/auth/device/approve", token, &api.DeviceApproveInput{UserCode: auth.UserCode}).
+		AssertProblem(t, http.StatusBadRequest, api.CodeExpiredToken)
+	h.Post(t, "/api/v1/auth/device/token", "", &api.DeviceTokenInput{DeviceCode: auth.DeviceCode}).
+		AssertProblem(t, http.StatusBadRequest, api.CodeExpiredToken)
+}
@@ -3,6 +3,7 @@ package api
 //	@title			Swagger Example API
 //	@version		1.0
 //	@description	This is a sample server celler server.
+//	@description	The routes are also served without the version prefix, directly under /api, those aliases are deprecated and answer with a Deprecation header.
 //	@termsOfService	http://swagger.io/terms/
 
 //	@contact.name	API Support
@@ -12,7 +13,7 @@ package api
 //	@license.name	Apache 2.0
 //	@license.url	http://www.apache.org/licenses/LICENSE-2.0.html
 
-//	@BasePath	/api
+//	@BasePath	/api/v1
 
 //	@securityDefinitions.apikey	jwt
 //	@in							header
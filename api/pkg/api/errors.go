@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"gorm.io/gorm"
+)
+
+// ErrorCode is a stable, machine readable identifier of an error that
+// clients can rely on, unlike the human readable title and detail.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest       ErrorCode = "invalid_request"
+	CodeValidationFailed     ErrorCode = "validation_failed"
+	CodePayloadTooLarge      ErrorCode = "payload_too_large"
+	CodeUnauthenticated      ErrorCode = "unauthenticated"
+	CodeInvalidCredentials   ErrorCode = "invalid_credentials"
+	CodeForbidden            ErrorCode = "forbidden"
+	CodeCSRFFailed           ErrorCode = "csrf_failed"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeConflict             ErrorCode = "conflict"
+	CodeOIDCError            ErrorCode = "oidc_error"
+	CodeRateLimited          ErrorCode = "rate_limited"
+	CodeBadGateway           ErrorCode = "bad_gateway"
+	CodeAuthorizationPending ErrorCode = "authorization_pending"
+	CodeSlowDown             ErrorCode = "slow_down"
+	CodeExpiredToken         ErrorCode = "expired_token"
+	CodeInternal             ErrorCode = "internal_error"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, it is the body of
+// every error response of the API.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      ErrorCode    `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// APIError is an error that knows how it should be presented to the
+// client. The wrapped error is only ever logged, never sent back.
+type APIError struct {
+	Status int
+	Code   ErrorCode
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Code, e.Detail, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func NewAPIError(status int, code ErrorCode, detail string, err error) *APIError {
+	return &APIError{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Err:    err,
+	}
+}
+
+func errBadRequest(detail string, err error) *APIError {
+	return NewAPIError(http.StatusBadRequest, CodeInvalidRequest, detail, err)
+}
+
+func errUnauthenticated() *APIError {
+	return NewAPIError(http.StatusUnauthorized, CodeUnauthenticated, "authentication required", nil)
+}
+
+func errForbidden() *APIError {
+	return NewAPIError(http.StatusForbidden, CodeForbidden, "access denied", nil)
+}
+
+func errNotFound(detail string) *APIError {
+	return NewAPIError(http.StatusNotFound, CodeNotFound, detail, nil)
+}
+
+func errOIDC(detail string, err error) *APIError {
+	return NewAPIError(http.StatusBadGateway, CodeOIDCError, detail, err)
+}
+
+func errBadGateway(detail string, err error) *APIError {
+	return NewAPIError(http.StatusBadGateway, CodeBadGateway, detail, err)
+}
+
+func errInternal(detail string, err error) *APIError {
+	return NewAPIError(http.StatusInternalServerError, CodeInternal, detail, err)
+}
+
+// toAPIError maps errors returned by the services to their API
+// representation. Unknown errors are internal errors and their message
+// is not disclosed.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch {
+	case errors.Is(err, userservice.ErrUserNotFound):
+		return NewAPIError(http.StatusNotFound, CodeNotFound, "user not found", err)
+	case errors.Is(err, userservice.ErrInvalidCredentials):
+		return NewAPIError(http.StatusUnauthorized, CodeInvalidCredentials, "invalid credentials", err)
+	case errors.Is(err, userservice.ErrInvalidSession):
+		return NewAPIError(http.StatusUnauthorized, CodeUnauthenticated, "invalid or expired session", err)
+	case errors.Is(err, userservice.ErrUserDeactivated):
+		return NewAPIError(http.StatusForbidden, CodeForbidden, "user is deactivated", err)
+	case errors.Is(err, userservice.ErrUserExists):
+		return NewAPIError(http.StatusConflict, CodeConflict, "username or email already taken", err)
+	case errors.Is(err, userservice.ErrSessionNotFound):
+		return NewAPIError(http.StatusNotFound, CodeNotFound, "session not found", err)
+	case errors.Is(err, configservice.ErrOIDCProviderExists):
+		return NewAPIError(http.StatusConflict, CodeConflict, "oidc provider already exists", err)
+	case errors.Is(err, configservice.ErrOIDCProviderNotFound):
+		return NewAPIError(http.StatusNotFound, CodeNotFound, "oidc provider not found", err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewAPIError(http.StatusNotFound, CodeNotFound, "resource not found", err)
+	default:
+		return errInternal("internal server error", err)
+	}
+}
+
+// abortWithError records the error on the context and stops the chain,
+// the response itself is written by ErrorMiddleware.
+func abortWithError(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
+
+func newProblem(ctx *gin.Context, apiErr *APIError) *Problem {
+	return &Problem{
+		Type:      "urn:go-vue:error:" + string(apiErr.Code),
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  ctx.Request.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: ctx.GetString(requestIDKey),
+		Errors:    apiErr.Fields,
+	}
+}
+
+// ErrorMiddleware renders the last error recorded on the context as a
+// problem details response, unless a response was already written.
+func (a *Api) ErrorMiddleware(ctx *gin.Context) {
+	ctx.Next()
+
+	if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+		return
+	}
+
+	apiErr := toAPIError(ctx.Errors.Last().Err)
+	ctx.Header("Content-Type", problemContentType)
+	ctx.JSON(apiErr.Status, newProblem(ctx, apiErr))
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"gorm.io/gorm"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	// secret is the text of the internal errors, never to be sent back
+	const secret = "dial tcp 10.0.0.1:5432: password authentication failed for user app"
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   ErrorCode
+		detail string
+	}{
+		{"UserNotFound", userservice.ErrUserNotFound, http.StatusNotFound, CodeNotFound, "user not found"},
+		{"InvalidCredentials", userservice.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials, "invalid credentials"},
+		{"InvalidSession", userservice.ErrInvalidSession, http.StatusUnauthorized, CodeUnauthenticated, "invalid or expired session"},
+		{"UserDeactivated", userservice.ErrUserDeactivated, http.StatusForbidden, CodeForbidden, "user is deactivated"},
+		{"UserExists", userservice.ErrUserExists, http.StatusConflict, CodeConflict, "username or email already taken"},
+		{"SessionNotFound", userservice.ErrSessionNotFound, http.StatusNotFound, CodeNotFound, "session not found"},
+		{"OIDCProviderExists", configservice.ErrOIDCProviderExists, http.StatusConflict, CodeConflict, "oidc provider already exists"},
+		{"OIDCProviderNotFound", configservice.ErrOIDCProviderNotFound, http.StatusNotFound, CodeNotFound, "oidc provider not found"},
+		{"RecordNotFound", gorm.ErrRecordNotFound, http.StatusNotFound, CodeNotFound, "resource not found"},
+		{"Wrapped", fmt.Errorf("could not get the user: %w", userservice.ErrUserNotFound), http.StatusNotFound, CodeNotFound, "user not found"},
+		{"APIError", errBadGateway("the provider is unreachable", errors.New(secret)), http.StatusBadGateway, CodeBadGateway, "the provider is unreachable"},
+		{"WrappedAPIError", fmt.Errorf("context: %w", errForbidden()), http.StatusForbidden, CodeForbidden, "access denied"},
+		{"Internal", errInternal("could not list the users", errors.New(secret)), http.StatusInternalServerError, CodeInternal, "could not list the users"},
+		{"Unknown", errors.New(secret), http.StatusInternalServerError, CodeInternal, "internal server error"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	a := &Api{Logger: slog.New(slog.DiscardHandler)}
+	router := gin.New()
+	router.Use(a.RequestIDMiddleware, a.ErrorMiddleware)
+
+	for _, c := range cases {
+		router.GET("/"+c.name, func(ctx *gin.Context) {
+			abortWithError(ctx, c.err)
+		})
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/"+c.name, nil))
+
+			if res.Code != c.status {
+				t.Fatalf("expected the status %d, got %d", c.status, res.Code)
+			}
+			if ct := res.Header().Get("Content-Type"); ct != problemContentType {
+				t.Fatalf("expected a problem, got the content type %q", ct)
+			}
+			if strings.Contains(res.Body.String(), "10.0.0.1") {
+				t.Fatalf("the internal error is disclosed: %s", res.Body.String())
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(res.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("could not decode the problem: %s", err)
+			}
+
+			want := Problem{
+				Type:      "urn:go-vue:error:" + string(c.code),
+				Title:     http.StatusText(c.status),
+				Status:    c.status,
+				Detail:    c.detail,
+				Instance:  "/" + c.name,
+				Code:      c.code,
+				RequestID: res.Header().Get(requestIDHeader),
+			}
+			if fmt.Sprint(problem) != fmt.Sprint(want) {
+				t.Fatalf("expected %+v, got %+v", want, problem)
+			}
+		})
+	}
+}
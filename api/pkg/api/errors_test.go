@@ -0,0 +1,15 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestUnknownAPIRoute(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	h.Get(t, "/api/v1/nope", "").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	configmodels "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql/models"
+	usermodels "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql/models"
+)
+
+const (
+	CheckStatusOk     = "ok"
+	CheckStatusFailed = "failed"
+
+	defaultReadinessCheckTimeout = 5 * time.Second
+	defaultOIDCCheckInterval     = time.Minute
+)
+
+type HealthOutput struct {
+	Status string `json:"status"`
+}
+
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type ReadinessOutput struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// readinessCheck is a dependency check. Critical checks failing will
+// make the instance not ready, the others are only informative.
+type readinessCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) error
+}
+
+// cachedCheck runs a check at most once per interval, its last result
+// being reported in between
+type cachedCheck struct {
+	lock     sync.Mutex
+	interval time.Duration
+	now      func() time.Time
+	check    func(ctx context.Context) error
+	last     time.Time
+	err      error
+}
+
+func (c *cachedCheck) run(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.last.IsZero() && c.now().Sub(c.last) < c.interval {
+		return c.err
+	}
+
+	c.err = c.check(ctx)
+	c.last = c.now()
+
+	return c.err
+}
+
+// newOIDCCheck returns the discovery check of the OIDC providers, kept
+// for the configured interval
+func (a *Api) newOIDCCheck(cfg config.HealthConfig) *cachedCheck {
+	interval := cfg.OIDCInterval
+	if interval <= 0 {
+		interval = defaultOIDCCheckInterval
+	}
+
+	return &cachedCheck{interval: interval, now: a.Clock, check: a.checkOIDCDiscovery}
+}
+
+// Healthz is the liveness probe, it only tells the process is up and
+// serving requests.
+func (a *Api) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, &HealthOutput{Status: CheckStatusOk})
+}
+
+// Readyz is the readiness probe. It runs every dependency check and
+// answers with a 503 if any of the critical ones failed. The probe is
+// not authenticated, the errors are logged rather than reported.
+func (a *Api) Readyz(ctx *gin.Context) {
+	output := ReadinessOutput{
+		Status: CheckStatusOk,
+		Checks: make([]CheckResult, 0),
+	}
+
+	timeout := a.Config.Health.Timeout
+	if timeout <= 0 {
+		timeout = defaultReadinessCheckTimeout
+	}
+
+	for _, c := range a.readinessChecks() {
+		checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		start := time.Now()
+		err := c.check(checkCtx)
+		cancel()
+
+		result := CheckResult{
+			Name:     c.name,
+			Status:   CheckStatusOk,
+			Critical: c.critical,
+			Duration: time.Since(start).String(),
+		}
+
+		if err != nil {
+			a.Logger.WarnContext(ctx.Request.Context(), "readiness check failed", "check", c.name, "critical", c.critical, "error", err)
+
+			result.Status = CheckStatusFailed
+			result.Error = "the check failed"
+			if errors.Is(err, context.DeadlineExceeded) {
+				result.Error = "the check timed out"
+			}
+			if c.critical {
+				output.Status = CheckStatusFailed
+			}
+		}
+
+		output.Checks = append(output.Checks, result)
+	}
+
+	status := http.StatusOK
+	if output.Status != CheckStatusOk {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.JSON(status, &output)
+}
+
+func (a *Api) readinessChecks() []readinessCheck {
+	var checks []readinessCheck
+
+	// the injected services may not use the database at all
+	if a.DB != nil {
+		checks = append(checks,
+			readinessCheck{name: "database", critical: true, check: a.checkDatabase},
+			readinessCheck{name: "migrations", critical: true, check: a.checkMigrations},
+		)
+	}
+
+	checks = append(checks, readinessCheck{name: "signing_key", critical: true, check: a.checkSigningKey})
+
+	if a.Config.Health.CheckOIDC {
+		checks = append(checks, readinessCheck{name: "oidc", critical: false, check: a.oidcCheck.run})
+	}
+
+	return checks
+}
+
+func (a *Api) checkDatabase(ctx context.Context) error {
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func (a *Api) checkMigrations(ctx context.Context) error {
+	migrator := a.DB.WithContext(ctx).Migrator()
+	for _, model := range []any{
+		&usermodels.User{},
+		&usermodels.Session{},
+		&usermodels.APIKey{},
+		&configmodels.OIDCProvider{},
+	} {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("missing table for %T", model)
+		}
+	}
+
+	return nil
+}
+
+func (a *Api) checkSigningKey(ctx context.Context) error {
+	if a.SigninigKey == nil || a.SigningPublicKey == nil {
+		return fmt.Errorf("no signing key loaded")
+	}
+
+	return nil
+}
+
+func (a *Api) checkOIDCDiscovery(ctx context.Context) error {
+	providers, err := a.ConfigService.GetOIDCProviders(ctx)
+	if err != nil {
+		return err
+	}
+
+	oidcCtx := oidc.ClientContext(ctx, a.HTTPClient)
+	for _, p := range providers {
+		if !p.Active {
+			continue
+		}
+
+		if _, err := oidc.NewProvider(oidcCtx, p.Issuer); err != nil {
+			return fmt.Errorf("provider %s: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
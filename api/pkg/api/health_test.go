@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+func TestHealthz(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	var out api.HealthOutput
+	h.Get(t, "/healthz", "").AssertJSON(t, http.StatusOK, &out)
+	if out.Status != api.CheckStatusOk {
+		t.Fatalf("unexpected status: %s", out.Status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	var out api.ReadinessOutput
+	h.Get(t, "/readyz", "").AssertJSON(t, http.StatusOK, &out)
+	if out.Status != api.CheckStatusOk {
+		t.Fatalf("unexpected status: %+v", out)
+	}
+
+	// the in-memory services have no database to check
+	for _, c := range out.Checks {
+		if c.Name == "database" || c.Name == "migrations" {
+			t.Fatalf("unexpected check %s without a database", c.Name)
+		}
+	}
+}
+
+// sqliteAPI builds the API on top of the sql services and a sqlite
+// database in a temporary directory, which the api opens itself
+func sqliteAPI(t *testing.T, cfg *config.Config, opts ...api.Option) *api.Api {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+
+	cfg.Storage = config.StorageConfig{Driver: "sqlite3", URL: filepath.Join(t.TempDir(), "db.sqlite3")}
+	opts = append([]api.Option{api.WithSigningKey(key), api.WithLogger(slog.New(slog.DiscardHandler))}, opts...)
+
+	a, err := api.New(cfg, opts...)
+	if err != nil {
+		t.Fatalf("could not create the api: %s", err)
+	}
+	t.Cleanup(func() { _ = a.Shutdown(t.Context()) })
+
+	return a
+}
+
+// readyz probes the readiness of a and returns the check of the given
+// name along with the whole output
+func readyz(t *testing.T, a *api.Api, status int, name string) (api.CheckResult, string) {
+	t.Helper()
+
+	res := httptest.NewRecorder()
+	a.Router.ServeHTTP(res, apitest.NewRequest(t, http.MethodGet, "/readyz", nil, ""))
+	if res.Code != status {
+		t.Fatalf("expected the status %d, got %d: %s", status, res.Code, res.Body.String())
+	}
+
+	var out api.ReadinessOutput
+	if err := json.Unmarshal(res.Body.Bytes(), &out); err != nil {
+		t.Fatalf("could not decode the readiness: %s", err)
+	}
+	for _, c := range out.Checks {
+		if c.Name == name {
+			return c, res.Body.String()
+		}
+	}
+	t.Fatalf("no %s check in %s", name, res.Body.String())
+
+	return api.CheckResult{}, ""
+}
+
+func TestReadyzCriticalChecks(t *testing.T) {
+	var logs bytes.Buffer
+	a := sqliteAPI(t, apitest.DefaultConfig(), api.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	for _, name := range []string{"database", "migrations", "signing_key"} {
+		if c, _ := readyz(t, a, http.StatusOK, name); c.Status != api.CheckStatusOk || !c.Critical {
+			t.Fatalf("unexpected %s check: %+v", name, c)
+		}
+	}
+
+	if err := a.DB.Migrator().DropTable("oidc_providers"); err != nil {
+		t.Fatalf("could not drop the table: %s", err)
+	}
+	if c, _ := readyz(t, a, http.StatusServiceUnavailable, "migrations"); c.Status != api.CheckStatusFailed || c.Error != "the check failed" {
+		t.Fatalf("unexpected migrations check: %+v", c)
+	}
+
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		t.Fatalf("could not get the database: %s", err)
+	}
+	_ = sqlDB.Close()
+
+	c, body := readyz(t, a, http.StatusServiceUnavailable, "database")
+	if c.Status != api.CheckStatusFailed || c.Error != "the check failed" {
+		t.Fatalf("unexpected database check: %+v", c)
+	}
+	if strings.Contains(body, "sql") || strings.Contains(body, "closed") {
+		t.Fatalf("the error of the database is reported: %s", body)
+	}
+	if !strings.Contains(logs.String(), "check=database") || !strings.Contains(logs.String(), "database is closed") {
+		t.Fatalf("the error of the database is not logged: %s", logs.String())
+	}
+}
+
+func TestReadyzOIDC(t *testing.T) {
+	var discoveries atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		http.Error(w, "internal details", http.StatusInternalServerError)
+	}))
+	t.Cleanup(idp.Close)
+
+	cfg := apitest.DefaultConfig()
+	cfg.Health.CheckOIDC = true
+	cfg.Security.OIDC = map[string]config.OIDCConfig{"down": {Issuer: idp.URL, ClientID: "id", ClientSecret: "secret"}}
+	h := apitest.New(t, cfg)
+
+	// the failure is reported without making the instance unready
+	c, body := readyz(t, h.API, http.StatusOK, "oidc")
+	if c.Status != api.CheckStatusFailed || c.Critical || c.Error != "the check failed" {
+		t.Fatalf("unexpected oidc check: %+v", c)
+	}
+	if strings.Contains(body, idp.URL) || strings.Contains(body, "internal details") {
+		t.Fatalf("the error of the discovery is reported: %s", body)
+	}
+
+	// the result is kept for the interval
+	readyz(t, h.API, http.StatusOK, "oidc")
+	if discoveries.Load() != 1 {
+		t.Fatalf("expected a single discovery, got %d", discoveries.Load())
+	}
+	h.Clock.Advance(time.Minute)
+	readyz(t, h.API, http.StatusOK, "oidc")
+	if discoveries.Load() != 2 {
+		t.Fatalf("expected the discovery to run again, got %d", discoveries.Load())
+	}
+}
+
+func TestReadyzTimeout(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(idp.Close)
+
+	cfg := apitest.DefaultConfig()
+	cfg.Health.CheckOIDC = true
+	cfg.Health.Timeout = 50 * time.Millisecond
+	cfg.Security.OIDC = map[string]config.OIDCConfig{"slow": {Issuer: idp.URL, ClientID: "id", ClientSecret: "secret"}}
+	h := apitest.New(t, cfg)
+
+	if c, _ := readyz(t, h.API, http.StatusOK, "oidc"); c.Status != api.CheckStatusFailed || c.Error != "the check timed out" {
+		t.Fatalf("unexpected oidc check: %+v", c)
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/scheduler"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+const (
+	JobSessionCleanup = "sessionCleanup"
+	JobAPIKeyCleanup  = "apiKeyCleanup"
+	JobStaleOIDCUsers = "staleOidcUsers"
+)
+
+type JobRunOutput struct {
+	Holder   string    `json:"holder"`
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Result   string    `json:"result,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type JobOutput struct {
+	Name     string        `json:"name"`
+	Interval string        `json:"interval"`
+	Running  bool          `json:"running"`
+	Next     *time.Time    `json:"next,omitempty"`
+	LastRun  *JobRunOutput `json:"last_run,omitempty"`
+}
+
+type JobsOutput struct {
+	Enabled  bool        `json:"enabled"`
+	Instance string      `json:"instance,omitempty"`
+	Leader   bool        `json:"leader"`
+	Jobs     []JobOutput `json:"jobs"`
+}
+
+// maintenanceJobs returns the jobs the scheduler can run, with their
+// default interval
+func (a *Api) maintenanceJobs() []scheduler.Job {
+	jobs := []scheduler.Job{
+		{
+			Name:     JobSessionCleanup,
+			Interval: 10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := a.UserService.DeleteExpiredSessions(ctx)
+				return fmt.Sprintf("%d expired sessions deleted", count), err
+			},
+		},
+		{
+			Name:     JobAPIKeyCleanup,
+			Interval: time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := a.UserService.DeleteExpiredAPIKeys(ctx)
+				return fmt.Sprintf("%d expired api keys deleted", count), err
+			},
+		},
+	}
+
+	if after := a.Config.Scheduler.StaleOIDCUsersAfter; after > 0 {
+		jobs = append(jobs, scheduler.Job{
+			Name:     JobStaleOIDCUsers,
+			Interval: 24 * time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				count, err := a.UserService.DeactivateStaleUsers(ctx, userservice.UserKindOIDC, a.Clock().Add(-after))
+				return fmt.Sprintf("%d stale oidc users deactivated", count), err
+			},
+		})
+	}
+
+	return jobs
+}
+
+// newScheduler creates the scheduler described by the configuration, it
+// shares its lease through the database when there is one
+func (a *Api) newScheduler(cfg config.SchedulerConfig) (*scheduler.Scheduler, error) {
+	if cfg.Tick < 0 {
+		return nil, fmt.Errorf("invalid scheduler tick: %s", cfg.Tick)
+	}
+
+	var store scheduler.Store = scheduler.NewMemoryStore()
+	if a.DB != nil {
+		sqlStore, err := scheduler.NewSQLStore(a.DB)
+		if err != nil {
+			return nil, err
+		}
+		store = sqlStore
+	}
+
+	s := scheduler.New(store)
+	s.Logger = a.Logger
+	s.Now = a.Clock
+	if cfg.Tick > 0 {
+		s.Tick = cfg.Tick
+	}
+
+	for name := range cfg.Jobs {
+		if name != JobSessionCleanup && name != JobAPIKeyCleanup && name != JobStaleOIDCUsers {
+			return nil, fmt.Errorf("invalid scheduler job provided: %s", name)
+		}
+	}
+
+	for _, job := range a.maintenanceJobs() {
+		jobCfg := cfg.Jobs[job.Name]
+		if jobCfg.Disabled {
+			continue
+		}
+		if jobCfg.Interval != 0 {
+			job.Interval = jobCfg.Interval
+		}
+
+		if err := s.Add(job); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// AdminJobs Status of the maintenance jobs
+//
+//	@Summary		Returns the status of the maintenance jobs
+//	@Description	Returns the maintenance jobs with their last run, which is shared by every instance, and whether the instance answering is the one running them
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	JobsOutput
+//	@Failure		401	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		jwt
+//	@Security		apikey
+//	@Router			/admin/jobs [get]
+func (a *Api) AdminJobs(ctx *gin.Context) {
+	out := JobsOutput{Jobs: make([]JobOutput, 0)}
+	if a.Scheduler == nil {
+		ctx.JSON(200, &out)
+		return
+	}
+
+	status, err := a.Scheduler.Status(ctx.Request.Context())
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	out.Enabled = true
+	out.Instance = status.Instance
+	out.Leader = status.Leader
+	for _, job := range status.Jobs {
+		jo := JobOutput{
+			Name:     job.Name,
+			Interval: job.Interval.String(),
+			Running:  job.Running,
+		}
+
+		if job.LastRun != nil {
+			next := job.Next
+			jo.Next = &next
+			jo.LastRun = &JobRunOutput{
+				Holder:   job.LastRun.Holder,
+				Started:  job.LastRun.Started,
+				Duration: job.LastRun.Duration.String(),
+				Result:   job.LastRun.Result,
+				Error:    job.LastRun.Error,
+			}
+		}
+
+		out.Jobs = append(out.Jobs, jo)
+	}
+
+	ctx.JSON(200, &out)
+}
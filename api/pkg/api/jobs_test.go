@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+func TestAdminJobs(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.Scheduler.StaleOIDCUsersAfter = 24 * time.Hour
+	cfg.Scheduler.Jobs = map[string]config.SchedulerJobConfig{
+		api.JobAPIKeyCleanup: {Disabled: true},
+	}
+	h := apitest.New(t, cfg)
+
+	stale, err := h.Users.CreateUser(t.Context(), "stale", "stale@example.com", "", string(userservice.UserKindOIDC), false, "")
+	if err != nil {
+		t.Fatalf("could not create the oidc user: %s", err)
+	}
+	h.Clock.Advance(25 * time.Hour)
+
+	admin := h.LoginAsAdmin(t)
+	user, _ := h.LoginAsUser(t, "alice")
+
+	var out api.JobsOutput
+	h.Get(t, "/api/v1/admin/jobs", admin).AssertJSON(t, http.StatusOK, &out)
+	if !out.Enabled || out.Leader || len(out.Jobs) != 2 {
+		t.Fatalf("unexpected jobs before the first run: %+v", out)
+	}
+	for _, job := range out.Jobs {
+		if job.LastRun != nil || job.Next != nil {
+			t.Fatalf("%s: unexpected run before the first one: %+v", job.Name, job)
+		}
+	}
+
+	if err := h.API.Scheduler.Step(t.Context()); err != nil {
+		t.Fatalf("could not run the jobs: %s", err)
+	}
+
+	got, err := h.Users.GetUserById(t.Context(), stale.Id)
+	if err != nil {
+		t.Fatalf("could not get the oidc user: %s", err)
+	}
+	if got.Active {
+		t.Fatal("the stale oidc user is still active")
+	}
+
+	// the jobs are not due again until their interval elapsed
+	h.Clock.Advance(time.Minute)
+	if err := h.API.Scheduler.Step(t.Context()); err != nil {
+		t.Fatalf("could not run the jobs: %s", err)
+	}
+
+	out = api.JobsOutput{}
+	h.Get(t, "/api/v1/admin/jobs", admin).AssertJSON(t, http.StatusOK, &out)
+	if !out.Leader || out.Instance == "" {
+		t.Fatalf("expected the instance to be the leader: %+v", out)
+	}
+
+	results := map[string]string{
+		api.JobSessionCleanup: "0 expired sessions deleted",
+		api.JobStaleOIDCUsers: "1 stale oidc users deactivated",
+	}
+	for _, job := range out.Jobs {
+		if job.LastRun == nil || job.Next == nil {
+			t.Fatalf("%s: expected a run: %+v", job.Name, job)
+		}
+		if job.LastRun.Result != results[job.Name] || job.LastRun.Error != "" || job.LastRun.Holder != out.Instance {
+			t.Fatalf("%s: unexpected run: %+v", job.Name, job.LastRun)
+		}
+		interval, _ := time.ParseDuration(job.Interval)
+		if !job.Next.Equal(h.Clock.Now().Add(-time.Minute).Add(interval)) {
+			t.Fatalf("%s: unexpected next run: %s", job.Name, job.Next)
+		}
+	}
+
+	h.Get(t, "/api/v1/admin/jobs", user).AssertProblem(t, http.StatusForbidden, api.CodeForbidden)
+	h.Get(t, "/api/v1/admin/jobs", "").AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
+
+func TestAdminJobsDisabled(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.Scheduler.Disabled = true
+	h := apitest.New(t, cfg)
+
+	var out api.JobsOutput
+	h.Get(t, "/api/v1/admin/jobs", h.LoginAsAdmin(t)).AssertJSON(t, http.StatusOK, &out)
+	if out.Enabled || len(out.Jobs) != 0 {
+		t.Fatalf("unexpected jobs: %+v", out)
+	}
+
+	cfg = apitest.DefaultConfig()
+	cfg.Scheduler.Jobs = map[string]config.SchedulerJobConfig{"unknown": {}}
+	_, err := api.New(cfg,
+		api.WithUserService(h.Users),
+		api.WithConfigService(h.Config),
+		api.WithSigningKey(h.Key),
+		api.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	if err == nil || !strings.Contains(err.Error(), "invalid scheduler job") {
+		t.Fatalf("expected an unknown job to be rejected, got %v", err)
+	}
+}
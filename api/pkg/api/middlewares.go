@@ -1,49 +1,110 @@
 package api
 
 import (
+	"log/slog"
+	"regexp"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
 )
 
-func (a *Api) DebugCORSMiddleware(ctx *gin.Context) {
-	if a.Debug {
-		ctx.Header("Access-Control-Allow-Origin", "*")
-		ctx.Header("Access-Control-Allow-Headers", "Content-Type,X-AUTH-TOKEN,X-API-KEY")
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
 
-		if ctx.Request.Method == "OPTIONS" {
-			ctx.AbortWithStatus(204)
-			return
-		}
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware reuses the `X-Request-ID` provided by the client
+// if it looks sane, generates one otherwise. The id is sent back in the
+// response headers, stored in the `request_id` key of the context and
+// attached to the request logger.
+func (a *Api) RequestIDMiddleware(ctx *gin.Context) {
+	id := ctx.Request.Header.Get(requestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = uuid.NewString()
 	}
 
+	ctx.Set(requestIDKey, id)
+	ctx.Header(requestIDHeader, id)
+
+	logger := a.Logger.With("request_id", id)
+	ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), logger))
+
 	ctx.Next()
 }
 
-func (a *Api) UserTokenMiddleware(ctx *gin.Context) {
-	token := ctx.Request.Header.Get("X-AUTH-TOKEN")
-	if token == "" {
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
-		return
+// LoggerMiddleware logs every request once it has been handled, using
+// the logger carried by the request so that fields added along the way,
+// such as the user id, are included.
+func (a *Api) LoggerMiddleware(ctx *gin.Context) {
+	start := time.Now()
+	path := ctx.Request.URL.Path
+
+	ctx.Next()
+
+	status := ctx.Writer.Status()
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	} else if status >= 400 {
+		level = slog.LevelWarn
 	}
 
-	session, user, err := a.UserService.VerifySessionToken(token)
-	if err != nil {
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
-		return
+	attrs := []any{
+		"method", ctx.Request.Method,
+		"path", path,
+		"status", status,
+		"duration", time.Since(start),
+		"client_ip", ctx.ClientIP(),
+	}
+	if len(ctx.Errors) != 0 {
+		attrs = append(attrs, "errors", ctx.Errors.String())
 	}
 
+	logging.FromContext(ctx.Request.Context()).Log(ctx.Request.Context(), level, "request", attrs...)
+}
+
+// setAuthenticated stores the authenticated user and session on the
+// context and attaches their ids to the request logger.
+func setAuthenticated(ctx *gin.Context, user *userservice.User, session *userservice.Session) {
 	ctx.Set("user", user)
 	ctx.Set("session", session)
 
+	logger := logging.FromContext(ctx.Request.Context()).With("user_id", user.Id, "session_id", session.Id)
+	ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), logger))
+}
+
+func (a *Api) UserTokenMiddleware(ctx *gin.Context) {
+	var session *userservice.Session
+	var user *userservice.User
+	var err error
+
+	if token := ctx.Request.Header.Get("X-AUTH-TOKEN"); token != "" {
+		session, user, err = a.UserService.VerifySessionToken(ctx.Request.Context(), token)
+	} else {
+		_, session, user, err = a.cookieSession(ctx)
+	}
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	setAuthenticated(ctx, user, session)
+
 	ctx.Next()
 }
 
 // RequiresUserLogin checks the user is logged in, and optionally
 // if it is an admin of the instance. The logic here will check if
-// there is an authentication JWT for the frontend first, and then
-// check if the user provided an api key. In both cases the `user`
-// key will be set on the context, and if the user is loggin in with
-// a jwt, the `session` key will be populated too.
+// there is an authentication JWT for the frontend first, then
+// check if the user provided an api key, and finally if the browser
+// sent a session cookie. In all cases the `user` key will be set on
+// the context, and if the user is loggin in with a jwt, the `session`
+// key will be populated too.
 func (a *Api) RequiresUserLogin(requiresAdmin bool) func(*gin.Context) {
 	return func(ctx *gin.Context) {
 		var user *userservice.User
@@ -52,43 +113,52 @@ func (a *Api) RequiresUserLogin(requiresAdmin bool) func(*gin.Context) {
 
 		token := ctx.Request.Header.Get("X-AUTH-TOKEN")
 		if token != "" {
-			session, user, err = a.UserService.VerifySessionToken(token)
+			session, user, err = a.UserService.VerifySessionToken(ctx.Request.Context(), token)
 			if err != nil {
-				ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
+				abortWithError(ctx, err)
 				return
 			}
 
 			if requiresAdmin && !user.Admin {
-				ctx.AbortWithStatusJSON(401, gin.H{"error": "access denied"})
+				abortWithError(ctx, errForbidden())
 				return
 			}
 
-			ctx.Set("user", user)
-			ctx.Set("session", session)
+			setAuthenticated(ctx, user, session)
 			ctx.Next()
 			return
 		} else {
 			token := ctx.Request.Header.Get("X-API-KEY")
 			if token != "" {
-				session, user, err = a.UserService.VerifySessionToken(token)
+				session, user, err = a.UserService.VerifySessionToken(ctx.Request.Context(), token)
 				if err != nil {
-					ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
+					abortWithError(ctx, err)
 					return
 				}
 
 				if requiresAdmin && !user.Admin {
-					ctx.AbortWithStatusJSON(401, gin.H{"error": "access denied"})
+					abortWithError(ctx, errForbidden())
 					return
 				}
 
-				ctx.Set("user", user)
-				ctx.Set("session", session)
+				setAuthenticated(ctx, user, session)
 				ctx.Next()
 				return
 			}
 		}
 
-		ctx.AbortWithStatusJSON(401, gin.H{"error": "unauthenticated"})
-		return
+		_, session, user, err = a.cookieSession(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		if requiresAdmin && !user.Admin {
+			abortWithError(ctx, errForbidden())
+			return
+		}
+
+		setAuthenticated(ctx, user, session)
+		ctx.Next()
 	}
 }
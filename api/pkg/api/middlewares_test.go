@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name   string
+		id     string
+		echoed bool
+	}{
+		{"Missing", "", false},
+		{"Valid", "abc-123_DEF.4:5", true},
+		{"UUID", "0b5f8a4e-3c38-4b1e-9e0c-3f1b3a7d2c10", true},
+		{"Spaces", "some id", false},
+		{"Markup", "<script>alert(1)</script>", false},
+		{"LineBreak", "id\r\nX-Injected: 1", false},
+		{"TooLong", strings.Repeat("a", 129), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h := apitest.New(t, nil, api.WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+
+			req := apitest.NewRequest(t, http.MethodGet, "/api/v1/nope", nil, "")
+			if c.id != "" {
+				req.Header.Set("X-Request-ID", c.id)
+			}
+			res := h.Serve(t, req)
+
+			id := res.Header().Get("X-Request-ID")
+			if c.echoed && id != c.id {
+				t.Fatalf("expected the request id %q to be echoed, got %q", c.id, id)
+			}
+			if !c.echoed {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Fatalf("expected a generated request id, got %q", id)
+				}
+			}
+
+			problem := res.AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+			if problem.RequestID != id {
+				t.Fatalf("the problem has the request id %q rather than %q", problem.RequestID, id)
+			}
+			if !strings.Contains(logs.String(), `"request_id":"`+id+`"`) {
+				t.Fatalf("the request id %q is not logged: %s", id, logs.String())
+			}
+		})
+	}
+}
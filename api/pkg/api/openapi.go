@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/docs"
+	"github.com/thomas-maurice/api/go-vue/pkg/openapi"
+)
+
+// openAPIHandler serves the OpenAPI 3.1 version of the swagger document,
+// it is converted once at startup.
+func openAPIHandler() (gin.HandlerFunc, error) {
+	doc, err := openapi.Convert([]byte(docs.SwaggerInfo.ReadDoc()), openapi.Options{
+		MediaTypes: map[string]string{
+			"api.Problem": problemContentType,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", cacheControlRevalidate)
+		ctx.Data(200, "application/json", doc)
+	}, nil
+}
@@ -0,0 +1,24 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	var out map[string]any
+	h.Get(t, "/api/openapi.json", "").AssertJSON(t, http.StatusOK, &out)
+	if paths, _ := out["paths"].(map[string]any); out["openapi"] == nil || len(paths) == 0 {
+		t.Fatalf("unexpected document: %v", out["openapi"])
+	}
+}
+
+func TestSwagger(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	h.Get(t, "/swagger/index.html", "").AssertStatus(t, http.StatusOK)
+}
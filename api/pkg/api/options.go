@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Option configures the API built by New
+type Option func(*Api)
+
+// WithUserService uses the given user service instead of the sql one
+func WithUserService(us userservice.UserService) Option {
+	return func(a *Api) {
+		a.UserService = us
+	}
+}
+
+// WithConfigService uses the given config service instead of the sql one
+func WithConfigService(cs configservice.ConfigService) Option {
+	return func(a *Api) {
+		a.ConfigService = cs
+	}
+}
+
+// WithClock makes the API read the time from now instead of time.Now
+func WithClock(now func() time.Time) Option {
+	return func(a *Api) {
+		a.Clock = now
+	}
+}
+
+// WithSigningKey signs the session tokens with key instead of the one of
+// the configuration
+func WithSigningKey(key *ecdsa.PrivateKey) Option {
+	return func(a *Api) {
+		a.SigninigKey = key
+	}
+}
+
+// WithLogger logs with logger instead of the one of the configuration,
+// which is then not made the default logger either
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Api) {
+		a.Logger = logger
+	}
+}
+
+// WithTracerProvider records the spans with tp instead of the provider
+// built from the configuration, tracing is then enabled whatever the
+// configuration says. The provider is shut down along with the API.
+func WithTracerProvider(tp *sdktrace.TracerProvider) Option {
+	return func(a *Api) {
+		a.TracerProvider = tp
+	}
+}
+
+// WithHTTPClient makes the outbound requests, to the OIDC providers, with
+// client instead of the one traced by the tracer provider
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *Api) {
+		a.HTTPClient = client
+	}
+}
+
+// WithConsole prints the messages meant for the operator only, such as
+// the generated password of the built-in admin, to w instead of the
+// standard error
+func WithConsole(w io.Writer) Option {
+	return func(a *Api) {
+		a.Console = w
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
+	"github.com/thomas-maurice/api/go-vue/pkg/ratelimit"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"gorm.io/gorm"
+)
+
+const (
+	RateLimitGroupGlobal = "global"
+	RateLimitGroupAuth   = "auth"
+	RateLimitGroupOIDC   = "oidc"
+	RateLimitGroupUser   = "user"
+	RateLimitGroupAdmin  = "admin"
+	RateLimitGroupConfig = "config"
+	RateLimitGroupMisc   = "misc"
+
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyUser   = "user"
+	RateLimitKeyAPIKey = "apikey"
+
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreSQL    = "sql"
+
+	rateLimitRemainingKey = "ratelimit_remaining"
+)
+
+// authenticatedRateLimitGroups are the groups whose requests are
+// authenticated before being limited, the only ones that can be keyed
+// on the user or the api key
+var authenticatedRateLimitGroups = map[string]bool{
+	RateLimitGroupUser:   true,
+	RateLimitGroupAdmin:  true,
+	RateLimitGroupConfig: true,
+}
+
+// checkRateLimitConfig rejects the unknown keys, and the keys other than
+// the ip on the groups limited before authenticating, since they could
+// only be read from headers the client is free to change
+func checkRateLimitConfig(cfg config.RateLimitConfig) error {
+	for group, rule := range cfg.Groups {
+		switch rule.Key {
+		case "", RateLimitKeyIP:
+		case RateLimitKeyUser, RateLimitKeyAPIKey:
+			if !authenticatedRateLimitGroups[group] {
+				return fmt.Errorf("invalid rate limit key for the %s group: %s, the requests are not authenticated yet", group, rule.Key)
+			}
+		default:
+			return fmt.Errorf("invalid rate limit key provided: %s", rule.Key)
+		}
+	}
+
+	return nil
+}
+
+// newRateLimitStore creates the store described by the configuration
+func newRateLimitStore(cfg config.RateLimitConfig, db *gorm.DB, now func() time.Time) (ratelimit.Store, error) {
+	switch cfg.Store {
+	case "", RateLimitStoreMemory:
+		s := ratelimit.NewMemoryStore()
+		s.Now = now
+		return s, nil
+	case RateLimitStoreSQL:
+		if db == nil {
+			return nil, fmt.Errorf("the sql rate limit store needs a database")
+		}
+		s, err := ratelimit.NewSQLStore(db)
+		if err != nil {
+			return nil, err
+		}
+		s.Now = now
+		return s, nil
+	default:
+		return nil, fmt.Errorf("invalid rate limit store provided: %s", cfg.Store)
+	}
+}
+
+// rateLimitKey returns what the bucket of the request is keyed on. The
+// api keys and the users are those authenticated by RequiresUserLogin.
+func rateLimitKey(ctx *gin.Context, kind string) string {
+	switch kind {
+	case RateLimitKeyAPIKey:
+		if session, ok := ctx.Get("session"); ok && ctx.Request.Header.Get("X-API-KEY") != "" {
+			if s, ok := session.(*userservice.Session); ok {
+				return "apikey:" + s.Id
+			}
+		}
+		fallthrough
+	case RateLimitKeyUser:
+		if user, ok := ctx.Get("user"); ok {
+			if u, ok := user.(*userservice.User); ok {
+				return "user:" + u.Id
+			}
+		}
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// setRateLimitHeaders sets the RateLimit headers, when several limits
+// apply to a request the one with the least remaining requests wins.
+func setRateLimitHeaders(ctx *gin.Context, rule config.RateLimitRule, res ratelimit.Result) {
+	if remaining, ok := ctx.Get(rateLimitRemainingKey); ok && remaining.(int) <= res.Remaining {
+		return
+	}
+
+	ctx.Set(rateLimitRemainingKey, res.Remaining)
+	ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+	ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Requests, int(rule.Period.Seconds())))
+}
+
+// RateLimit returns a middleware enforcing the rule configured for the
+// group, requests over the limit get a 429. When the store fails the
+// request is let through.
+func (a *Api) RateLimit(group string) gin.HandlerFunc {
+	rule, ok := a.Config.HTTP.RateLimit.Groups[group]
+	if !a.Config.HTTP.RateLimit.Enabled || !ok || rule.Requests <= 0 || rule.Period <= 0 {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	limit := ratelimit.Limit{
+		Requests: rule.Requests,
+		Period:   rule.Period,
+		Burst:    rule.Burst,
+	}
+
+	return func(ctx *gin.Context) {
+		key := group + ":" + rateLimitKey(ctx, rule.Key)
+		res, err := a.RateLimitStore.Take(ctx.Request.Context(), key, limit)
+		if err != nil {
+			logging.FromContext(ctx.Request.Context()).Warn("rate limiter failed", "error", err, "group", group)
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, rule, res)
+
+		if !res.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			abortWithError(ctx, NewAPIError(http.StatusTooManyRequests, CodeRateLimited, "too many requests", nil))
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package api_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+func rateLimitConfig(groups map[string]config.RateLimitRule) *config.Config {
+	cfg := apitest.DefaultConfig()
+	cfg.HTTP.RateLimit = config.RateLimitConfig{Enabled: true, Groups: groups}
+
+	return cfg
+}
+
+func loginRequest(t *testing.T, headers map[string]string) *http.Request {
+	t.Helper()
+
+	req := apitest.NewRequest(t, http.MethodPost, "/api/v1/auth/login", &api.LoginInput{Username: "nobody", Password: "wrong"}, "")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	return req
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	h := apitest.New(t, rateLimitConfig(map[string]config.RateLimitRule{
+		api.RateLimitGroupAuth: {Requests: 2, Period: time.Minute, Burst: 3},
+	}))
+
+	for _, remaining := range []string{"2", "1", "0"} {
+		h.Serve(t, loginRequest(t, nil)).
+			AssertStatus(t, http.StatusUnauthorized).
+			AssertHeader(t, "RateLimit-Limit", "3").
+			AssertHeader(t, "RateLimit-Remaining", remaining).
+			AssertHeader(t, "RateLimit-Policy", "2;w=60")
+	}
+
+	res := h.Serve(t, loginRequest(t, nil))
+	res.AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+	res.AssertHeader(t, "Retry-After", "30").AssertHeader(t, "RateLimit-Reset", "90")
+
+	h.Clock.Advance(30 * time.Second)
+	h.Serve(t, loginRequest(t, nil)).AssertStatus(t, http.StatusUnauthorized)
+
+	// the other groups are not limited
+	for range 5 {
+		h.Get(t, "/api/v1/uuid", "").AssertStatus(t, http.StatusOK).AssertHeader(t, "RateLimit-Limit", "")
+	}
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	groups := map[string]config.RateLimitRule{
+		api.RateLimitGroupAuth: {Requests: 1, Period: time.Hour},
+	}
+
+	// the forwarding headers of untrusted peers are ignored
+	h := apitest.New(t, rateLimitConfig(groups))
+	h.Serve(t, loginRequest(t, map[string]string{"X-Forwarded-For": "198.51.100.1"})).AssertStatus(t, http.StatusUnauthorized)
+	for _, ip := range []string{"198.51.100.2", "198.51.100.3"} {
+		h.Serve(t, loginRequest(t, map[string]string{"X-Forwarded-For": ip, "X-Real-IP": ip})).
+			AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+	}
+
+	// the requests going through a trusted proxy are keyed on the client
+	cfg := rateLimitConfig(groups)
+	cfg.HTTP.TrustedProxies = []string{"192.0.2.0/24"}
+	h = apitest.New(t, cfg)
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2"} {
+		h.Serve(t, loginRequest(t, map[string]string{"X-Forwarded-For": ip})).AssertStatus(t, http.StatusUnauthorized)
+	}
+	h.Serve(t, loginRequest(t, map[string]string{"X-Forwarded-For": "198.51.100.1"})).
+		AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+}
+
+func TestRateLimitAuthenticatedKeys(t *testing.T) {
+	h := apitest.New(t, rateLimitConfig(map[string]config.RateLimitRule{
+		api.RateLimitGroupUser:  {Requests: 1, Period: time.Hour, Key: api.RateLimitKeyAPIKey},
+		api.RateLimitGroupAdmin: {Requests: 1, Period: time.Hour, Key: api.RateLimitKeyUser},
+	}))
+
+	apiKeyRequest := func(path string, key string) *http.Request {
+		req := apitest.NewRequest(t, http.MethodGet, path, nil, "")
+		req.Header.Set("X-API-KEY", key)
+		return req
+	}
+
+	// every api key has its own bucket, the requests without one share
+	// the bucket of their user
+	first, second, session := h.LoginAsAdmin(t), h.LoginAsAdmin(t), h.LoginAsAdmin(t)
+	for _, key := range []string{first, second} {
+		h.Serve(t, apiKeyRequest("/api/v1/user/profile", key)).AssertStatus(t, http.StatusOK)
+		h.Serve(t, apiKeyRequest("/api/v1/user/profile", key)).AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+	}
+	h.Get(t, "/api/v1/user/profile", session).AssertStatus(t, http.StatusOK)
+	h.Get(t, "/api/v1/user/profile", session).AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+
+	// invalid keys are rejected before reaching the limiter
+	for _, key := range []string{"a", "b", "c"} {
+		h.Serve(t, apiKeyRequest("/api/v1/user/profile", key)).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	}
+
+	// the sessions of a user share its bucket
+	h.Get(t, "/api/v1/admin/users", first).AssertStatus(t, http.StatusOK)
+	h.Get(t, "/api/v1/admin/users", second).AssertProblem(t, http.StatusTooManyRequests, api.CodeRateLimited)
+}
+
+func TestRateLimitInvalidConfig(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	cases := []struct {
+		name   string
+		groups map[string]config.RateLimitRule
+		err    string
+	}{
+		{"APIKeyBeforeAuth", map[string]config.RateLimitRule{api.RateLimitGroupAuth: {Requests: 1, Period: time.Hour, Key: api.RateLimitKeyAPIKey}}, "not authenticated"},
+		{"UserBeforeAuth", map[string]config.RateLimitRule{api.RateLimitGroupGlobal: {Requests: 1, Period: time.Hour, Key: api.RateLimitKeyUser}}, "not authenticated"},
+		{"UnknownKey", map[string]config.RateLimitRule{api.RateLimitGroupUser: {Requests: 1, Period: time.Hour, Key: "header"}}, "invalid rate limit key"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := api.New(rateLimitConfig(c.groups),
+				api.WithUserService(h.Users),
+				api.WithConfigService(h.Config),
+				api.WithSigningKey(h.Key),
+				api.WithLogger(slog.New(slog.DiscardHandler)),
+			)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+
+	cfg := apitest.DefaultConfig()
+	cfg.HTTP.TrustedProxies = []string{"not an address"}
+	_, err := api.New(cfg,
+		api.WithUserService(h.Users),
+		api.WithConfigService(h.Config),
+		api.WithSigningKey(h.Key),
+		api.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	if err == nil || !strings.Contains(err.Error(), "trusted proxies") {
+		t.Fatalf("expected the trusted proxies to be rejected, got %v", err)
+	}
+}
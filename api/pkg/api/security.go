@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// cspNoncePlaceholder is the value given to `html.cspNonce` in the
+	// Vite configuration, it is replaced by the nonce of the request
+	// when index.html is served.
+	cspNoncePlaceholder = "__CSP_NONCE__"
+
+	defaultHSTSMaxAge     = 31536000
+	defaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+	apiContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+)
+
+// unnoncedTag matches opening script and style tags without a nonce, in
+// case the UI was built without the cspNonce option.
+var unnoncedTag = regexp.MustCompile(`<(script|style)\b([^>]*)>`)
+
+// SecurityHeadersMiddleware sets the security headers common to every
+// response. The Content-Security-Policy of the UI is set when serving
+// index.html since it depends on the nonce, API responses get a policy
+// that forbids everything.
+func (a *Api) SecurityHeadersMiddleware() gin.HandlerFunc {
+	cfg := a.Config.HTTP.SecurityHeaders
+
+	hsts := ""
+	maxAge := cfg.HSTSMaxAge
+	if maxAge == 0 {
+		maxAge = defaultHSTSMaxAge
+	}
+	if maxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", maxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	referrerPolicy := cfg.ReferrerPolicy
+	if referrerPolicy == "" {
+		referrerPolicy = defaultReferrerPolicy
+	}
+
+	return func(ctx *gin.Context) {
+		if cfg.Disabled {
+			ctx.Next()
+			return
+		}
+
+		h := ctx.Writer.Header()
+		if hsts != "" {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", referrerPolicy)
+		if len(cfg.FrameAncestors) == 0 {
+			h.Set("X-Frame-Options", "DENY")
+		}
+
+		if strings.HasPrefix(ctx.Request.URL.Path, "/api") {
+			h.Set("Content-Security-Policy", apiContentSecurityPolicy)
+		}
+
+		ctx.Next()
+	}
+}
+
+// contentSecurityPolicy returns the policy of the UI for the given nonce
+func (a *Api) contentSecurityPolicy(nonce string) string {
+	cfg := a.Config.HTTP.SecurityHeaders
+
+	frameAncestors := "'none'"
+	if len(cfg.FrameAncestors) != 0 {
+		frameAncestors = strings.Join(cfg.FrameAncestors, " ")
+	}
+
+	connectSrc := "'self'"
+	if origin := originOf(a.Config.UI.APIBaseURL); origin != "" {
+		connectSrc += " " + origin
+	}
+
+	imgSrc := "'self' data:"
+	if origin := originOf(a.Config.UI.Branding.LogoURL); origin != "" {
+		imgSrc += " " + origin
+	}
+	if len(cfg.ConnectSrc) != 0 {
+		connectSrc += " " + strings.Join(cfg.ConnectSrc, " ")
+	}
+
+	directives := []string{
+		"default-src 'self'",
+		fmt.Sprintf("script-src 'self' 'nonce-%s'", nonce),
+		fmt.Sprintf("style-src 'self' 'nonce-%s'", nonce),
+		"style-src-attr 'unsafe-inline'",
+		"img-src " + imgSrc,
+		"font-src 'self' data:",
+		"connect-src " + connectSrc,
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors " + frameAncestors,
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// injectNonce sets the nonce on every script and style tag of the page
+func injectNonce(page []byte, nonce string) []byte {
+	page = bytes.ReplaceAll(page, []byte(cspNoncePlaceholder), []byte(nonce))
+
+	return unnoncedTag.ReplaceAllFunc(page, func(tag []byte) []byte {
+		if bytes.Contains(tag, []byte("nonce=")) {
+			return tag
+		}
+		m := unnoncedTag.FindSubmatch(tag)
+		return []byte(fmt.Sprintf(`<%s nonce="%s"%s>`, m[1], nonce, m[2]))
+	})
+}
+
+// indexHandler serves the index.html of the UI with a fresh nonce on
+// every request, and the matching Content-Security-Policy. The page is
+// never cached: a revalidated copy would keep its old nonce while the
+// new response brings a policy with another one, blocking its scripts.
+func (a *Api) indexHandler(ui fs.FS) (gin.HandlerFunc, error) {
+	page, err := fs.ReadFile(ui, "index.html")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", cacheControlNoStore)
+
+		nonce, err := newNonce()
+		if err != nil {
+			abortWithError(ctx, errInternal("failed to generate nonce", err))
+			return
+		}
+
+		if !a.Config.HTTP.SecurityHeaders.Disabled {
+			ctx.Header("Content-Security-Policy", a.contentSecurityPolicy(nonce))
+		}
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", injectNonce(page, nonce))
+	}, nil
+}
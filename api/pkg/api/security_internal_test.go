@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestInjectNonce(t *testing.T) {
+	cases := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			"Placeholder",
+			`<script nonce="__CSP_NONCE__" src="/a.js"></script>`,
+			`<script nonce="abc" src="/a.js"></script>`,
+		},
+		{
+			"MissingNonce",
+			`<script type="module" src="/a.js"></script><style>p{}</style>`,
+			`<script nonce="abc" type="module" src="/a.js"></script><style nonce="abc">p{}</style>`,
+		},
+		{
+			"ExistingNonce",
+			`<script nonce="other"></script>`,
+			`<script nonce="other"></script>`,
+		},
+		{
+			"OtherTags",
+			`<link rel="stylesheet" href="/a.css"><scripts></scripts><div>script</div>`,
+			`<link rel="stylesheet" href="/a.css"><scripts></scripts><div>script</div>`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := string(injectNonce([]byte(c.page), "abc")); got != c.want {
+				t.Fatalf("expected %s, got %s", c.want, got)
+			}
+		})
+	}
+}
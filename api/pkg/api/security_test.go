@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+var cspNonce = regexp.MustCompile(`script-src 'self' 'nonce-([^']+)'`)
+
+// uiConfig serves a UI made of the given index.html from disk
+func uiConfig(t *testing.T, index string) *config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(index), 0o644); err != nil {
+		t.Fatalf("could not write index.html: %s", err)
+	}
+
+	cfg := apitest.DefaultConfig()
+	cfg.UI.Dev.Dir = dir
+
+	return cfg
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	h.Get(t, "/api/v1/nope", "").
+		AssertHeader(t, "Strict-Transport-Security", "max-age=31536000").
+		AssertHeader(t, "X-Content-Type-Options", "nosniff").
+		AssertHeader(t, "Referrer-Policy", "strict-origin-when-cross-origin").
+		AssertHeader(t, "X-Frame-Options", "DENY").
+		AssertHeader(t, "Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+
+	cfg := apitest.DefaultConfig()
+	cfg.HTTP.SecurityHeaders = config.SecurityHeadersConfig{
+		HSTSMaxAge:            60,
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        "no-referrer",
+		FrameAncestors:        []string{"https://portal.example.com"},
+	}
+	h = apitest.New(t, cfg)
+	res := h.Get(t, "/", "").
+		AssertHeader(t, "Strict-Transport-Security", "max-age=60; includeSubDomains").
+		AssertHeader(t, "Referrer-Policy", "no-referrer").
+		AssertHeader(t, "X-Frame-Options", "")
+	if csp := res.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors https://portal.example.com") {
+		t.Fatalf("the frame ancestors are not in the policy: %s", csp)
+	}
+
+	cfg = apitest.DefaultConfig()
+	cfg.HTTP.SecurityHeaders.HSTSMaxAge = -1
+	apitest.New(t, cfg).Get(t, "/api/v1/nope", "").AssertHeader(t, "Strict-Transport-Security", "")
+
+	cfg = apitest.DefaultConfig()
+	cfg.HTTP.SecurityHeaders.Disabled = true
+	h = apitest.New(t, cfg)
+	for _, path := range []string{"/api/v1/nope", "/"} {
+		res := h.Get(t, path, "")
+		for _, header := range []string{"Strict-Transport-Security", "X-Content-Type-Options", "X-Frame-Options", "Content-Security-Policy"} {
+			if v := res.Header().Get(header); v != "" {
+				t.Fatalf("%s: the header %s is set while disabled: %s", path, header, v)
+			}
+		}
+	}
+}
+
+func TestIndexNonce(t *testing.T) {
+	h := apitest.New(t, uiConfig(t, `<html><head><script type="module" nonce="__CSP_NONCE__" src="/assets/index.js"></script><style>body{}</style></head></html>`))
+
+	nonces := make(map[string]bool)
+	for range 2 {
+		req := apitest.NewRequest(t, http.MethodGet, "/", nil, "")
+		req.Header.Set("If-None-Match", "*")
+		res := h.Serve(t, req).
+			AssertStatus(t, http.StatusOK).
+			AssertHeader(t, "Cache-Control", "no-store").
+			AssertHeader(t, "ETag", "")
+
+		m := cspNonce.FindStringSubmatch(res.Header().Get("Content-Security-Policy"))
+		if m == nil {
+			t.Fatalf("the policy has no nonce: %s", res.Header().Get("Content-Security-Policy"))
+		}
+		nonce := m[1]
+		nonces[nonce] = true
+
+		page := res.Body.String()
+		if strings.Count(page, `nonce="`+nonce+`"`) != 2 || strings.Contains(page, "__CSP_NONCE__") {
+			t.Fatalf("the page does not carry the nonce %s of its policy: %s", nonce, page)
+		}
+	}
+
+	if len(nonces) != 2 {
+		t.Fatal("the nonce is reused across requests")
+	}
+}
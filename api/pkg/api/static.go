@@ -0,0 +1,224 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cacheControlImmutable   = "public, max-age=604800, immutable"
+	cacheControlRevalidate  = "no-cache"
+	cacheControlNoStore     = "no-store"
+	encodingIdentity        = "identity"
+	precompressedMinQuality = 0.0
+)
+
+// precompressedEncodings lists the encodings for which variants may be
+// embedded, by order of preference, with the extension of the variant.
+var precompressedEncodings = []struct {
+	name string
+	ext  string
+}{
+	{name: "br", ext: ".br"},
+	{name: "gzip", ext: ".gz"},
+}
+
+// staticFile is a file of the UI along with its precompressed variants,
+// every representation has its own strong ETag.
+type staticFile struct {
+	contentType string
+	etags       map[string]string
+	variants    map[string]string
+}
+
+// staticFS serves the files of the UI, negotiating the precompressed
+// variants and answering conditional requests.
+type staticFS struct {
+	fsys  fs.FS
+	files map[string]*staticFile
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// newStaticFS indexes the files and computes their ETags upfront, the
+// filesystem being embedded they cannot change afterwards.
+func newStaticFS(fsys fs.FS) (*staticFS, error) {
+	s := &staticFS{
+		fsys:  fsys,
+		files: make(map[string]*staticFile),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		for _, enc := range precompressedEncodings {
+			if strings.HasSuffix(name, enc.ext) {
+				return nil
+			}
+		}
+
+		hash, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		file := &staticFile{
+			contentType: contentType,
+			etags:       map[string]string{encodingIdentity: strconv.Quote(hash)},
+			variants:    map[string]string{encodingIdentity: name},
+		}
+
+		for _, enc := range precompressedEncodings {
+			if _, err := fs.Stat(fsys, name+enc.ext); err == nil {
+				file.etags[enc.name] = strconv.Quote(hash + "-" + enc.name)
+				file.variants[enc.name] = name + enc.ext
+			}
+		}
+
+		s.files[name] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// acceptedEncodings parses the Accept-Encoding header into the quality
+// of each encoding, `*` included.
+func acceptedEncodings(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		accepted[name] = q
+	}
+
+	return accepted
+}
+
+// negotiate picks the preferred encoding the client accepts among the
+// variants of the file.
+func (f *staticFile) negotiate(header string) string {
+	accepted := acceptedEncodings(header)
+	for _, enc := range precompressedEncodings {
+		if _, ok := f.variants[enc.name]; !ok {
+			continue
+		}
+
+		q, ok := accepted[enc.name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > precompressedMinQuality {
+			return enc.name
+		}
+	}
+
+	return encodingIdentity
+}
+
+// etagMatches tells if the If-None-Match header matches the etag, using
+// the weak comparison as mandated by RFC 9110.
+func etagMatches(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serve writes the file with the given cache policy, answering 404 if
+// it does not exist.
+func (s *staticFS) serve(ctx *gin.Context, name string, cacheControl string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	file, ok := s.files[name]
+	if !ok {
+		abortWithError(ctx, errNotFound("file not found"))
+		return
+	}
+
+	encoding := file.negotiate(ctx.Request.Header.Get("Accept-Encoding"))
+	etag := file.etags[encoding]
+
+	h := ctx.Writer.Header()
+	h.Set("Cache-Control", cacheControl)
+	h.Set("ETag", etag)
+	if len(file.variants) > 1 {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	if inm := ctx.Request.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	f, err := s.fsys.Open(file.variants[encoding])
+	if err != nil {
+		abortWithError(ctx, errInternal("failed to open file", err))
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		abortWithError(ctx, errInternal("file is not seekable", nil))
+		return
+	}
+
+	h.Set("Content-Type", file.contentType)
+	if encoding != encodingIdentity {
+		h.Set("Content-Encoding", encoding)
+	}
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", time.Time{}, content)
+}
@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+// staticHarness serves a UI with an asset precompressed with brotli and
+// gzip, the variants holding their encoding name rather than actually
+// compressed content, and an asset without variants.
+func staticHarness(t *testing.T) *apitest.Harness {
+	t.Helper()
+
+	cfg := uiConfig(t, "<html></html>")
+	files := map[string]string{
+		"assets/app.js":    "console.log(1)",
+		"assets/app.js.br": "br",
+		"assets/app.js.gz": "gzip",
+		"assets/app.css":   "body{}",
+		"favicon.ico":      "icon",
+	}
+	for name, content := range files {
+		path := filepath.Join(cfg.UI.Dev.Dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create the directory of %s: %s", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("could not write %s: %s", name, err)
+		}
+	}
+
+	return apitest.New(t, cfg)
+}
+
+func staticRequest(t *testing.T, method string, path string, acceptEncoding string, ifNoneMatch string) *http.Request {
+	t.Helper()
+
+	req := apitest.NewRequest(t, method, path, nil, "")
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	return req
+}
+
+func TestStaticNegotiation(t *testing.T) {
+	h := staticHarness(t)
+
+	cases := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"", "", "console.log(1)"},
+		{"br, gzip", "br", "br"},
+		{"gzip, deflate", "gzip", "gzip"},
+		{"br;q=0, gzip;q=0.5", "gzip", "gzip"},
+		{"GZIP", "gzip", "gzip"},
+		{"*", "br", "br"},
+		{"br;q=0, *;q=0", "", "console.log(1)"},
+		{"identity", "", "console.log(1)"},
+		{"deflate", "", "console.log(1)"},
+	}
+
+	etags := make(map[string]string)
+	for _, c := range cases {
+		res := h.Serve(t, staticRequest(t, http.MethodGet, "/assets/app.js", c.acceptEncoding, "")).
+			AssertStatus(t, http.StatusOK).
+			AssertHeader(t, "Content-Encoding", c.encoding).
+			AssertHeader(t, "Vary", "Accept-Encoding").
+			AssertHeader(t, "Cache-Control", "public, max-age=604800, immutable").
+			AssertHeader(t, "Content-Type", "text/javascript; charset=utf-8")
+		if res.Body.String() != c.body {
+			t.Errorf("%q: expected %q, got %q", c.acceptEncoding, c.body, res.Body.String())
+		}
+
+		etag := res.Header().Get("ETag")
+		if other, ok := etags[c.encoding]; ok && other != etag {
+			t.Errorf("%q: the ETag of the %q variant changed from %s to %s", c.acceptEncoding, c.encoding, other, etag)
+		}
+		etags[c.encoding] = etag
+	}
+
+	if len(etags) != 3 || etags[""] == etags["br"] || etags[""] == etags["gzip"] || etags["br"] == etags["gzip"] {
+		t.Fatalf("expected an ETag per variant, got %v", etags)
+	}
+
+	// assets without variants do not vary on the encoding
+	h.Serve(t, staticRequest(t, http.MethodGet, "/assets/app.css", "br, gzip", "")).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "Content-Encoding", "").
+		AssertHeader(t, "Vary", "")
+
+	h.Serve(t, staticRequest(t, http.MethodGet, "/assets/nope.js", "", "")).
+		AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+}
+
+func TestStaticConditionalRequests(t *testing.T) {
+	h := staticHarness(t)
+
+	br := h.Serve(t, staticRequest(t, http.MethodGet, "/assets/app.js", "br", "")).Header().Get("ETag")
+	gzip := h.Serve(t, staticRequest(t, http.MethodGet, "/assets/app.js", "gzip", "")).Header().Get("ETag")
+
+	cases := []struct {
+		name           string
+		acceptEncoding string
+		ifNoneMatch    string
+		status         int
+	}{
+		{"SameVariant", "br", br, http.StatusNotModified},
+		{"Weak", "br", "W/" + br, http.StatusNotModified},
+		{"List", "gzip", `"other", ` + gzip, http.StatusNotModified},
+		{"Any", "", "*", http.StatusNotModified},
+		{"OtherVariant", "gzip", br, http.StatusOK},
+		{"Identity", "", br, http.StatusOK},
+		{"Stale", "br", `"stale"`, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := h.Serve(t, staticRequest(t, http.MethodGet, "/assets/app.js", c.acceptEncoding, c.ifNoneMatch)).
+				AssertStatus(t, c.status).
+				AssertHeader(t, "Vary", "Accept-Encoding")
+			if c.status == http.StatusNotModified && res.Body.Len() != 0 {
+				t.Fatalf("the 304 has a body: %q", res.Body.String())
+			}
+		})
+	}
+
+	res := h.Serve(t, staticRequest(t, http.MethodHead, "/assets/app.js", "br", "")).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "ETag", br)
+	if res.Body.Len() != 0 {
+		t.Fatalf("the HEAD response has a body: %q", res.Body.String())
+	}
+
+	icon := h.Get(t, "/favicon.ico", "").AssertHeader(t, "Cache-Control", "no-cache").Header().Get("ETag")
+	h.Serve(t, staticRequest(t, http.MethodGet, "/favicon.ico", "", icon)).AssertStatus(t, http.StatusNotModified)
+}
@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// findSpan returns the first span of the given name and kind
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string, kind trace.SpanKind) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name && span.SpanKind == kind {
+			return span
+		}
+	}
+
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("no %s span named %s in %v", kind, name, names)
+
+	return tracetest.SpanStub{}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProviderWithExporter(config.TracingConfig{}, exporter)
+
+	idp := apitest.NewOIDCProvider(t)
+	cfg := oidcConfig(idp)
+	cfg.Health.CheckOIDC = true
+
+	// the database is opened by the api, for its queries to be traced
+	a := sqliteAPI(t, cfg, api.WithTracerProvider(tp))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		a.Router.ServeHTTP(res, req)
+		return res
+	}
+
+	// the trace context of the caller is carried on
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := apitest.NewRequest(t, http.MethodPost, "/api/v1/auth/login", &api.LoginInput{Username: apitest.AdminUsername, Password: apitest.AdminPassword}, "")
+	req.Header.Set("traceparent", traceParent)
+	if res := serve(req); res.Code != http.StatusOK {
+		t.Fatalf("could not log in: %d %s", res.Code, res.Body.String())
+	}
+	if res := serve(apitest.NewRequest(t, http.MethodGet, "/readyz", nil, "")); res.Code != http.StatusOK {
+		t.Fatalf("the api is not ready: %d %s", res.Code, res.Body.String())
+	}
+
+	if err := tp.ForceFlush(t.Context()); err != nil {
+		t.Fatalf("could not flush the spans: %s", err)
+	}
+	spans := exporter.GetSpans()
+
+	login := findSpan(t, spans, "POST /api/v1/auth/login", trace.SpanKindServer)
+	if login.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !login.Parent.IsRemote() {
+		t.Fatalf("the login span does not continue the trace of the caller: %+v", login.Parent)
+	}
+
+	authenticate := findSpan(t, spans, "UserService.Authenticate", trace.SpanKindInternal)
+	if authenticate.Parent.SpanID() != login.SpanContext.SpanID() {
+		t.Fatal("the service span is not a child of the request span")
+	}
+
+	query := findSpan(t, spans, "insert sessions", trace.SpanKindClient)
+	if query.SpanContext.TraceID() != login.SpanContext.TraceID() {
+		t.Fatal("the query span is not part of the trace of the request")
+	}
+
+	readyz := findSpan(t, spans, "GET /readyz", trace.SpanKindServer)
+	discovery := findSpan(t, spans, "HTTP GET", trace.SpanKindClient)
+	if discovery.SpanContext.TraceID() != readyz.SpanContext.TraceID() {
+		t.Fatal("the discovery span is not part of the trace of the readiness check")
+	}
+
+	// the injected http client is used as is
+	client := &http.Client{Transport: http.DefaultTransport}
+	h := apitest.New(t, nil, api.WithTracerProvider(sdktrace.NewTracerProvider()), api.WithHTTPClient(client))
+	if h.API.HTTPClient != client {
+		t.Fatal("the http client was replaced")
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thomas-maurice/api/go-vue/pkg/embeded"
+)
+
+// uiFiles are the handlers serving a build of the UI
+type uiFiles struct {
+	static *staticFS
+	index  gin.HandlerFunc
+}
+
+func (a *Api) newUIFiles(ui fs.FS) (*uiFiles, error) {
+	static, err := newStaticFS(ui)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := a.indexHandler(ui)
+	if err != nil {
+		return nil, err
+	}
+
+	return &uiFiles{static: static, index: index}, nil
+}
+
+// uiFilesLoader returns how to get the files of the UI. The embedded
+// build is indexed once, whereas a build on disk is indexed again on
+// every request so that rebuilding it does not require a restart.
+func (a *Api) uiFilesLoader() (func() (*uiFiles, error), error) {
+	if dir := a.Config.UI.Dev.Dir; dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("invalid ui directory: %w", err)
+		}
+
+		a.Logger.Warn("serving the UI from disk", "dir", dir)
+		return func() (*uiFiles, error) {
+			return a.newUIFiles(os.DirFS(dir))
+		}, nil
+	}
+
+	subbed, err := fs.Sub(embeded.UserInterfaceFS, "ui/dist")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := a.newUIFiles(subbed)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (*uiFiles, error) {
+		return files, nil
+	}, nil
+}
+
+// newDevProxy forwards requests to the Vite dev server, websockets
+// included so that hot module replacement keeps working.
+func (a *Api) newDevProxy(target string) (gin.HandlerFunc, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid ui dev proxy url: %s", target)
+	}
+
+	a.Logger.Warn("proxying the UI to the dev server", "url", target)
+	return func(ctx *gin.Context) {
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(u)
+				r.SetXForwarded()
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				abortWithError(ctx, errBadGateway("the ui dev server is unreachable", err))
+			},
+		}
+		proxy.ServeHTTP(ctx.Writer, ctx.Request)
+	}, nil
+}
+
+// registerUI mounts the routes of the UI, and returns the handler that
+// serves the client-side routes the router does not know about.
+func (a *Api) registerUI(router *gin.Engine) (gin.HandlerFunc, error) {
+	if a.Config.UI.Dev.ProxyURL != "" {
+		// everything the API does not handle goes through the fallback
+		return a.newDevProxy(a.Config.UI.Dev.ProxyURL)
+	}
+
+	load, err := a.uiFilesLoader()
+	if err != nil {
+		return nil, err
+	}
+
+	withFiles := func(handler func(ctx *gin.Context, files *uiFiles)) gin.HandlerFunc {
+		return func(ctx *gin.Context) {
+			files, err := load()
+			if err != nil {
+				abortWithError(ctx, errInternal("failed to load the user interface", err))
+				return
+			}
+			handler(ctx, files)
+		}
+	}
+
+	serveAsset := withFiles(func(ctx *gin.Context, files *uiFiles) {
+		files.static.serve(ctx, "assets"+ctx.Param("filepath"), cacheControlImmutable)
+	})
+	router.GET("/assets/*filepath", serveAsset)
+	router.HEAD("/assets/*filepath", serveAsset)
+
+	index := withFiles(func(ctx *gin.Context, files *uiFiles) {
+		files.index(ctx)
+	})
+	router.GET("/", index)
+	router.GET("/index.html", index)
+
+	router.GET("/favicon.ico", withFiles(func(ctx *gin.Context, files *uiFiles) {
+		files.static.serve(ctx, "favicon.ico", cacheControlRevalidate)
+	}))
+
+	return index, nil
+}
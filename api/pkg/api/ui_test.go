@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestUI(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	for _, path := range []string{"/", "/index.html", "/some/client/route"} {
+		res := h.Get(t, path, "").AssertStatus(t, http.StatusOK)
+		if ct := res.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Fatalf("%s: expected html, got %q", path, ct)
+		}
+	}
+}
+
+func TestUIDir(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := uiConfig(t, "<html>first build</html>")
+	h := apitest.New(t, cfg, api.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if !strings.Contains(logs.String(), "serving the UI from disk") {
+		t.Fatalf("the ui directory is not logged: %s", logs.String())
+	}
+
+	for _, path := range []string{"/", "/some/client/route"} {
+		if body := h.Get(t, path, "").AssertStatus(t, http.StatusOK).Body.String(); !strings.Contains(body, "first build") {
+			t.Fatalf("%s: unexpected page: %s", path, body)
+		}
+	}
+	h.Get(t, "/assets/app.js", "").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	// the rebuilds are served without restarting
+	dir := cfg.UI.Dev.Dir
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>second build</html>"), 0o644); err != nil {
+		t.Fatalf("could not write index.html: %s", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "assets"), 0o755); err != nil {
+		t.Fatalf("could not create the assets: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(2)"), 0o644); err != nil {
+		t.Fatalf("could not write app.js: %s", err)
+	}
+
+	if body := h.Get(t, "/", "").AssertStatus(t, http.StatusOK).Body.String(); !strings.Contains(body, "second build") {
+		t.Fatalf("the rebuild is not served: %s", body)
+	}
+	if body := h.Get(t, "/assets/app.js", "").AssertStatus(t, http.StatusOK).Body.String(); body != "console.log(2)" {
+		t.Fatalf("unexpected asset: %s", body)
+	}
+
+	// a build without index cannot be served
+	if err := os.Remove(filepath.Join(dir, "index.html")); err != nil {
+		t.Fatalf("could not remove index.html: %s", err)
+	}
+	h.Get(t, "/", "").AssertProblem(t, http.StatusInternalServerError, api.CodeInternal)
+}
+
+func TestUIProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "vite %s %s forwarded=%s", r.Method, r.URL.RequestURI(), r.Header.Get("X-Forwarded-Host"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	var logs bytes.Buffer
+	cfg := uiConfig(t, "<html></html>")
+	cfg.UI.Dev.ProxyURL = upstream.URL
+	h := apitest.New(t, cfg, api.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if !strings.Contains(logs.String(), "proxying the UI to the dev server") {
+		t.Fatalf("the proxy is not logged: %s", logs.String())
+	}
+
+	// the proxy needs a real connection, the recorder cannot be notified
+	// of it being closed
+	srv := httptest.NewServer(h.API.Router)
+	t.Cleanup(srv.Close)
+	get := func(path string) (int, string) {
+		res, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("could not get %s: %s", path, err)
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("could not read %s: %s", path, err)
+		}
+		return res.StatusCode, string(body)
+	}
+
+	// everything but the API goes to the dev server, which wins over the
+	// directory
+	host := strings.TrimPrefix(srv.URL, "http://")
+	for _, path := range []string{"/", "/src/main.ts?t=1", "/some/client/route"} {
+		status, body := get(path)
+		if want := "vite GET " + path + " forwarded=" + host; status != http.StatusOK || body != want {
+			t.Fatalf("expected %q, got %d %q", want, status, body)
+		}
+	}
+	h.Get(t, "/api/v1/uuid", "").AssertStatus(t, http.StatusOK)
+	h.Get(t, "/api/v1/nope", "").AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	upstream.Close()
+	if status, body := get("/"); status != http.StatusBadGateway || !strings.Contains(body, string(api.CodeBadGateway)) {
+		t.Fatalf("expected a bad gateway, got %d %s", status, body)
+	}
+}
+
+func TestUIInvalidConfig(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	cases := []struct {
+		name     string
+		dir      string
+		proxyURL string
+		err      string
+	}{
+		{"MissingDir", filepath.Join(t.TempDir(), "nope"), "", "invalid ui directory"},
+		{"RelativeProxyURL", "", "localhost:5173", "invalid ui dev proxy url"},
+		{"UnparsableProxyURL", "", "http://local host", "invalid ui dev proxy url"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := apitest.DefaultConfig()
+			cfg.UI.Dev.Dir = c.dir
+			cfg.UI.Dev.ProxyURL = c.proxyURL
+
+			_, err := api.New(cfg,
+				api.WithUserService(h.Users),
+				api.WithConfigService(h.Config),
+				api.WithSigningKey(h.Key),
+				api.WithLogger(slog.New(slog.DiscardHandler)),
+			)
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultUITitle = "Some app"
+
+type LoginMethods struct {
+	Password bool `json:"password"`
+	OIDC     bool `json:"oidc"`
+}
+
+type Branding struct {
+	Title   string `json:"title"`
+	LogoURL string `json:"logo_url,omitempty"`
+}
+
+// RuntimeConfig is the configuration of the UI, it is fetched before the
+// application is mounted.
+type RuntimeConfig struct {
+	APIBaseURL    string         `json:"api_base_url"`
+	LoginMethods  LoginMethods   `json:"login_methods"`
+	OIDCProviders []OIDCProvider `json:"oidc_providers"`
+	Branding      Branding       `json:"branding"`
+	SessionCookie bool           `json:"session_cookie"`
+}
+
+// originOf returns the origin of an absolute url, or an empty string
+// for relative ones which are served from the origin of the UI.
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+// UIConfig serves the runtime configuration of the UI. It lives next to
+// index.html rather than under /api, an empty api_base_url meaning the
+// origin it was fetched from.
+func (a *Api) UIConfig(ctx *gin.Context) {
+	providers, err := a.ConfigService.GetOIDCProviders(ctx.Request.Context())
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	cfg := RuntimeConfig{
+		APIBaseURL: strings.TrimSuffix(a.Config.UI.APIBaseURL, "/"),
+		LoginMethods: LoginMethods{
+			Password: !a.Config.UI.DisablePasswordLogin,
+			OIDC:     len(providers) != 0,
+		},
+		OIDCProviders: make([]OIDCProvider, 0, len(providers)),
+		Branding: Branding{
+			Title:   a.Config.UI.Branding.Title,
+			LogoURL: a.Config.UI.Branding.LogoURL,
+		},
+		SessionCookie: a.Config.Security.SessionCookie.Enabled,
+	}
+
+	if cfg.Branding.Title == "" {
+		cfg.Branding.Title = defaultUITitle
+	}
+
+	for _, p := range providers {
+		cfg.OIDCProviders = append(cfg.OIDCProviders, OIDCProvider{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+		})
+	}
+
+	ctx.Header("Cache-Control", cacheControlRevalidate)
+	ctx.JSON(200, cfg)
+}
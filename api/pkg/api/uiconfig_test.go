@@ -0,0 +1,19 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestUIConfig(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	var out api.RuntimeConfig
+	h.Get(t, "/config.json", "").AssertJSON(t, http.StatusOK, &out)
+	if !out.LoginMethods.Password {
+		t.Fatalf("the password login is not advertised: %+v", out)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +19,12 @@ type ProfileOutput struct {
 	LastLogin   time.Time `json:"last_login"`
 }
 
+type SessionOutput struct {
+	Id      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+	Current bool      `json:"current"`
+}
+
 // ProfileSelf returns the profile of a user
 //
 //	@Summary		Returns the profile of a user
@@ -25,21 +32,21 @@ type ProfileOutput struct {
 //	@Tags			User
 //	@Produce		json
 //	@Success		200	{object}	ProfileOutput
-//	@Failure		400	{object}	Error
-//	@Failure		500	{object}	Error
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		jwt
 //	@Security		apikey
 //	@Router			/user/profile [get]
 func (a *Api) ProfileSelf(ctx *gin.Context) {
 	user, exists := ctx.Get("user")
 	if !exists {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": "unknown user"})
+		abortWithError(ctx, errUnauthenticated())
 		return
 	}
 
 	self, ok := user.(*userservice.User)
 	if !ok {
-		ctx.AbortWithStatusJSON(400, gin.H{"error": "unknown user"})
+		abortWithError(ctx, errUnauthenticated())
 		return
 	}
 
@@ -54,3 +61,70 @@ func (a *Api) ProfileSelf(ctx *gin.Context) {
 		LastLogin:   self.LastLogin,
 	})
 }
+
+// ListSessionsSelf lists the sessions of a user
+//
+//	@Summary		Lists the active sessions of a user
+//	@Description	Lists the active sessions of a user, current is set for the session of the request
+//	@Tags			User
+//	@Produce		json
+//	@Success		200	{object}	[]SessionOutput
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		jwt
+//	@Security		apikey
+//	@Router			/user/sessions [get]
+func (a *Api) ListSessionsSelf(ctx *gin.Context) {
+	self, ok := ctx.MustGet("user").(*userservice.User)
+	if !ok {
+		abortWithError(ctx, errUnauthenticated())
+		return
+	}
+
+	current, _ := ctx.MustGet("session").(*userservice.Session)
+
+	sessions, err := a.UserService.ListSessions(ctx.Request.Context(), self.Id)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	out := make([]SessionOutput, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, SessionOutput{
+			Id:      s.Id,
+			Expires: s.Expires,
+			Current: current != nil && current.Id == s.Id,
+		})
+	}
+
+	ctx.JSON(200, out)
+}
+
+// RevokeSessionSelf revokes a session of a user
+//
+//	@Summary		Revokes a session of a user
+//	@Description	Revokes a session of a user, its token can no longer be used
+//	@Tags			User
+//	@Param			id	path	string	true	"Id of the session"
+//	@Success		204
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		jwt
+//	@Security		apikey
+//	@Router			/user/sessions/{id} [delete]
+func (a *Api) RevokeSessionSelf(ctx *gin.Context) {
+	self, ok := ctx.MustGet("user").(*userservice.User)
+	if !ok {
+		abortWithError(ctx, errUnauthenticated())
+		return
+	}
+
+	if err := a.UserService.RevokeSession(ctx.Request.Context(), self.Id, ctx.Param("id")); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+func TestProfile(t *testing.T) {
+	h := apitest.New(t, nil)
+	token, u := h.LoginAsUser(t, "alice")
+
+	var out api.ProfileOutput
+	h.Get(t, "/api/v1/user/profile", token).AssertJSON(t, http.StatusOK, &out)
+	if out.Id != u.Id || out.Username != "alice" || out.Admin || out.Kind != "local" {
+		t.Fatalf("unexpected profile: %+v", out)
+	}
+
+	h.Get(t, "/api/v1/user/profile", "").AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	h.Get(t, "/api/v1/user/profile", "garbage").AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
+
+func TestSessions(t *testing.T) {
+	h := apitest.New(t, nil)
+	token, _ := h.LoginAsUser(t, "alice")
+	other := h.Login(t, "alice", apitest.UserPassword)
+
+	var sessions []api.SessionOutput
+	h.Get(t, "/api/v1/user/sessions", token).AssertJSON(t, http.StatusOK, &sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	var current, revoked string
+	for _, s := range sessions {
+		if s.Current {
+			current = s.Id
+		} else {
+			revoked = s.Id
+		}
+	}
+	if current == "" || revoked == "" {
+		t.Fatalf("expected exactly one current session: %+v", sessions)
+	}
+
+	// the sessions of the other users are out of reach
+	bob, _ := h.LoginAsUser(t, "bob")
+	h.Delete(t, "/api/v1/user/sessions/"+revoked, bob).AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	h.Delete(t, "/api/v1/user/sessions/"+revoked, token).AssertStatus(t, http.StatusNoContent)
+	h.Get(t, "/api/v1/user/profile", other).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+	h.Delete(t, "/api/v1/user/sessions/"+revoked, token).AssertProblem(t, http.StatusNotFound, api.CodeNotFound)
+
+	h.Get(t, "/api/v1/user/sessions", token).AssertJSON(t, http.StatusOK, &sessions)
+	if len(sessions) != 1 || sessions[0].Id != current {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	h := apitest.New(t, nil)
+	token, _ := h.LoginAsUser(t, "alice")
+
+	h.Clock.Advance(userservice.SessionDuration)
+	h.Get(t, "/api/v1/user/profile", token).AssertStatus(t, http.StatusOK)
+
+	h.Clock.Advance(userservice.ClockSkewLeeway)
+	h.Get(t, "/api/v1/user/profile", token).AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
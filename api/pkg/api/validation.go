@@ -0,0 +1,201 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultMaxBodySize = 1 << 20
+
+// FieldError describes why a given field of the input was rejected, so
+// that clients can point the user at it.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var registerValidatorsOnce sync.Once
+
+// registerValidators configures the gin validator to report fields by
+// their json names, and registers the validation tags specific to this
+// API.
+func registerValidators() {
+	registerValidatorsOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+			name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+
+		_ = v.RegisterValidation("oidc_issuer", validateOIDCIssuer)
+		_ = v.RegisterValidation("oidc_scopes", validateOIDCScopes)
+	})
+}
+
+// validateOIDCIssuer requires an absolute https url, without query nor
+// fragment as mandated by the OIDC discovery spec. Plain http is only
+// tolerated for loopback addresses to ease local development.
+func validateOIDCIssuer(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	default:
+		return false
+	}
+}
+
+// validateOIDCScopes requires a list of valid RFC 6749 scope tokens that
+// includes `openid`.
+func validateOIDCScopes(fl validator.FieldLevel) bool {
+	scopes, ok := fl.Field().Interface().([]string)
+	if !ok {
+		return false
+	}
+
+	hasOpenID := false
+	for _, scope := range scopes {
+		if scope == "" {
+			return false
+		}
+		for _, c := range scope {
+			if c < 0x21 || c == 0x22 || c == 0x5c || c > 0x7e {
+				return false
+			}
+		}
+		if scope == "openid" {
+			hasOpenID = true
+		}
+	}
+
+	return hasOpenID
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters long", fe.Param())
+	case "alphanum":
+		return "must only contain letters and digits"
+	case "printascii":
+		return "must only contain printable ascii characters"
+	case "oidc_issuer":
+		return "must be an absolute https url without query or fragment"
+	case "oidc_scopes":
+		return "must be a list of valid scopes including openid"
+	default:
+		return fmt.Sprintf("failed the %s validation", fe.Tag())
+	}
+}
+
+// fieldPath turns the validator namespace, such as `NewOIDCProvider.scopes[0]`,
+// into the path of the field in the json input, `scopes[0]`.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if idx := strings.Index(ns, "."); idx != -1 {
+		return ns[idx+1:]
+	}
+
+	return ns
+}
+
+// bindJSON decodes and validates the json body into obj. The returned
+// error is ready to be handed to abortWithError.
+func bindJSON(ctx *gin.Context, obj any) error {
+	err := ctx.ShouldBindJSON(obj)
+	if err == nil {
+		return nil
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return NewAPIError(
+			http.StatusRequestEntityTooLarge,
+			CodePayloadTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit),
+			err,
+		)
+	case errors.As(err, &validationErrs):
+		apiErr := NewAPIError(http.StatusUnprocessableEntity, CodeValidationFailed, "the request is invalid", err)
+		for _, fe := range validationErrs {
+			apiErr.Fields = append(apiErr.Fields, FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: fieldErrorMessage(fe),
+			})
+		}
+		return apiErr
+	case errors.Is(err, io.EOF):
+		return errBadRequest("the request body is empty", err)
+	default:
+		return errBadRequest("the request body is not valid json", err)
+	}
+}
+
+// BodyLimitMiddleware caps the size of request bodies, reads beyond the
+// limit fail and are reported as a 413.
+func (a *Api) BodyLimitMiddleware(ctx *gin.Context) {
+	limit := a.Config.HTTP.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMaxBodySize
+	}
+
+	if ctx.Request.ContentLength > limit {
+		abortWithError(ctx, NewAPIError(
+			http.StatusRequestEntityTooLarge,
+			CodePayloadTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", limit),
+			nil,
+		))
+		return
+	}
+
+	if ctx.Request.Body != nil {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+	}
+
+	ctx.Next()
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	APIVersion1 = "v1"
+
+	// CurrentAPIVersion is the version the unversioned routes alias
+	CurrentAPIVersion = APIVersion1
+)
+
+// legacyRoutesDeprecation is when the unversioned routes were deprecated
+// in favour of their versioned counterpart.
+var legacyRoutesDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecation schedules the removal of routes. Sunset is when they stop
+// being served, it is left out of the headers when unknown. Successor
+// returns the path replacing the one of the request, if any.
+type Deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor func(path string) string
+}
+
+// apiVersion is a version of the API, Register mounts its routes on the
+// group of the version. A new version is added by appending it to the
+// list returned by apiVersions, it can share the handlers of the
+// previous one for the routes that did not change.
+type apiVersion struct {
+	Name     string
+	Register func(group *gin.RouterGroup)
+	// Deprecation is set once the whole version is scheduled for removal
+	Deprecation *Deprecation
+}
+
+// Deprecated returns a middleware advertising the deprecation of the
+// route with the Deprecation (RFC 9745), Sunset (RFC 8594) and Link
+// headers.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	sunset := ""
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		if sunset != "" {
+			ctx.Header("Sunset", sunset)
+		}
+		if d.Successor != nil {
+			if successor := d.Successor(ctx.Request.URL.Path); successor != "" {
+				ctx.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// apiVersions lists the versions of the API, oldest first
+func (a *Api) apiVersions() []apiVersion {
+	return []apiVersion{
+		{Name: APIVersion1, Register: a.registerV1},
+	}
+}
+
+// registerAPI mounts every version of the API under `/api/<version>`,
+// and the current one under `/api` as well for the clients predating
+// the versioning. The latter are deprecated.
+func (a *Api) registerAPI(apiGroup *gin.RouterGroup) {
+	for _, version := range a.apiVersions() {
+		group := apiGroup.Group("/" + version.Name)
+		if version.Deprecation != nil {
+			group.Use(Deprecated(*version.Deprecation))
+		}
+		version.Register(group)
+
+		if version.Name != CurrentAPIVersion {
+			continue
+		}
+
+		legacy := apiGroup.Group("", Deprecated(Deprecation{
+			Since:  legacyRoutesDeprecation,
+			Sunset: a.Config.HTTP.LegacyAPISunset,
+			Successor: func(path string) string {
+				return "/api/" + version.Name + strings.TrimPrefix(path, "/api")
+			},
+		}))
+		version.Register(legacy)
+	}
+}
+
+// registerV1 mounts the routes of the v1 API
+func (a *Api) registerV1(apiGroup *gin.RouterGroup) {
+	authGroup := apiGroup.Group("/auth", a.RateLimit(RateLimitGroupAuth))
+	{
+		authGroup.GET("/oidc/:provider", a.RateLimit(RateLimitGroupOIDC), a.GenerateOIDCRedirectURL)
+		authGroup.GET("/callback/:provider", a.RateLimit(RateLimitGroupOIDC), a.OIDCCallback)
+		authGroup.GET("/oidc/providers", a.GetAvailableOIDCProviders)
+		authGroup.POST("/login", a.AuthPassword)
+		authGroup.POST("/logout", a.Logout)
+		authGroup.POST("/device", a.StartDeviceAuthorization)
+		authGroup.POST("/device/token", a.DeviceToken)
+		authGroup.POST("/device/approve", a.RequiresUserLogin(false), a.ApproveDeviceAuthorization)
+	}
+
+	userGroup := apiGroup.Group("/user", a.RequiresUserLogin(false), a.RateLimit(RateLimitGroupUser))
+	{
+		userGroup.GET("/profile", a.ProfileSelf)
+		userGroup.GET("/sessions", a.ListSessionsSelf)
+		userGroup.DELETE("/sessions/:id", a.RevokeSessionSelf)
+	}
+
+	adminGroup := apiGroup.Group("/admin", a.RequiresUserLogin(true), a.RateLimit(RateLimitGroupAdmin))
+	{
+		adminGroup.GET("/users", a.AdminListUsers)
+		adminGroup.GET("/user/:id", a.AdminGetUser)
+		adminGroup.GET("/jobs", a.AdminJobs)
+		adminGroup.GET("/cache", a.AdminSessionCache)
+	}
+
+	configGroup := apiGroup.Group("/config", a.RequiresUserLogin(true), a.RateLimit(RateLimitGroupConfig))
+	{
+		configGroup.POST("/oidc/provider", a.CreateOIDCProvider)
+	}
+
+	miscGroup := apiGroup.Group("", a.RateLimit(RateLimitGroupMisc))
+
+	miscGroup.GET("/ping", a.UserTokenMiddleware, func(ctx *gin.Context) {
+		ctx.JSON(200, gin.H{"pong": a.Clock()})
+	})
+
+	miscGroup.GET("/uuid", func(ctx *gin.Context) {
+		ctx.JSON(200, gin.H{"uuid": uuid.NewString()})
+	})
+}
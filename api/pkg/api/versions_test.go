@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+)
+
+func TestPing(t *testing.T) {
+	h := apitest.New(t, nil)
+	token := h.LoginAsAdmin(t)
+
+	var out struct {
+		Pong time.Time `json:"pong"`
+	}
+	h.Get(t, "/api/v1/ping", token).AssertJSON(t, http.StatusOK, &out)
+	if !out.Pong.Equal(h.Clock.Now()) {
+		t.Fatalf("expected the time of the clock %s, got %s", h.Clock.Now(), out.Pong)
+	}
+
+	h.Get(t, "/api/v1/ping", "").AssertProblem(t, http.StatusUnauthorized, api.CodeUnauthenticated)
+}
+
+func TestUUID(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	var first, second struct {
+		UUID string `json:"uuid"`
+	}
+	h.Get(t, "/api/v1/uuid", "").AssertJSON(t, http.StatusOK, &first)
+	h.Get(t, "/api/v1/uuid", "").AssertJSON(t, http.StatusOK, &second)
+	if first.UUID == "" || first.UUID == second.UUID {
+		t.Fatalf("unexpected uuids: %s, %s", first.UUID, second.UUID)
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	h := apitest.New(t, nil)
+
+	res := h.Get(t, "/api/uuid", "").AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "Link", `</api/v1/uuid>; rel="successor-version"`)
+	if !strings.HasPrefix(res.Header().Get("Deprecation"), "@") {
+		t.Fatalf("unexpected deprecation header: %q", res.Header().Get("Deprecation"))
+	}
+
+	res = h.Get(t, "/api/v1/uuid", "").AssertStatus(t, http.StatusOK)
+	if res.Header().Get("Deprecation") != "" {
+		t.Fatal("the versioned routes are not deprecated")
+	}
+}
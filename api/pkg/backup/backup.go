@@ -0,0 +1,238 @@
+// Package backup dumps the database to a driver neutral archive and
+// restores it, into any of the supported drivers. The archive is made of
+// JSON lines: a header, a line per row, and a footer counting the rows of
+// every table so that truncated archives are detected.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	Format  = "go-vue-backup"
+	Version = 1
+
+	// maxLineSize is the size of the longest row that can be restored
+	maxLineSize = 16 << 20
+)
+
+// Header is the first line of an archive
+type Header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Driver  string    `json:"driver"`
+}
+
+// Counts is the number of rows of every table
+type Counts map[string]int
+
+// entry is any line of an archive past the header, either a row or the
+// footer
+type entry struct {
+	Table  string          `json:"table,omitempty"`
+	Row    json.RawMessage `json:"row,omitempty"`
+	Counts Counts          `json:"counts,omitempty"`
+}
+
+// RestoreOptions tunes the restoration
+type RestoreOptions struct {
+	// Wipe deletes the existing rows, otherwise only empty databases
+	// are restored into
+	Wipe bool
+}
+
+// Dump writes every table of the database to w
+func Dump(ctx context.Context, db *gorm.DB, w io.Writer) (Counts, error) {
+	db = db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true})
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(&Header{
+		Format:  Format,
+		Version: Version,
+		Created: time.Now().UTC(),
+		Driver:  db.Dialector.Name(),
+	}); err != nil {
+		return nil, err
+	}
+
+	counts := make(Counts, len(tables))
+	for _, t := range tables {
+		n := 0
+		err := t.dump(db, func(row any) error {
+			b, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			n++
+			return enc.Encode(&entry{Table: t.name, Row: b})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not dump %s: %w", t.name, err)
+		}
+		counts[t.name] = n
+	}
+
+	if err := enc.Encode(&entry{Counts: counts}); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Restore reads an archive written by Dump into the database, creating
+// the tables when needed. Everything is restored in a single
+// transaction, nothing is written unless the whole archive is valid.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader, opts RestoreOptions) (Counts, error) {
+	db = db.WithContext(ctx)
+
+	for _, t := range tables {
+		if err := db.AutoMigrate(t.model); err != nil {
+			return nil, fmt.Errorf("could not migrate %s: %w", t.name, err)
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	if !sc.Scan() {
+		return nil, fmt.Errorf("invalid archive: %w", scanError(sc))
+	}
+
+	var header Header
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("invalid archive header: %w", err)
+	}
+	if header.Format != Format {
+		return nil, fmt.Errorf("not a %s archive", Format)
+	}
+	if header.Version < 1 || header.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d, the latest supported is %d", header.Version, Version)
+	}
+
+	counts := make(Counts, len(tables))
+	err := db.Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		if err := prepare(tx, opts); err != nil {
+			return err
+		}
+
+		var (
+			current *table
+			batch   []json.RawMessage
+			footer  Counts
+		)
+
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := current.restore(tx, batch); err != nil {
+				return fmt.Errorf("could not restore %s: %w", current.name, err)
+			}
+			counts[current.name] += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		line := 1
+		for sc.Scan() {
+			line++
+
+			var e entry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				return fmt.Errorf("invalid archive line %d: %w", line, err)
+			}
+
+			if footer != nil {
+				return fmt.Errorf("invalid archive line %d: data after the footer", line)
+			}
+
+			if e.Table == "" {
+				if err := flush(); err != nil {
+					return err
+				}
+				footer = e.Counts
+				continue
+			}
+
+			if current == nil || current.name != e.Table {
+				if err := flush(); err != nil {
+					return err
+				}
+				if current = tableByName(e.Table); current == nil {
+					return fmt.Errorf("invalid archive line %d: unknown table %s", line, e.Table)
+				}
+			}
+
+			// the scanner reuses its buffer
+			batch = append(batch, append(json.RawMessage(nil), e.Row...))
+			if len(batch) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("could not read the archive: %w", err)
+		}
+
+		if footer == nil {
+			return fmt.Errorf("truncated archive: no footer found")
+		}
+
+		for name, n := range footer {
+			if counts[name] != n {
+				return fmt.Errorf("truncated archive: %d rows of %s restored, %d expected", counts[name], name, n)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// prepare makes sure the tables can be restored into, wiping them first
+// if asked to
+func prepare(tx *gorm.DB, opts RestoreOptions) error {
+	if opts.Wipe {
+		// in reverse order, so that the rows referencing others go first
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Where("1 = 1").Delete(tables[i].model).Error; err != nil {
+				return fmt.Errorf("could not wipe %s: %w", tables[i].name, err)
+			}
+		}
+
+		return nil
+	}
+
+	for _, t := range tables {
+		var n int64
+		if err := tx.Model(t.model).Count(&n).Error; err != nil {
+			return err
+		}
+		if n != 0 {
+			return fmt.Errorf("the database is not empty, %s has %d rows", t.name, n)
+		}
+	}
+
+	return nil
+}
+
+func scanError(sc *bufio.Scanner) error {
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	return io.ErrUnexpectedEOF
+}
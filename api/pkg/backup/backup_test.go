@@ -0,0 +1,331 @@
+package backup_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/backup"
+	configmodels "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql/models"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	sqluserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql"
+	usermodels "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql/models"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+	"gorm.io/gorm"
+)
+
+// openDB opens a new sqlite database with the tables of the backup
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := storetest.SQLite(t)
+	if err := db.AutoMigrate(&usermodels.User{}, &usermodels.Session{}, &usermodels.APIKey{}, &configmodels.OIDCProvider{}); err != nil {
+		t.Fatalf("could not migrate the database: %s", err)
+	}
+
+	return db
+}
+
+// seed fills the database, with zero values for the columns having a
+// default so that they are checked to survive the round trip
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []any{
+		&usermodels.User{Id: "u1", Username: "admin", Email: "admin@localhost", Active: true, Kind: userservice.UserKindLocal, Admin: true, Password: "$2a$10$hash", Created: now, LastLogin: now.Add(time.Hour)},
+		&usermodels.User{Id: "u2", Username: "alice", Email: "alice@example.com", Active: false, DisplayName: "Alice", Kind: userservice.UserKindOIDC, Created: now},
+		&usermodels.Session{Id: "s1", UserId: "u1", Expires: now.Add(time.Hour)},
+		&usermodels.Session{Id: "s2", UserId: "u2", Expires: now.Add(2 * time.Hour)},
+		&usermodels.APIKey{Id: "k1", Hash: "hash1", Name: "ci", Active: true, UserId: "u1", Expires: now.Add(24 * time.Hour)},
+		&usermodels.APIKey{Id: "k2", Hash: "hash2", Name: "old", Active: false, UserId: "u2"},
+		&configmodels.OIDCProvider{Name: "google", Active: true, DisplayName: "Google", Issuer: "https://accounts.google.com", ClientID: "id", ClientSecret: "secret", Scopes: "openid,email", Created: now},
+		&configmodels.OIDCProvider{Name: "old", Active: false, Issuer: "https://idp.example.com", ClientID: "id", ClientSecret: "secret", Scopes: "openid"},
+	}
+
+	for _, row := range rows {
+		if err := db.Select("*").Create(row).Error; err != nil {
+			t.Fatalf("could not create %+v: %s", row, err)
+		}
+	}
+
+	// gorm replaces the zero values of the columns having a default on
+	// create, and the unset nullable times are NULL as the services leave
+	// them
+	updates := []struct {
+		model  any
+		where  string
+		column string
+		value  any
+	}{
+		{&usermodels.User{}, "id = 'u2'", "active", false},
+		{&usermodels.User{}, "id = 'u2'", "last_login", nil},
+		{&usermodels.APIKey{}, "id = 'k2'", "active", false},
+		{&usermodels.APIKey{}, "id = 'k2'", "expires", nil},
+		{&configmodels.OIDCProvider{}, "name = 'old'", "active", false},
+	}
+	for _, u := range updates {
+		if err := db.Model(u.model).Where(u.where).UpdateColumn(u.column, u.value).Error; err != nil {
+			t.Fatalf("could not set %s: %s", u.column, err)
+		}
+	}
+}
+
+// nullIds returns the ids of the rows of the model having column NULL
+func nullIds(t *testing.T, db *gorm.DB, model any, column string) []string {
+	t.Helper()
+
+	var ids []string
+	if err := db.Model(model).Where(column+" IS NULL").Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("could not read the null %s: %s", column, err)
+	}
+
+	return ids
+}
+
+// snapshot returns the rows of every backed up table, as JSON
+func snapshot(t *testing.T, db *gorm.DB) map[string]string {
+	t.Helper()
+
+	db = db.Session(&gorm.Session{SkipHooks: true})
+	var (
+		users     []usermodels.User
+		sessions  []usermodels.Session
+		apiKeys   []usermodels.APIKey
+		providers []configmodels.OIDCProvider
+	)
+
+	snap := make(map[string]string)
+	for name, rows := range map[string]any{"users": &users, "sessions": &sessions, "api_keys": &apiKeys, "oidc_providers": &providers} {
+		order := "id"
+		if name == "oidc_providers" {
+			order = "name"
+		}
+		if err := db.Order(order).Find(rows).Error; err != nil {
+			t.Fatalf("could not read %s: %s", name, err)
+		}
+		b, err := json.Marshal(rows)
+		if err != nil {
+			t.Fatalf("could not encode %s: %s", name, err)
+		}
+		snap[name] = string(b)
+	}
+
+	return snap
+}
+
+func assertSnapshot(t *testing.T, db *gorm.DB, want map[string]string) {
+	t.Helper()
+
+	got := snapshot(t, db)
+	for name := range want {
+		if got[name] != want[name] {
+			t.Errorf("%s differ:\nexpected %s\ngot      %s", name, want[name], got[name])
+		}
+	}
+}
+
+func dump(t *testing.T, db *gorm.DB) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := backup.Dump(t.Context(), db, &buf); err != nil {
+		t.Fatalf("could not dump the database: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := openDB(t)
+	seed(t, src)
+	want := snapshot(t, src)
+
+	var buf bytes.Buffer
+	counts, err := backup.Dump(t.Context(), src, &buf)
+	if err != nil {
+		t.Fatalf("could not dump the database: %s", err)
+	}
+	for _, name := range backup.Tables() {
+		if counts[name] != 2 {
+			t.Fatalf("expected 2 rows of %s to be dumped, got %v", name, counts)
+		}
+	}
+	archive := buf.Bytes()
+
+	dst := openDB(t)
+	restored, err := backup.Restore(t.Context(), dst, bytes.NewReader(archive), backup.RestoreOptions{})
+	if err != nil {
+		t.Fatalf("could not restore the archive: %s", err)
+	}
+	for _, name := range backup.Tables() {
+		if restored[name] != counts[name] {
+			t.Fatalf("expected %v rows to be restored, got %v", counts, restored)
+		}
+	}
+	assertSnapshot(t, dst, want)
+
+	// only empty databases are restored into, unless wiped
+	if _, err := backup.Restore(t.Context(), dst, bytes.NewReader(archive), backup.RestoreOptions{}); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("expected the restore into a non empty database to fail, got %v", err)
+	}
+	if err := dst.Session(&gorm.Session{SkipHooks: true}).Where(&usermodels.Session{Id: "s1"}).Delete(&usermodels.Session{}).Error; err != nil {
+		t.Fatalf("could not delete a session: %s", err)
+	}
+	if _, err := backup.Restore(t.Context(), dst, bytes.NewReader(archive), backup.RestoreOptions{Wipe: true}); err != nil {
+		t.Fatalf("could not restore the archive over the database: %s", err)
+	}
+	assertSnapshot(t, dst, want)
+}
+
+func TestRoundTripNullTimes(t *testing.T) {
+	src := openDB(t)
+	seed(t, src)
+
+	dst := openDB(t)
+	if _, err := backup.Restore(t.Context(), dst, bytes.NewReader(dump(t, src)), backup.RestoreOptions{}); err != nil {
+		t.Fatalf("could not restore the archive: %s", err)
+	}
+
+	for _, db := range []*gorm.DB{src, dst} {
+		if ids := nullIds(t, db, &usermodels.User{}, "last_login"); !slices.Equal(ids, []string{"u2"}) {
+			t.Fatalf("expected the last login of u2 only to be null, got %v", ids)
+		}
+		if ids := nullIds(t, db, &usermodels.APIKey{}, "expires"); !slices.Equal(ids, []string{"k2"}) {
+			t.Fatalf("expected the expiry of k2 only to be null, got %v", ids)
+		}
+	}
+
+	// the maintenance jobs tell the unset times apart from the old ones
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+	us, err := sqluserservice.NewUserService(dst, key)
+	if err != nil {
+		t.Fatalf("could not create the user service: %s", err)
+	}
+	us.Now = func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	if deleted, err := us.DeleteExpiredAPIKeys(t.Context()); err != nil || deleted != 1 {
+		t.Fatalf("expected the expired key only to be deleted, got %d %v", deleted, err)
+	}
+	if ids := nullIds(t, dst, &usermodels.APIKey{}, "expires"); !slices.Equal(ids, []string{"k2"}) {
+		t.Fatalf("the key without an expiry was deleted: %v", ids)
+	}
+
+	// u2 never logged in and was created before u1 last did
+	before := time.Date(2026, 1, 2, 3, 30, 0, 0, time.UTC)
+	if deactivated, err := us.DeactivateStaleUsers(t.Context(), userservice.UserKindOIDC, before); err != nil || deactivated != 0 {
+		t.Fatalf("expected the inactive user to be left alone, got %d %v", deactivated, err)
+	}
+	if err := us.SetActive(t.Context(), "u2", true); err != nil {
+		t.Fatalf("could not activate the user: %s", err)
+	}
+	if deactivated, err := us.DeactivateStaleUsers(t.Context(), userservice.UserKindOIDC, before); err != nil || deactivated != 1 {
+		t.Fatalf("expected the user who never logged in to be deactivated, got %d %v", deactivated, err)
+	}
+	if deactivated, err := us.DeactivateStaleUsers(t.Context(), userservice.UserKindLocal, before); err != nil || deactivated != 0 {
+		t.Fatalf("expected the user who logged in since to be left alone, got %d %v", deactivated, err)
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	src := openDB(t)
+	seed(t, src)
+	want := snapshot(t, src)
+	archive := dump(t, src)
+
+	passphrase := []byte("correct horse battery staple")
+	encrypted, err := backup.Encrypt(archive, passphrase)
+	if err != nil {
+		t.Fatalf("could not encrypt the archive: %s", err)
+	}
+	if !backup.IsEncrypted(encrypted) || backup.IsEncrypted(archive) {
+		t.Fatal("the encrypted archives are not told apart")
+	}
+	if bytes.Contains(encrypted, []byte("secret")) {
+		t.Fatal("the encrypted archive contains the secrets in clear")
+	}
+
+	if _, err := backup.Decrypt(encrypted, []byte("wrong")); !errors.Is(err, backup.ErrWrongPassphrase) {
+		t.Fatalf("expected a wrong passphrase error, got %v", err)
+	}
+
+	tampered := bytes.Clone(encrypted)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := backup.Decrypt(tampered, passphrase); !errors.Is(err, backup.ErrWrongPassphrase) {
+		t.Fatalf("expected a corrupted archive to be rejected, got %v", err)
+	}
+
+	decrypted, err := backup.Decrypt(encrypted, passphrase)
+	if err != nil {
+		t.Fatalf("could not decrypt the archive: %s", err)
+	}
+	if !bytes.Equal(decrypted, archive) {
+		t.Fatal("the decrypted archive differs from the original")
+	}
+
+	dst := openDB(t)
+	if _, err := backup.Restore(t.Context(), dst, bytes.NewReader(decrypted), backup.RestoreOptions{}); err != nil {
+		t.Fatalf("could not restore the archive: %s", err)
+	}
+	assertSnapshot(t, dst, want)
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	src := openDB(t)
+	seed(t, src)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(dump(t, src)), "\n"), "\n")
+	footer := len(lines) - 1
+
+	// without returns the archive without the line at the given index
+	without := func(index int) string {
+		return strings.Join(lines[:index], "") + strings.Join(lines[index+1:], "")
+	}
+
+	cases := []struct {
+		name    string
+		archive string
+		err     string
+	}{
+		{"Empty", "", "invalid archive"},
+		{"NotAnArchive", `{"format":"other","version":1}` + "\n", "not a go-vue-backup archive"},
+		{"FutureVersion", `{"format":"go-vue-backup","version":99}` + "\n", "unsupported archive version"},
+		{"MissingRow", without(footer - 1), "truncated archive"},
+		{"MissingUser", without(1), "could not restore sessions"},
+		{"MissingFooter", without(footer), "truncated archive"},
+		{"WrongCount", without(footer) + `{"counts":{"users":2,"sessions":3,"api_keys":2,"oidc_providers":2}}` + "\n", "truncated archive"},
+		{"UnknownTable", without(footer) + `{"table":"nope","row":{}}` + "\n" + lines[footer], "unknown table"},
+		{"AfterFooter", strings.Join(lines, "") + "\n" + lines[1], "after the footer"},
+		{"InvalidRow", without(footer) + `{"table":"users","row":{"unknown":1}}` + "\n" + lines[footer], "could not restore users"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// into an empty database, that is left empty
+			dst := openDB(t)
+			empty := snapshot(t, dst)
+			_, err := backup.Restore(t.Context(), dst, strings.NewReader(c.archive), backup.RestoreOptions{})
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected an error containing %q, got %v", c.err, err)
+			}
+			assertSnapshot(t, dst, empty)
+
+			// over an existing database, whose wipe is rolled back
+			seed(t, dst)
+			want := snapshot(t, dst)
+			if _, err := backup.Restore(t.Context(), dst, strings.NewReader(c.archive), backup.RestoreOptions{Wipe: true}); err == nil {
+				t.Fatal("expected the restore to fail")
+			}
+			assertSnapshot(t, dst, want)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedMagic starts the encrypted archives, it is followed by the
+// salt of the key, the nonce and the archive sealed with AES-256-GCM
+var encryptedMagic = []byte("GVBKENC1")
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// scrypt parameters recommended for interactive logins as of 2017
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted archive")
+
+// IsEncrypted tells whether the archive was encrypted with Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// Encrypt seals the archive with a key derived from the passphrase
+func Encrypt(archive []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+saltSize+len(nonce)+len(archive)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, archive, encryptedMagic), nil
+}
+
+// Decrypt opens an archive sealed by Encrypt
+func Decrypt(data []byte, passphrase []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("not an encrypted archive")
+	}
+	data = data[len(encryptedMagic):]
+
+	if len(data) < saltSize {
+		return nil, ErrWrongPassphrase
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	archive, err := aead.Open(nil, nonce, data, encryptedMagic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return archive, nil
+}
+
+func newAEAD(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	configmodels "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql/models"
+	usermodels "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql/models"
+	"gorm.io/gorm"
+)
+
+const batchSize = 500
+
+// table knows how to dump and restore the rows of a model
+type table struct {
+	name    string
+	model   any
+	dump    func(db *gorm.DB, emit func(row any) error) error
+	restore func(db *gorm.DB, rows []json.RawMessage) error
+}
+
+// tables are the tables that are backed up, the ones referenced by
+// others first. The rate limiting buckets are not, they are transient.
+var tables = []table{
+	newTable[usermodels.User]("users"),
+	newTable[usermodels.Session]("sessions"),
+	newTable[usermodels.APIKey]("api_keys"),
+	newTable[configmodels.OIDCProvider]("oidc_providers"),
+}
+
+func newTable[T any](name string) table {
+	return table{
+		name:  name,
+		model: new(T),
+		dump: func(db *gorm.DB, emit func(row any) error) error {
+			var rows []T
+			return db.Model(new(T)).FindInBatches(&rows, batchSize, func(tx *gorm.DB, batch int) error {
+				for i := range rows {
+					if err := emit(&rows[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+		restore: func(db *gorm.DB, raw []json.RawMessage) error {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(new(T)); err != nil {
+				return err
+			}
+			sch := stmt.Schema
+
+			// the rows are inserted as maps of their columns, gorm would
+			// otherwise replace the zero values of the columns having a
+			// default, such as inactive users, with the default
+			values := make([]map[string]any, len(raw))
+			for i, r := range raw {
+				var row T
+				dec := json.NewDecoder(bytes.NewReader(r))
+				dec.DisallowUnknownFields()
+				if err := dec.Decode(&row); err != nil {
+					return err
+				}
+
+				rv := reflect.ValueOf(&row).Elem()
+				values[i] = make(map[string]any, len(sch.DBNames))
+				for _, f := range sch.Fields {
+					if f.DBName == "" {
+						continue
+					}
+					v, zero := f.ValueOf(db.Statement.Context, rv)
+					if zero && f.HasDefaultValue && f.DefaultValue == "null" {
+						v = nil
+					}
+					values[i][f.DBName] = v
+				}
+			}
+
+			return db.Table(sch.Table).CreateInBatches(values, batchSize).Error
+		},
+	}
+}
+
+func tableByName(name string) *table {
+	for i := range tables {
+		if tables[i].name == name {
+			return &tables[i]
+		}
+	}
+
+	return nil
+}
+
+// Tables returns the names of the tables that are backed up
+func Tables() []string {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.name
+	}
+
+	return names
+}
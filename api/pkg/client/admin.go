@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListUsers lists every user, it requires an admin
+func (c *Client) ListUsers(ctx context.Context) ([]UserListAdmin, error) {
+	var out []UserListAdmin
+	if err := c.do(ctx, http.MethodGet, "/admin/users", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetUser returns a user by id, it requires an admin
+func (c *Client) GetUser(ctx context.Context, id string) (*UserAdmin, error) {
+	var out UserAdmin
+	if err := c.do(ctx, http.MethodGet, "/admin/user/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Jobs returns the status of the maintenance jobs, it requires an admin
+func (c *Client) Jobs(ctx context.Context) (*JobsOutput, error) {
+	var out JobsOutput
+	if err := c.do(ctx, http.MethodGet, "/admin/jobs", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// SessionCache returns the statistics of the session cache of the
+// instance answering, it requires an admin
+func (c *Client) SessionCache(ctx context.Context) (*SessionCacheOutput, error) {
+	var out SessionCacheOutput
+	if err := c.do(ctx, http.MethodGet, "/admin/cache", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
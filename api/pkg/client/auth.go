@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Login logs a local user in, the session token is kept by the client
+// to authenticate the following requests.
+func (c *Client) Login(ctx context.Context, username string, password string) (*LoginOutput, error) {
+	var out LoginOutput
+	err := c.do(ctx, http.MethodPost, "/auth/login", nil, &LoginInput{
+		Username: username,
+		Password: password,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	c.token = out.Token
+	return &out, nil
+}
+
+// Logout invalidates the session of the client, and forgets its token
+func (c *Client) Logout(ctx context.Context) error {
+	var out LogoutOutput
+	if err := c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, &out); err != nil {
+		return err
+	}
+
+	c.token = ""
+	return nil
+}
+
+// OIDCProviders lists the OIDC providers users can log in with
+func (c *Client) OIDCProviders(ctx context.Context) ([]OIDCProvider, error) {
+	var out []OIDCProvider
+	if err := c.do(ctx, http.MethodGet, "/auth/oidc/providers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// OIDCRedirectURL returns the url of the provider the user has to be
+// sent to in order to log in
+func (c *Client) OIDCRedirectURL(ctx context.Context, provider string) (string, error) {
+	var out OIDCURLOutput
+	if err := c.do(ctx, http.MethodGet, "/auth/oidc/"+url.PathEscape(provider), nil, nil, &out); err != nil {
+		return "", err
+	}
+
+	return out.Url, nil
+}
+
+// OIDCCallback completes an OIDC login with the state and code the
+// provider redirected the user with, the session token is kept by the
+// client.
+func (c *Client) OIDCCallback(ctx context.Context, provider string, state string, code string) (*OIDCCallbackOutput, error) {
+	query := url.Values{}
+	query.Set("state", state)
+	query.Set("code", code)
+
+	var out OIDCCallbackOutput
+	if err := c.do(ctx, http.MethodGet, "/auth/callback/"+url.PathEscape(provider), query, nil, &out); err != nil {
+		return nil, err
+	}
+
+	c.token = out.Token
+	return &out, nil
+}
+
+// StartDeviceAuthorization starts a device authorization, the user then
+// has to approve it by visiting the verification url while the client
+// waits for it with WaitForDeviceToken.
+func (c *Client) StartDeviceAuthorization(ctx context.Context) (*DeviceAuthorizationOutput, error) {
+	var out DeviceAuthorizationOutput
+	if err := c.do(ctx, http.MethodPost, "/auth/device", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// DeviceToken polls for the token of a device authorization, it fails
+// with ErrAuthorizationPending until the user approved it. The session
+// token is kept by the client.
+func (c *Client) DeviceToken(ctx context.Context, deviceCode string) (*LoginOutput, error) {
+	var out LoginOutput
+	if err := c.do(ctx, http.MethodPost, "/auth/device/token", nil, &DeviceTokenInput{DeviceCode: deviceCode}, &out); err != nil {
+		return nil, err
+	}
+
+	c.token = out.Token
+	return &out, nil
+}
+
+// WaitForDeviceToken polls for the token of the device authorization at
+// the interval requested by the server, until it is approved, expires
+// or ctx is done.
+func (c *Client) WaitForDeviceToken(ctx context.Context, auth *DeviceAuthorizationOutput) (*LoginOutput, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		out, err := c.DeviceToken(ctx, auth.DeviceCode)
+		switch {
+		case err == nil:
+			return out, nil
+		case errors.Is(err, ErrSlowDown):
+			interval += 5 * time.Second
+		case !errors.Is(err, ErrAuthorizationPending):
+			return nil, err
+		}
+	}
+}
+
+// ApproveDevice approves the device authorization with the given user
+// code, granting the device a session of the authenticated user
+func (c *Client) ApproveDevice(ctx context.Context, userCode string) error {
+	return c.do(ctx, http.MethodPost, "/auth/device/approve", nil, &DeviceApproveInput{UserCode: userCode}, nil)
+}
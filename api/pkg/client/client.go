@@ -0,0 +1,175 @@
+// Package client is a typed Go client of the API. It mirrors the types
+// of the handlers of pkg/api without depending on the server code.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// APIPrefix is the path the version of the API this client speaks is
+	// served under
+	APIPrefix = "/api/v1"
+
+	tokenHeader  = "X-AUTH-TOKEN"
+	apiKeyHeader = "X-API-KEY"
+)
+
+// Client talks to the API. It authenticates with a session token, as
+// returned by Login, or with an API key, the token wins when both are
+// set. It is safe for concurrent use as long as the credentials are not
+// changed meanwhile.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	apiKey     string
+	userAgent  string
+	strict     bool
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http client used to talk to the API, it
+// defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates the requests with a session token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey authenticates the requests with an API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithStrictDecoding makes the client fail on response fields it does
+// not know about, which catches the client lagging behind the server.
+func WithStrictDecoding() Option {
+	return func(c *Client) {
+		c.strict = true
+	}
+}
+
+// New returns a client of the API served at baseURL, such as
+// `https://app.example.com`.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url: %s", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "go-vue-client",
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// SetToken sets the session token used to authenticate the requests
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// Token returns the session token of the client, if any
+func (c *Client) Token() string {
+	return c.token
+}
+
+// SetAPIKey sets the API key used to authenticate the requests
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// do sends the request to the given path of the API, relative to
+// APIPrefix, and decodes the response into out unless it is nil. Error
+// responses are returned as an *Error.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	u := c.baseURL.JoinPath(APIPrefix, path)
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	} else if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return newError(resp)
+	}
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	if c.strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("could not decode the response of %s %s: %w", method, path, err)
+	}
+
+	return nil
+}
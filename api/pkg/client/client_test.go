@@ -0,0 +1,448 @@
+package client_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api"
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/client"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+)
+
+// newServer serves the router of h over https, the client of the
+// server keeping the cookies it is sent
+func newServer(t *testing.T, h *apitest.Harness) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(h.API.Router)
+	t.Cleanup(srv.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("could not create the cookie jar: %s", err)
+	}
+	srv.Client().Jar = jar
+
+	return srv
+}
+
+// newClient returns a client of srv failing on any field it does not
+// know about, so that the types of the client stay in sync with the API
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	opts = append([]client.Option{client.WithHTTPClient(srv.Client()), client.WithStrictDecoding()}, opts...)
+	c, err := client.New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("could not create the client: %s", err)
+	}
+
+	return c
+}
+
+// setup runs the API described by cfg, DefaultConfig when nil, and
+// returns a client logged in as the admin
+func setup(t *testing.T, cfg *config.Config) (*apitest.Harness, *httptest.Server, *client.Client) {
+	t.Helper()
+
+	h := apitest.New(t, cfg)
+	srv := newServer(t, h)
+
+	return h, srv, newClient(t, srv, client.WithToken(h.LoginAsAdmin(t)))
+}
+
+func TestLogin(t *testing.T) {
+	_, srv, _ := setup(t, nil)
+	c := newClient(t, srv)
+
+	login, err := c.Login(t.Context(), apitest.AdminUsername, apitest.AdminPassword)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+	if login.Token == "" || c.Token() != login.Token {
+		t.Fatal("the client did not keep the session token")
+	}
+
+	if _, err := newClient(t, srv).Login(t.Context(), apitest.AdminUsername, "wrong"); !errors.Is(err, client.ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	_, _, c := setup(t, nil)
+
+	if err := c.Logout(t.Context()); err != nil {
+		t.Fatalf("could not log out: %s", err)
+	}
+	if _, err := c.Profile(t.Context()); !errors.Is(err, client.ErrUnauthenticated) {
+		t.Fatalf("expected the session to be invalidated, got %v", err)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	_, srv, c := setup(t, nil)
+
+	profile, err := c.Profile(t.Context())
+	if err != nil {
+		t.Fatalf("could not get the profile: %s", err)
+	}
+	if profile.Username != apitest.AdminUsername || !profile.Admin || profile.Kind != "local" {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	if _, err := newClient(t, srv).Profile(t.Context()); !errors.Is(err, client.ErrUnauthenticated) {
+		t.Fatalf("expected an unauthenticated error without a token, got %v", err)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	h, srv, _ := setup(t, nil)
+	token, _ := h.LoginAsUser(t, "alice")
+
+	c := newClient(t, srv, client.WithAPIKey(token))
+	if profile, err := c.Profile(t.Context()); err != nil || profile.Username != "alice" {
+		t.Fatalf("could not authenticate with an api key: %+v %v", profile, err)
+	}
+
+	c.SetAPIKey("garbage")
+	if _, err := c.Profile(t.Context()); !errors.Is(err, client.ErrUnauthenticated) {
+		t.Fatalf("expected an invalid api key to be rejected, got %v", err)
+	}
+
+	// the session token wins over the api key
+	c.SetToken(token)
+	if _, err := c.Profile(t.Context()); err != nil {
+		t.Fatalf("could not authenticate with the session token: %s", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	h, srv, c := setup(t, nil)
+	other := newClient(t, srv, client.WithToken(h.LoginAsAdmin(t)))
+
+	sessions, err := c.Sessions(t.Context())
+	if err != nil {
+		t.Fatalf("could not list the sessions: %s", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+
+	for _, session := range sessions {
+		if session.Current {
+			continue
+		}
+		if err := c.RevokeSession(t.Context(), session.Id); err != nil {
+			t.Fatalf("could not revoke the session: %s", err)
+		}
+	}
+	if _, err := other.Profile(t.Context()); !errors.Is(err, client.ErrUnauthenticated) {
+		t.Fatalf("expected the revoked session to be invalidated, got %v", err)
+	}
+
+	if err := c.RevokeSession(t.Context(), "does-not-exist"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	_, _, c := setup(t, nil)
+
+	if _, err := c.Ping(t.Context()); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	h, _, c := setup(t, nil)
+	_, alice := h.LoginAsUser(t, "alice")
+
+	users, err := c.ListUsers(t.Context())
+	if err != nil {
+		t.Fatalf("could not list the users: %s", err)
+	}
+	if len(users) != 2 || (users[0].Id != alice.Id && users[1].Id != alice.Id) {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	h, _, c := setup(t, nil)
+	_, alice := h.LoginAsUser(t, "alice")
+
+	user, err := c.GetUser(t.Context(), alice.Id)
+	if err != nil {
+		t.Fatalf("could not get the user: %s", err)
+	}
+	if user.Username != "alice" || !user.Active || user.Admin {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	if _, err := c.GetUser(t.Context(), "does-not-exist"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	_, _, c := setup(t, nil)
+
+	jobs, err := c.Jobs(t.Context())
+	if err != nil {
+		t.Fatalf("could not get the jobs: %s", err)
+	}
+	if !jobs.Enabled || len(jobs.Jobs) == 0 {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+}
+
+func TestSessionCache(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.SessionCache.Enabled = true
+	_, _, c := setup(t, cfg)
+
+	stats, err := c.SessionCache(t.Context())
+	if err != nil {
+		t.Fatalf("could not get the session cache statistics: %s", err)
+	}
+	if !stats.Enabled {
+		t.Fatalf("unexpected statistics: %+v", stats)
+	}
+}
+
+func TestCreateOIDCProvider(t *testing.T) {
+	_, _, c := setup(t, nil)
+
+	input := &client.NewOIDCProvider{
+		Name:         "example",
+		DisplayName:  "Example",
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Issuer:       "https://accounts.example.com",
+		Scopes:       []string{"openid", "email"},
+	}
+	provider, err := c.CreateOIDCProvider(t.Context(), input)
+	if err != nil {
+		t.Fatalf("could not create the oidc provider: %s", err)
+	}
+	if provider.Name != "example" || provider.DisplayName != "Example" {
+		t.Fatalf("unexpected oidc provider: %+v", provider)
+	}
+
+	if _, err := c.CreateOIDCProvider(t.Context(), input); !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+}
+
+// oidcConfig returns a configuration with the provider registered as
+// `fake`
+func oidcConfig(idp *apitest.OIDCProvider) *config.Config {
+	cfg := apitest.DefaultConfig()
+	cfg.Security.OIDC = map[string]config.OIDCConfig{
+		"fake": {DisplayName: "Fake", Issuer: idp.Issuer(), ClientID: idp.ClientID, ClientSecret: idp.ClientSecret},
+	}
+
+	return cfg
+}
+
+func TestOIDCProviders(t *testing.T) {
+	_, srv, _ := setup(t, oidcConfig(apitest.NewOIDCProvider(t)))
+
+	providers, err := newClient(t, srv).OIDCProviders(t.Context())
+	if err != nil {
+		t.Fatalf("could not list the oidc providers: %s", err)
+	}
+	if len(providers) != 1 || providers[0].Name != "fake" || providers[0].DisplayName != "Fake" {
+		t.Fatalf("unexpected oidc providers: %+v", providers)
+	}
+}
+
+func TestOIDCRedirectURL(t *testing.T) {
+	idp := apitest.NewOIDCProvider(t)
+	_, srv, _ := setup(t, oidcConfig(idp))
+	c := newClient(t, srv)
+
+	redirect, err := c.OIDCRedirectURL(t.Context(), "fake")
+	if err != nil {
+		t.Fatalf("could not get the redirect url: %s", err)
+	}
+	if !strings.HasPrefix(redirect, idp.Issuer()+"/authorize") {
+		t.Fatalf("unexpected redirect url: %s", redirect)
+	}
+
+	if _, err := c.OIDCRedirectURL(t.Context(), "does-not-exist"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestOIDCCallback(t *testing.T) {
+	idp := apitest.NewOIDCProvider(t)
+	idp.SetIdentity("carol@example.com", "Carol")
+	_, srv, _ := setup(t, oidcConfig(idp))
+	c := newClient(t, srv)
+
+	// the state is sent back in the cookie set along with the url
+	redirect, err := c.OIDCRedirectURL(t.Context(), "fake")
+	if err != nil {
+		t.Fatalf("could not get the redirect url: %s", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("could not parse the redirect url: %s", err)
+	}
+
+	if _, err := c.OIDCCallback(t.Context(), "fake", "other", "code"); !errors.Is(err, client.ErrInvalidRequest) {
+		t.Fatalf("expected a mismatching state to be rejected, got %v", err)
+	}
+
+	out, err := c.OIDCCallback(t.Context(), "fake", u.Query().Get("state"), "code")
+	if err != nil {
+		t.Fatalf("could not complete the login: %s", err)
+	}
+	if out.Username != "carol@example.com" || c.Token() != out.Token {
+		t.Fatalf("unexpected login: %+v", out)
+	}
+	if profile, err := c.Profile(t.Context()); err != nil || profile.DisplayName != "Carol" {
+		t.Fatalf("unexpected profile: %+v %v", profile, err)
+	}
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	_, srv, c := setup(t, nil)
+	device := newClient(t, srv)
+
+	auth, err := device.StartDeviceAuthorization(t.Context())
+	if err != nil {
+		t.Fatalf("could not start the device authorization: %s", err)
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" {
+		t.Fatalf("unexpected authorization: %+v", auth)
+	}
+
+	if _, err := device.DeviceToken(t.Context(), auth.DeviceCode); !errors.Is(err, client.ErrAuthorizationPending) {
+		t.Fatalf("expected the authorization to be pending, got %v", err)
+	}
+	if _, err := device.DeviceToken(t.Context(), auth.DeviceCode); !errors.Is(err, client.ErrSlowDown) {
+		t.Fatalf("expected to be told to slow down, got %v", err)
+	}
+
+	if err := c.ApproveDevice(t.Context(), "BCDF-GHJK"); !errors.Is(err, client.ErrExpiredToken) {
+		t.Fatalf("expected an unknown user code to be rejected, got %v", err)
+	}
+	if err := c.ApproveDevice(t.Context(), auth.UserCode); err != nil {
+		t.Fatalf("could not approve the device: %s", err)
+	}
+}
+
+func TestWaitForDeviceToken(t *testing.T) {
+	_, srv, c := setup(t, nil)
+	device := newClient(t, srv)
+
+	auth, err := device.StartDeviceAuthorization(t.Context())
+	if err != nil {
+		t.Fatalf("could not start the device authorization: %s", err)
+	}
+	if err := c.ApproveDevice(t.Context(), auth.UserCode); err != nil {
+		t.Fatalf("could not approve the device: %s", err)
+	}
+
+	// polls once, a second later
+	auth.Interval = 1
+	login, err := device.WaitForDeviceToken(t.Context(), auth)
+	if err != nil {
+		t.Fatalf("could not get the device token: %s", err)
+	}
+	if login.Token == "" || device.Token() != login.Token {
+		t.Fatalf("the client did not keep the session token: %+v", login)
+	}
+
+	// the device code is single use
+	if _, err := device.DeviceToken(t.Context(), auth.DeviceCode); !errors.Is(err, client.ErrExpiredToken) {
+		t.Fatalf("expected the device code to be single use, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cfg := apitest.DefaultConfig()
+	cfg.HTTP.RateLimit = config.RateLimitConfig{
+		Enabled: true,
+		Groups:  map[string]config.RateLimitRule{api.RateLimitGroupAuth: {Requests: 1, Period: time.Minute}},
+	}
+	_, srv, _ := setup(t, cfg)
+	c := newClient(t, srv)
+
+	// the problem details are decoded, field errors included
+	_, err := c.Login(t.Context(), "", "")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an api error, got %v", err)
+	}
+	if apiErr.Status != http.StatusUnprocessableEntity || apiErr.Code != client.CodeValidationFailed ||
+		apiErr.Type != "urn:go-vue:error:validation_failed" || apiErr.Title != "Unprocessable Entity" ||
+		apiErr.Instance != "/api/v1/auth/login" || apiErr.RequestID == "" || len(apiErr.Errors) != 2 {
+		t.Fatalf("unexpected error: %+v", apiErr)
+	}
+	if !errors.Is(err, client.ErrValidationFailed) || errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("the error does not match its code only: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "api error 422 (validation_failed): ") {
+		t.Fatalf("unexpected message: %s", err)
+	}
+
+	// the retry delay of the rate limited requests is kept
+	_, err = c.Login(t.Context(), "nobody", "wrong")
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrRateLimited) || apiErr.RetryAfter == "" {
+		t.Fatalf("expected a rate limited error with a retry delay, got %+v", err)
+	}
+}
+
+func TestForeignErrors(t *testing.T) {
+	// the responses of a proxy in front of the API only carry the status
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "proxy-id")
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("<html>bad gateway</html>"))
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("could not create the client: %s", err)
+	}
+
+	_, err = c.Profile(t.Context())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Code != "" || apiErr.RequestID != "proxy-id" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if err.Error() != "api error 502: Bad Gateway" {
+		t.Fatalf("unexpected message: %s", err)
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"pong":"2026-01-01T00:00:00Z","extra":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	lenient, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("could not create the client: %s", err)
+	}
+	if _, err := lenient.Ping(t.Context()); err != nil {
+		t.Fatalf("the unknown fields are not ignored: %s", err)
+	}
+
+	if _, err := newClient(t, srv).Ping(t.Context()); err == nil || !strings.Contains(err.Error(), "extra") {
+		t.Fatalf("expected the unknown field to be rejected, got %v", err)
+	}
+}
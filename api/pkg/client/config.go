@@ -0,0 +1,16 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateOIDCProvider adds an OIDC provider, it requires an admin
+func (c *Client) CreateOIDCProvider(ctx context.Context, input *NewOIDCProvider) (*OIDCProvider, error) {
+	var out OIDCProvider
+	if err := c.do(ctx, http.MethodPost, "/config/oidc/provider", nil, input, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
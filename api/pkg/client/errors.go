@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrorCode is the machine readable code of an API error
+type ErrorCode string
+
+const (
+	CodeInvalidRequest       ErrorCode = "invalid_request"
+	CodeValidationFailed     ErrorCode = "validation_failed"
+	CodePayloadTooLarge      ErrorCode = "payload_too_large"
+	CodeUnauthenticated      ErrorCode = "unauthenticated"
+	CodeInvalidCredentials   ErrorCode = "invalid_credentials"
+	CodeForbidden            ErrorCode = "forbidden"
+	CodeCSRFFailed           ErrorCode = "csrf_failed"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeConflict             ErrorCode = "conflict"
+	CodeOIDCError            ErrorCode = "oidc_error"
+	CodeRateLimited          ErrorCode = "rate_limited"
+	CodeBadGateway           ErrorCode = "bad_gateway"
+	CodeAuthorizationPending ErrorCode = "authorization_pending"
+	CodeSlowDown             ErrorCode = "slow_down"
+	CodeExpiredToken         ErrorCode = "expired_token"
+	CodeInternal             ErrorCode = "internal_error"
+)
+
+// Sentinel errors to be used with errors.Is, they match any *Error with
+// the same code.
+var (
+	ErrInvalidRequest       = &Error{Code: CodeInvalidRequest}
+	ErrValidationFailed     = &Error{Code: CodeValidationFailed}
+	ErrUnauthenticated      = &Error{Code: CodeUnauthenticated}
+	ErrInvalidCredentials   = &Error{Code: CodeInvalidCredentials}
+	ErrForbidden            = &Error{Code: CodeForbidden}
+	ErrNotFound             = &Error{Code: CodeNotFound}
+	ErrConflict             = &Error{Code: CodeConflict}
+	ErrRateLimited          = &Error{Code: CodeRateLimited}
+	ErrAuthorizationPending = &Error{Code: CodeAuthorizationPending}
+	ErrSlowDown             = &Error{Code: CodeSlowDown}
+	ErrExpiredToken         = &Error{Code: CodeExpiredToken}
+)
+
+// FieldError describes why a field of the input was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error returned by the API, decoded from its RFC 7807
+// problem details. Responses that are not problem details, such as the
+// ones of a proxy in front of the API, only carry the status.
+type Error struct {
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Status     int          `json:"status"`
+	Detail     string       `json:"detail,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	Code       ErrorCode    `json:"code"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+	RetryAfter string       `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+
+	if e.Code != "" {
+		return fmt.Sprintf("api error %d (%s): %s", e.Status, e.Code, msg)
+	}
+	return fmt.Sprintf("api error %d: %s", e.Status, msg)
+}
+
+// Is matches the sentinel errors on their code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Code != "" && t.Code == e.Code
+}
+
+func newError(resp *http.Response) *Error {
+	e := &Error{}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err == nil {
+		_ = json.Unmarshal(b, e)
+	}
+
+	e.Status = resp.StatusCode
+	e.RetryAfter = resp.Header.Get("Retry-After")
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+
+	return e
+}
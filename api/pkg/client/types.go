@@ -0,0 +1,134 @@
+package client
+
+import (
+	"time"
+)
+
+// The types below mirror the ones of pkg/api, they are kept in sync by
+// the tests of the client, run against the router of the API.
+
+type LoginInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginOutput struct {
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+type LogoutOutput struct {
+	Ok bool `json:"ok"`
+}
+
+type OIDCURLOutput struct {
+	Url string `json:"url"`
+}
+
+type OIDCCallbackOutput struct {
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrf_token,omitempty"`
+	Username  string `json:"username"`
+}
+
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type NewOIDCProvider struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Issuer       string   `json:"issuer"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+type ProfileOutput struct {
+	Id          string    `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Kind        string    `json:"kind"`
+	Admin       bool      `json:"admin"`
+	Created     time.Time `json:"created"`
+	LastLogin   time.Time `json:"last_login"`
+}
+
+type UserAdmin struct {
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Admin       bool      `json:"admin"`
+	Active      bool      `json:"active"`
+	Id          string    `json:"id"`
+	Created     time.Time `json:"created"`
+	LastLogin   time.Time `json:"last_login"`
+}
+
+type UserListAdmin struct {
+	Username string `json:"username"`
+	Id       string `json:"id"`
+}
+
+type SessionOutput struct {
+	Id      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+	Current bool      `json:"current"`
+}
+
+type DeviceAuthorizationOutput struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceTokenInput struct {
+	DeviceCode string `json:"device_code"`
+}
+
+type DeviceApproveInput struct {
+	UserCode string `json:"user_code"`
+}
+
+type PingOutput struct {
+	Pong time.Time `json:"pong"`
+}
+
+type JobRunOutput struct {
+	Holder   string    `json:"holder"`
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Result   string    `json:"result,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type JobOutput struct {
+	Name     string        `json:"name"`
+	Interval string        `json:"interval"`
+	Running  bool          `json:"running"`
+	Next     *time.Time    `json:"next,omitempty"`
+	LastRun  *JobRunOutput `json:"last_run,omitempty"`
+}
+
+type JobsOutput struct {
+	Enabled  bool        `json:"enabled"`
+	Instance string      `json:"instance,omitempty"`
+	Leader   bool        `json:"leader"`
+	Jobs     []JobOutput `json:"jobs"`
+}
+
+type SessionCacheOutput struct {
+	Enabled       bool    `json:"enabled"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Sessions      int     `json:"sessions"`
+	Users         int     `json:"users"`
+}
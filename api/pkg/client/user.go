@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Profile returns the profile of the authenticated user
+func (c *Client) Profile(ctx context.Context) (*ProfileOutput, error) {
+	var out ProfileOutput
+	if err := c.do(ctx, http.MethodGet, "/user/profile", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Sessions lists the active sessions of the authenticated user
+func (c *Client) Sessions(ctx context.Context) ([]SessionOutput, error) {
+	var out []SessionOutput
+	if err := c.do(ctx, http.MethodGet, "/user/sessions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// RevokeSession revokes a session of the authenticated user
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/user/sessions/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Ping checks that the API is reachable and the client authenticated
+func (c *Client) Ping(ctx context.Context) (*PingOutput, error) {
+	var out PingOutput
+	if err := c.do(ctx, http.MethodGet, "/ping", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
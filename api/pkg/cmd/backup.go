@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/backup"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"golang.org/x/term"
+)
+
+var (
+	flagBackupFile           string
+	flagBackupEncrypt        bool
+	flagBackupPassphraseFile string
+	flagBackupWipe           bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Backs the database up to a driver neutral archive",
+	Long: `Backs the users, sessions, API keys and OIDC providers up to an
+archive of JSON lines, that can be restored into any supported driver. The
+archive holds the password hashes and the OIDC client secrets, it is
+written readable by the user only and can be encrypted with a passphrase.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadFromFile(flagConfigFile)
+		if err != nil {
+			return err
+		}
+
+		db, closeDB, err := openDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		var passphrase []byte
+		if flagBackupEncrypt || flagBackupPassphraseFile != "" {
+			passphrase, err = readPassphrase(flagBackupPassphraseFile, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		var buf bytes.Buffer
+		counts, err := backup.Dump(cmd.Context(), db, &buf)
+		if err != nil {
+			return err
+		}
+
+		archive := buf.Bytes()
+		if passphrase != nil {
+			if archive, err = backup.Encrypt(archive, passphrase); err != nil {
+				return err
+			}
+		}
+
+		if flagBackupFile == "-" {
+			_, err = os.Stdout.Write(archive)
+		} else {
+			err = os.WriteFile(flagBackupFile, archive, 0600)
+		}
+		if err != nil {
+			return err
+		}
+
+		printCounts("backed up", counts)
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restores the database from an archive",
+	Long: `Restores the database from an archive written by the backup command,
+whatever the driver it was taken from. The database has to be empty unless
+--wipe is given, and nothing is written unless the whole archive is valid.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadFromFile(flagConfigFile)
+		if err != nil {
+			return err
+		}
+
+		var archive []byte
+		if flagBackupFile == "-" {
+			archive, err = io.ReadAll(os.Stdin)
+		} else {
+			archive, err = os.ReadFile(flagBackupFile)
+		}
+		if err != nil {
+			return err
+		}
+
+		if backup.IsEncrypted(archive) {
+			passphrase, err := readPassphrase(flagBackupPassphraseFile, false)
+			if err != nil {
+				return err
+			}
+			if archive, err = backup.Decrypt(archive, passphrase); err != nil {
+				return err
+			}
+		}
+
+		db, closeDB, err := openDatabase(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		counts, err := backup.Restore(cmd.Context(), db, bytes.NewReader(archive), backup.RestoreOptions{Wipe: flagBackupWipe})
+		if err != nil {
+			return err
+		}
+
+		printCounts("restored", counts)
+		return nil
+	},
+}
+
+// readPassphrase reads the passphrase from the first line of a file, or
+// prompts for it, twice when it is a new one
+func readPassphrase(file string, confirm bool) ([]byte, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, _, _ := strings.Cut(string(b), "\n")
+		passphrase = strings.TrimSuffix(passphrase, "\r")
+		if passphrase == "" {
+			return nil, fmt.Errorf("empty passphrase in %s", file)
+		}
+		return []byte(passphrase), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("a passphrase is needed, use --passphrase-file")
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, fmt.Errorf("the passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// printCounts reports the rows of every table on stderr, stdout may be
+// the archive itself
+func printCounts(verb string, counts backup.Counts) {
+	var parts []string
+	for _, name := range backup.Tables() {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[name], name))
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s\n", strings.ToUpper(verb[:1])+verb[1:], strings.Join(parts, ", "))
+}
+
+func initBackupCmds() {
+	for _, cmd := range []*cobra.Command{backupCmd, restoreCmd} {
+		cmd.Flags().StringVarP(&flagConfigFile, "config", "c", "config.yaml", "Path to the configuration file")
+		cmd.Flags().StringVar(&flagBackupPassphraseFile, "passphrase-file", "", "File holding the passphrase of the archive, prompted for when missing")
+	}
+
+	backupCmd.Flags().StringVarP(&flagBackupFile, "file", "f", "", "Path of the archive, - for stdout")
+	backupCmd.Flags().BoolVar(&flagBackupEncrypt, "encrypt", false, "Encrypt the archive with a passphrase")
+	_ = backupCmd.MarkFlagRequired("file")
+
+	restoreCmd.Flags().StringVarP(&flagBackupFile, "file", "f", "", "Path of the archive, - for stdin")
+	restoreCmd.Flags().BoolVar(&flagBackupWipe, "wipe", false, "Delete the existing rows before restoring")
+	_ = restoreCmd.MarkFlagRequired("file")
+}
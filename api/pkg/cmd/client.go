@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+
+	serverEnvVar = "GO_VUE_SERVER"
+)
+
+var (
+	flagClientServer      string
+	flagClientCredentials string
+	flagOutput            string
+)
+
+var clientCmd = &cobra.Command{
+	Use:               "client",
+	Short:             "Talks to a running server",
+	Long:              "Talks to a running server, the server defaults to the last one logged into",
+	PersistentPreRunE: checkOutputFormat,
+}
+
+// checkOutputFormat validates the --output flag, shared by every command
+// printing with printOutput
+func checkOutputFormat(cmd *cobra.Command, args []string) error {
+	if flagOutput != outputTable && flagOutput != outputJSON {
+		return fmt.Errorf("invalid output format: %s", flagOutput)
+	}
+
+	// past this point the errors do not come from the usage
+	cmd.SilenceUsage = true
+
+	return nil
+}
+
+// resolveServer returns the server to talk to, from the flags, the
+// environment or the credentials file
+func resolveServer(creds *credentials) (string, error) {
+	server := flagClientServer
+	if server == "" {
+		server = os.Getenv(serverEnvVar)
+	}
+	if server == "" {
+		server = creds.Current
+	}
+	if server == "" {
+		return "", fmt.Errorf("no server provided, use --server or log in first")
+	}
+
+	return normalizeServer(server), nil
+}
+
+// newAuthenticatedClient returns a client of the current server using
+// the stored credentials
+func newAuthenticatedClient() (*client.Client, error) {
+	creds, err := loadCredentials(flagClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := resolveServer(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := creds.Servers[server]
+	if !ok {
+		return nil, fmt.Errorf("not logged into %s, run the login command first", server)
+	}
+
+	return client.New(server, client.WithToken(sc.Token), client.WithAPIKey(sc.APIKey))
+}
+
+// clientError makes the errors of the API actionable
+func clientError(err error) error {
+	if errors.Is(err, client.ErrUnauthenticated) {
+		return fmt.Errorf("%w, the session probably expired, run the login command again", err)
+	}
+
+	return err
+}
+
+// printOutput writes v as json, or as a table with the given headers and
+// rows
+func printOutput(w io.Writer, v any, headers []string, rows [][]string) error {
+	if flagOutput == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	printRow(tw, headers)
+	for _, row := range rows {
+		printRow(tw, row)
+	}
+
+	return tw.Flush()
+}
+
+func printRow(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
+
+// openBrowser tries to open the url in the browser of the user, failing
+// silently since the url is printed anyway
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	_ = cmd.Start()
+}
+
+func initClientCmd() {
+	clientCmd.PersistentFlags().StringVarP(&flagClientServer, "server", "s", "", "URL of the server, defaults to $"+serverEnvVar+" or the last server logged into")
+	clientCmd.PersistentFlags().StringVar(&flagClientCredentials, "credentials", defaultCredentialsFile(), "Path to the credentials file")
+	clientCmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", outputTable, "Output format, table or json")
+
+	initClientAuthCmds()
+	initClientUserCmds()
+	initClientAdminCmds()
+	initClientOIDCCmds()
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var clientUsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manages the users, requires an admin",
+}
+
+var clientUsersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the users",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		users, err := c.ListUsers(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		rows := make([][]string, 0, len(users))
+		for _, u := range users {
+			rows = append(rows, []string{u.Id, u.Username})
+		}
+
+		return printOutput(os.Stdout, users, []string{"ID", "USERNAME"}, rows)
+	},
+}
+
+var clientUsersGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Shows a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		u, err := c.GetUser(cmd.Context(), args[0])
+		if err != nil {
+			return clientError(err)
+		}
+
+		return printOutput(os.Stdout, u,
+			[]string{"ID", "USERNAME", "EMAIL", "DISPLAY NAME", "ADMIN", "ACTIVE", "CREATED", "LAST LOGIN"},
+			[][]string{{
+				u.Id,
+				u.Username,
+				u.Email,
+				u.DisplayName,
+				strconv.FormatBool(u.Admin),
+				strconv.FormatBool(u.Active),
+				formatTime(u.Created),
+				formatTime(u.LastLogin),
+			}},
+		)
+	},
+}
+
+var clientJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Shows the status of the maintenance jobs, requires an admin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		status, err := c.Jobs(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		rows := make([][]string, 0, len(status.Jobs))
+		for _, job := range status.Jobs {
+			row := []string{job.Name, job.Interval, strconv.FormatBool(job.Running), "-", "-", "-", "-"}
+			if job.LastRun != nil {
+				row[3] = formatTime(job.LastRun.Started)
+				row[4] = job.LastRun.Duration
+				row[5] = job.LastRun.Result
+				if job.LastRun.Error != "" {
+					row[5] = "error: " + job.LastRun.Error
+				}
+			}
+			if job.Next != nil {
+				row[6] = formatTime(*job.Next)
+			}
+			rows = append(rows, row)
+		}
+
+		return printOutput(os.Stdout, status, []string{"NAME", "INTERVAL", "RUNNING", "LAST RUN", "DURATION", "RESULT", "NEXT RUN"}, rows)
+	},
+}
+
+var clientCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Shows the statistics of the session cache of the instance answering, requires an admin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		stats, err := c.SessionCache(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		return printOutput(os.Stdout, stats,
+			[]string{"ENABLED", "HITS", "MISSES", "HIT RATIO", "EVICTIONS", "INVALIDATIONS", "SESSIONS", "USERS"},
+			[][]string{{
+				strconv.FormatBool(stats.Enabled),
+				strconv.FormatUint(stats.Hits, 10),
+				strconv.FormatUint(stats.Misses, 10),
+				strconv.FormatFloat(stats.HitRatio, 'f', 2, 64),
+				strconv.FormatUint(stats.Evictions, 10),
+				strconv.FormatUint(stats.Invalidations, 10),
+				strconv.Itoa(stats.Sessions),
+				strconv.Itoa(stats.Users),
+			}},
+		)
+	},
+}
+
+func initClientAdminCmds() {
+	clientUsersCmd.AddCommand(clientUsersListCmd)
+	clientUsersCmd.AddCommand(clientUsersGetCmd)
+
+	clientCmd.AddCommand(clientUsersCmd)
+	clientCmd.AddCommand(clientJobsCmd)
+	clientCmd.AddCommand(clientCacheCmd)
+}
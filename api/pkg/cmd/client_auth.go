@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/client"
+	"golang.org/x/term"
+)
+
+var (
+	flagLoginUsername string
+	flagLoginBrowser  bool
+	flagLoginAPIKey   string
+)
+
+var clientLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs into a server",
+	Long: `Logs into a server with a password, an API key, or in the browser with
+any of the login methods of the UI, OIDC included. The credentials are
+stored in the credentials file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		creds, err := loadCredentials(flagClientCredentials)
+		if err != nil {
+			return err
+		}
+
+		server, err := resolveServer(creds)
+		if err != nil {
+			return err
+		}
+
+		c, err := client.New(server)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		sc := &serverCredentials{}
+
+		switch {
+		case flagLoginAPIKey != "":
+			sc.APIKey = flagLoginAPIKey
+			if sc.APIKey == "-" {
+				fmt.Print("API key: ")
+				key, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if err != nil {
+					return err
+				}
+				sc.APIKey = strings.TrimSpace(string(key))
+			}
+			c.SetAPIKey(sc.APIKey)
+		case flagLoginBrowser:
+			if err := loginInBrowser(ctx, cmd.OutOrStdout(), c); err != nil {
+				return err
+			}
+			sc.Token = c.Token()
+		default:
+			if err := loginWithPassword(ctx, c); err != nil {
+				return err
+			}
+			sc.Token = c.Token()
+		}
+
+		profile, err := c.Profile(ctx)
+		if err != nil {
+			return clientError(err)
+		}
+		sc.Username = profile.Username
+
+		creds.Servers[server] = sc
+		creds.Current = server
+		if err := creds.save(flagClientCredentials); err != nil {
+			return err
+		}
+
+		fmt.Printf("Logged into %s as %s\n", server, profile.Username)
+		return nil
+	},
+}
+
+var clientLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Logs out of a server",
+	Long:  "Logs out of a server, invalidating the session, and forgets its credentials",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		creds, err := loadCredentials(flagClientCredentials)
+		if err != nil {
+			return err
+		}
+
+		server, err := resolveServer(creds)
+		if err != nil {
+			return err
+		}
+
+		sc, ok := creds.Servers[server]
+		if !ok {
+			return fmt.Errorf("not logged into %s", server)
+		}
+
+		if sc.Token != "" {
+			c, err := client.New(server, client.WithToken(sc.Token))
+			if err != nil {
+				return err
+			}
+			if err := c.Logout(cmd.Context()); err != nil {
+				fmt.Fprintf(os.Stderr, "could not invalidate the session: %s\n", err)
+			}
+		}
+
+		delete(creds.Servers, server)
+		if creds.Current == server {
+			creds.Current = ""
+		}
+
+		return creds.save(flagClientCredentials)
+	},
+}
+
+func loginWithPassword(ctx context.Context, c *client.Client) error {
+	username := flagLoginUsername
+	if username == "" {
+		fmt.Print("Username: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		username = strings.TrimSpace(line)
+	}
+
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Login(ctx, username, string(password))
+	return err
+}
+
+// loginInBrowser logs c in with a device authorization, telling the user
+// on w which code to confirm in the browser
+func loginInBrowser(ctx context.Context, w io.Writer, c *client.Client) error {
+	auth, err := c.StartDeviceAuthorization(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Open %s and confirm the code %s\n", auth.VerificationURIComplete, auth.UserCode)
+	openBrowser(auth.VerificationURIComplete)
+
+	_, err = c.WaitForDeviceToken(ctx, auth)
+	return err
+}
+
+func initClientAuthCmds() {
+	clientLoginCmd.Flags().StringVarP(&flagLoginUsername, "username", "u", "", "Username to log in with a password, prompted when missing")
+	clientLoginCmd.Flags().BoolVar(&flagLoginBrowser, "browser", false, "Log in through the browser")
+	clientLoginCmd.Flags().StringVar(&flagLoginAPIKey, "api-key", "", "API key to authenticate with, - to be prompted for it")
+	clientLoginCmd.MarkFlagsMutuallyExclusive("username", "browser", "api-key")
+
+	clientCmd.AddCommand(clientLoginCmd)
+	clientCmd.AddCommand(clientLogoutCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/api/apitest"
+	"github.com/thomas-maurice/api/go-vue/pkg/client"
+)
+
+// loginInBrowserWith runs loginInBrowser against a fresh API, calling
+// confirm with the user code it prints before the first poll
+func loginInBrowserWith(t *testing.T, confirm func(h *apitest.Harness, admin *client.Client, code string) error) (*client.Client, error) {
+	t.Helper()
+
+	// no browser to open
+	t.Setenv("PATH", "")
+
+	h := apitest.New(t, nil)
+	srv := httptest.NewServer(h.API.Router)
+	t.Cleanup(srv.Close)
+
+	admin, err := client.New(srv.URL, client.WithToken(h.LoginAsAdmin(t)))
+	if err != nil {
+		t.Fatalf("could not create the client: %s", err)
+	}
+	device, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatalf("could not create the client: %s", err)
+	}
+
+	pr, pw := io.Pipe()
+	confirmed := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(pr).ReadString('\n')
+		if err != nil {
+			confirmed <- err
+			return
+		}
+		_, code, ok := strings.Cut(strings.TrimSpace(line), "confirm the code ")
+		if !ok || !strings.HasPrefix(line, "Open "+srv.URL+"/device?user_code=") {
+			confirmed <- errors.New("unexpected instructions: " + line)
+			return
+		}
+		confirmed <- confirm(h, admin, code)
+	}()
+
+	err = loginInBrowser(t.Context(), pw, device)
+	if err := <-confirmed; err != nil {
+		t.Fatalf("could not confirm the code: %s", err)
+	}
+
+	return device, err
+}
+
+func TestLoginInBrowser(t *testing.T) {
+	device, err := loginInBrowserWith(t, func(h *apitest.Harness, admin *client.Client, code string) error {
+		return admin.ApproveDevice(t.Context(), code)
+	})
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+
+	profile, err := device.Profile(t.Context())
+	if err != nil {
+		t.Fatalf("could not get the profile: %s", err)
+	}
+	if profile.Username != apitest.AdminUsername {
+		t.Fatalf("expected to be logged in as the admin, got %s", profile.Username)
+	}
+}
+
+func TestLoginInBrowserExpired(t *testing.T) {
+	device, err := loginInBrowserWith(t, func(h *apitest.Harness, admin *client.Client, code string) error {
+		h.Clock.Advance(time.Hour)
+		return nil
+	})
+	if !errors.Is(err, client.ErrExpiredToken) {
+		t.Fatalf("expected the authorization to expire, got %v", err)
+	}
+	if device.Token() != "" {
+		t.Fatal("the client kept a token")
+	}
+}
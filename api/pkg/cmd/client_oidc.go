@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/client"
+)
+
+var flagOIDCProvider client.NewOIDCProvider
+
+var clientOIDCCmd = &cobra.Command{
+	Use:   "oidc",
+	Short: "Manages the OIDC providers",
+}
+
+var clientOIDCListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the OIDC providers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		providers, err := c.OIDCProviders(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		rows := make([][]string, 0, len(providers))
+		for _, p := range providers {
+			rows = append(rows, []string{p.Name, p.DisplayName})
+		}
+
+		return printOutput(os.Stdout, providers, []string{"NAME", "DISPLAY NAME"}, rows)
+	},
+}
+
+var clientOIDCCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates an OIDC provider, requires an admin",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		p, err := c.CreateOIDCProvider(cmd.Context(), &flagOIDCProvider)
+		if err != nil {
+			return clientError(err)
+		}
+
+		return printOutput(os.Stdout, p, []string{"NAME", "DISPLAY NAME"}, [][]string{{p.Name, p.DisplayName}})
+	},
+}
+
+func initClientOIDCCmds() {
+	clientOIDCCreateCmd.Flags().StringVar(&flagOIDCProvider.Name, "name", "", "Name of the provider")
+	clientOIDCCreateCmd.Flags().StringVar(&flagOIDCProvider.DisplayName, "display-name", "", "Name of the provider shown to the users")
+	clientOIDCCreateCmd.Flags().StringVar(&flagOIDCProvider.Issuer, "issuer", "", "Issuer URL")
+	clientOIDCCreateCmd.Flags().StringVar(&flagOIDCProvider.ClientID, "client-id", "", "Client ID")
+	clientOIDCCreateCmd.Flags().StringVar(&flagOIDCProvider.ClientSecret, "client-secret", "", "Client secret")
+	clientOIDCCreateCmd.Flags().StringSliceVar(&flagOIDCProvider.Scopes, "scopes", []string{"openid", "profile", "email"}, "Scopes to request")
+	for _, flag := range []string{"name", "issuer", "client-id", "client-secret"} {
+		_ = clientOIDCCreateCmd.MarkFlagRequired(flag)
+	}
+
+	clientOIDCCmd.AddCommand(clientOIDCListCmd)
+	clientOIDCCmd.AddCommand(clientOIDCCreateCmd)
+
+	clientCmd.AddCommand(clientOIDCCmd)
+}
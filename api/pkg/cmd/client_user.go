@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var clientProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Shows the profile of the logged in user",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		profile, err := c.Profile(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		return printOutput(os.Stdout, profile,
+			[]string{"ID", "USERNAME", "DISPLAY NAME", "KIND", "ADMIN", "LAST LOGIN"},
+			[][]string{{
+				profile.Id,
+				profile.Username,
+				profile.DisplayName,
+				profile.Kind,
+				strconv.FormatBool(profile.Admin),
+				formatTime(profile.LastLogin),
+			}},
+		)
+	},
+}
+
+var clientSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manages the sessions of the logged in user",
+}
+
+var clientSessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the active sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		sessions, err := c.Sessions(cmd.Context())
+		if err != nil {
+			return clientError(err)
+		}
+
+		rows := make([][]string, 0, len(sessions))
+		for _, s := range sessions {
+			current := ""
+			if s.Current {
+				current = "*"
+			}
+			rows = append(rows, []string{s.Id, formatTime(s.Expires), current})
+		}
+
+		return printOutput(os.Stdout, sessions, []string{"ID", "EXPIRES", "CURRENT"}, rows)
+	},
+}
+
+var clientSessionsRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>...",
+	Short: "Revokes sessions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newAuthenticatedClient()
+		if err != nil {
+			return err
+		}
+
+		for _, id := range args {
+			if err := c.RevokeSession(cmd.Context(), id); err != nil {
+				return clientError(err)
+			}
+			fmt.Printf("Revoked session %s\n", id)
+		}
+
+		return nil
+	},
+}
+
+func initClientUserCmds() {
+	clientSessionsCmd.AddCommand(clientSessionsListCmd)
+	clientSessionsCmd.AddCommand(clientSessionsRevokeCmd)
+
+	clientCmd.AddCommand(clientProfileCmd)
+	clientCmd.AddCommand(clientSessionsCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const credentialsFileName = "credentials.json"
+
+// serverCredentials are how the client authenticates against a server
+type serverCredentials struct {
+	Token    string `json:"token,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// credentials is the content of the credentials file of the client, the
+// credentials of every server it logged into along with the last one,
+// used by default.
+type credentials struct {
+	Current string                        `json:"current,omitempty"`
+	Servers map[string]*serverCredentials `json:"servers"`
+}
+
+// defaultCredentialsFile returns the path of the credentials file in the
+// configuration directory of the user
+func defaultCredentialsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return credentialsFileName
+	}
+
+	return filepath.Join(dir, "go-vue", credentialsFileName)
+}
+
+func normalizeServer(server string) string {
+	return strings.TrimSuffix(server, "/")
+}
+
+func loadCredentials(pth string) (*credentials, error) {
+	creds := &credentials{Servers: make(map[string]*serverCredentials)}
+
+	b, err := os.ReadFile(pth)
+	if errors.Is(err, fs.ErrNotExist) {
+		return creds, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, creds); err != nil {
+		return nil, err
+	}
+	if creds.Servers == nil {
+		creds.Servers = make(map[string]*serverCredentials)
+	}
+
+	return creds, nil
+}
+
+// save writes the credentials, readable by the user only
+func (c *credentials) save(pth string) error {
+	if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := pth + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, pth)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "go-vue", credentialsFileName)
+
+	t.Run("Missing", func(t *testing.T) {
+		creds, err := loadCredentials(pth)
+		if err != nil {
+			t.Fatalf("could not load a missing file: %s", err)
+		}
+		if creds.Current != "" || creds.Servers == nil || len(creds.Servers) != 0 {
+			t.Fatalf("expected empty credentials, got %+v", creds)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		creds := &credentials{
+			Current: "https://a.example.com",
+			Servers: map[string]*serverCredentials{
+				"https://a.example.com": {Token: "token", Username: "alice"},
+				"https://b.example.com": {APIKey: "key"},
+			},
+		}
+		if err := creds.save(pth); err != nil {
+			t.Fatalf("could not save the credentials: %s", err)
+		}
+
+		info, err := os.Stat(pth)
+		if err != nil {
+			t.Fatalf("could not stat the credentials: %s", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Fatalf("expected the credentials to be readable by the user only, got %o", perm)
+		}
+		if info, err := os.Stat(filepath.Dir(pth)); err != nil || info.Mode().Perm() != 0700 {
+			t.Fatalf("expected the directory to be private, got %v %v", info, err)
+		}
+		if _, err := os.Stat(pth + ".tmp"); !os.IsNotExist(err) {
+			t.Fatalf("the temporary file was left behind: %v", err)
+		}
+
+		loaded, err := loadCredentials(pth)
+		if err != nil {
+			t.Fatalf("could not load the credentials: %s", err)
+		}
+		if !reflect.DeepEqual(loaded, creds) {
+			t.Fatalf("expected %+v, got %+v", creds, loaded)
+		}
+
+		// the file stays private when overwritten
+		if err := os.Chmod(pth, 0644); err != nil {
+			t.Fatalf("could not change the permissions: %s", err)
+		}
+		if err := loaded.save(pth); err != nil {
+			t.Fatalf("could not save the credentials: %s", err)
+		}
+		if info, err := os.Stat(pth); err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("expected the overwritten credentials to be private, got %v %v", info, err)
+		}
+	})
+
+	t.Run("NoServers", func(t *testing.T) {
+		if err := os.WriteFile(pth, []byte(`{"current":"https://a.example.com"}`), 0600); err != nil {
+			t.Fatalf("could not write the credentials: %s", err)
+		}
+
+		creds, err := loadCredentials(pth)
+		if err != nil {
+			t.Fatalf("could not load the credentials: %s", err)
+		}
+		if creds.Servers == nil {
+			t.Fatal("the servers were not initialized")
+		}
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		if err := os.WriteFile(pth, []byte(`{"servers":`), 0600); err != nil {
+			t.Fatalf("could not write the credentials: %s", err)
+		}
+
+		if _, err := loadCredentials(pth); err == nil {
+			t.Fatal("expected a corrupt file to be rejected")
+		}
+	})
+
+	t.Run("Unreadable", func(t *testing.T) {
+		if _, err := loadCredentials(t.TempDir()); err == nil {
+			t.Fatal("expected a directory to be rejected")
+		}
+	})
+}
@@ -12,7 +12,7 @@ import (
 var hashPassCmd = &cobra.Command{
 	Use:   "hashpass",
 	Short: "Hashes a password for a user",
-	Long:  "",
+	Long:  "Hashes a password for a user, the hash can be used as the adminPassword of the configuration",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Print("Password: ")
 		password, err := term.ReadPassword(int(os.Stdin.Fd()))
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	sqlconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+	sqluserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql"
+	"github.com/thomas-maurice/api/go-vue/pkg/store"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// services are the services the admin commands work with, directly on
+// the database of the configuration, without starting the server
+type services struct {
+	Users  userservice.UserService
+	Config configservice.ConfigService
+}
+
+// openDatabase opens the database of the configuration, along with the
+// func closing it
+func openDatabase(cfg *config.Config) (*gorm.DB, func(), error) {
+	db, err := store.NewSqlStore(context.Background(), cfg.Storage, slog.Default())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	closeDB := func() { _ = sqlDB.Close() }
+
+	// the errors are reported by the commands themselves
+	return db.Session(&gorm.Session{Logger: db.Logger.LogMode(gormlogger.Silent)}), closeDB, nil
+}
+
+// loadServices opens the services of the configuration, along with the
+// func closing their database
+func loadServices(cfgFile string) (*services, func(), error) {
+	cfg, err := config.LoadFromFile(cfgFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pKey, err := cfg.Security.ParseSigningKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, closeDB, err := openDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	svc, err := newServices(cfg, db, pKey)
+	if err != nil {
+		closeDB()
+		return nil, nil, err
+	}
+
+	return svc, closeDB, nil
+}
+
+// newServices builds the services on the database
+func newServices(cfg *config.Config, db *gorm.DB, pKey *ecdsa.PrivateKey) (*services, error) {
+	sqlUs, err := sqluserservice.NewUserService(db, pKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the running instances caching the sessions are told about the
+	// changes, nothing is cached here
+	var us userservice.UserService = sqlUs
+	if cfg.SessionCache.Enabled && cfg.SessionCache.Notifier != cacheuserservice.NotifierNone {
+		notifier, err := cacheuserservice.NewSQLNotifier(db)
+		if err != nil {
+			return nil, err
+		}
+		us = cacheuserservice.NewUserService(sqlUs, notifier, 0, 0)
+	}
+
+	cs, err := sqlconfigservice.NewConfigService(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &services{Users: us, Config: cs}, nil
+}
+
+// findUser looks a user up by username, then by id
+func findUser(ctx context.Context, us userservice.UserService, ref string) (*userservice.User, error) {
+	user, err := us.GetUserByUsername(ctx, ref)
+	if errors.Is(err, userservice.ErrUserNotFound) {
+		user, err = us.GetUserById(ctx, ref)
+	}
+	if errors.Is(err, userservice.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: %s", err, ref)
+	}
+
+	return user, err
+}
+
+// readNewPassword prompts for a password twice when the input of cmd is
+// a terminal, otherwise it reads it from the first line of the input
+func readNewPassword(cmd *cobra.Command) (string, error) {
+	in := cmd.InOrStdin()
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("could not read the password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprint(out, "Password: ")
+	password, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprint(out, "Confirm password: ")
+	confirm, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", err
+	}
+
+	if string(password) != string(confirm) {
+		return "", fmt.Errorf("the passwords do not match")
+	}
+
+	return string(password), nil
+}
+
+// confirm asks a yes/no question on the input of cmd, defaulting to no
+func confirm(cmd *cobra.Command, question string) bool {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s [y/N] ", question)
+	line, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+// addOfflineFlags adds the flags shared by the commands working on the
+// database
+func addOfflineFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&flagConfigFile, "config", "c", "config.yaml", "Path to the configuration file")
+	cmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", outputTable, "Output format, table or json")
+	cmd.PersistentPreRunE = checkOutputFormat
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+	"gopkg.in/yaml.v3"
+)
+
+// offlineConfig writes the configuration of a fresh sqlite database,
+// changed by the configure funcs, and returns its path along with the
+// services working on it
+func offlineConfig(t *testing.T, configure ...func(*config.Config)) (string, *services) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not encode the signing key: %s", err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.Config{
+		Storage:  config.StorageConfig{Driver: "sqlite", URL: filepath.Join(dir, "db.sqlite3")},
+		Security: config.SecurityConfig{SigninigKey: string(pem.EncodeToMemory(&pem.Block{Type: "ECDSA PRIVATE KEY", Bytes: der}))},
+	}
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	b, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("could not encode the configuration: %s", err)
+	}
+
+	pth := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(pth, b, 0600); err != nil {
+		t.Fatalf("could not write the configuration: %s", err)
+	}
+
+	svc, closeDB, err := loadServices(pth)
+	if err != nil {
+		t.Fatalf("could not load the services: %s", err)
+	}
+	t.Cleanup(closeDB)
+
+	return pth, svc
+}
+
+// resetFlags sets the flags back to their default, the commands being
+// global their values would otherwise leak from one run to the next
+func resetFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		_ = f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+}
+
+// runCommand runs the command line with stdin as its input, and returns
+// its output
+func runCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil {
+		t.Fatalf("unknown command %v: %s", args, err)
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		resetFlags(c.Flags())
+		resetFlags(c.PersistentFlags())
+	}
+	// the commands keep the context of their first run otherwise
+	cmd.SetContext(t.Context())
+
+	var out bytes.Buffer
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	t.Cleanup(func() {
+		rootCmd.SetIn(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+
+	err = rootCmd.ExecuteContext(t.Context())
+	return out.String(), err
+}
+
+// createUser creates a local user with the password hunter2
+func createUser(t *testing.T, cfgFile string, username string, flags ...string) {
+	t.Helper()
+
+	if out, err := runCommand(t, "hunter2\n", append([]string{"user", "create", username, "-c", cfgFile}, flags...)...); err != nil {
+		t.Fatalf("could not create %s: %s\n%s", username, err, out)
+	}
+}
+
+func TestUserCreate(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+
+	out, err := runCommand(t, "hunter2\n", "user", "create", "alice", "--admin", "--display-name", "Alice", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not create the user: %s", err)
+	}
+	if !strings.Contains(out, "alice@localhost") {
+		t.Fatalf("the user was not printed: %s", out)
+	}
+
+	user, err := svc.Users.Authenticate(t.Context(), "alice", "hunter2")
+	if err != nil {
+		t.Fatalf("could not log in with the password read from stdin: %s", err)
+	}
+	if !user.Admin || !user.Active || user.DisplayName != "Alice" || user.Kind != userservice.UserKindLocal {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	if _, err := runCommand(t, "", "user", "create", "bob", "-c", cfgFile); err == nil {
+		t.Fatal("expected a missing password to be rejected")
+	}
+
+	out, err = runCommand(t, "", "user", "create", "bob", "--no-password", "--email", "bob@example.com", "-o", "json", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not create the user without a password: %s", err)
+	}
+	if !strings.Contains(out, `"email": "bob@example.com"`) {
+		t.Fatalf("the user was not printed as json: %s", out)
+	}
+	if bob, err := svc.Users.GetUserByUsername(t.Context(), "bob"); err != nil || bob.Admin {
+		t.Fatalf("the flags of the previous run leaked: %+v %v", bob, err)
+	}
+	if _, err := svc.Users.Authenticate(t.Context(), "bob", ""); !errors.Is(err, userservice.ErrInvalidCredentials) {
+		t.Fatalf("expected the user without a password not to log in, got %v", err)
+	}
+
+	if _, err := runCommand(t, "hunter2\n", "user", "create", "alice", "-c", cfgFile); err == nil {
+		t.Fatal("expected a duplicate user to be rejected")
+	}
+}
+
+func TestUserSetPassword(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+	createUser(t, cfgFile, "alice")
+
+	alice, err := svc.Users.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("could not get the user: %s", err)
+	}
+
+	// the user is found by id too
+	out, err := runCommand(t, "correct horse\r\n", "user", "set-password", alice.Id, "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not set the password: %s", err)
+	}
+	if out != "Password of alice updated\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if _, err := svc.Users.Authenticate(t.Context(), "alice", "correct horse"); err != nil {
+		t.Fatalf("could not log in with the new password: %s", err)
+	}
+
+	if _, err := svc.Users.CreateUser(t.Context(), "carol@example.com", "carol@example.com", "", string(userservice.UserKindOIDC), false, ""); err != nil {
+		t.Fatalf("could not create the oidc user: %s", err)
+	}
+	if _, err := runCommand(t, "hunter2\n", "user", "set-password", "carol@example.com", "-c", cfgFile); err == nil {
+		t.Fatal("expected the password of an oidc user to be rejected")
+	}
+
+	if _, err := runCommand(t, "hunter2\n", "user", "set-password", "nobody", "-c", cfgFile); !errors.Is(err, userservice.ErrUserNotFound) {
+		t.Fatalf("expected an unknown user to be rejected, got %v", err)
+	}
+}
+
+func TestUserPromote(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+	createUser(t, cfgFile, "alice")
+
+	for _, admin := range []bool{true, false} {
+		verb := "demote"
+		if admin {
+			verb = "promote"
+		}
+		if _, err := runCommand(t, "", "user", verb, "alice", "-c", cfgFile); err != nil {
+			t.Fatalf("could not %s the user: %s", verb, err)
+		}
+
+		alice, err := svc.Users.GetUserByUsername(t.Context(), "alice")
+		if err != nil {
+			t.Fatalf("could not get the user: %s", err)
+		}
+		if alice.Admin != admin {
+			t.Fatalf("expected admin to be %t after %s", admin, verb)
+		}
+	}
+}
+
+func TestUserDeactivate(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+	createUser(t, cfgFile, "alice")
+
+	alice, err := svc.Users.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("could not get the user: %s", err)
+	}
+	token, err := svc.Users.GenerateSessionToken(t.Context(), alice)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+
+	out, err := runCommand(t, "", "user", "deactivate", "alice", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+	if out != "alice deactivated, 1 sessions revoked\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if _, _, err := svc.Users.VerifySessionToken(t.Context(), token); !errors.Is(err, userservice.ErrInvalidSession) {
+		t.Fatalf("expected the session to be revoked, got %v", err)
+	}
+	if _, err := svc.Users.Authenticate(t.Context(), "alice", "hunter2"); !errors.Is(err, userservice.ErrUserDeactivated) {
+		t.Fatalf("expected the deactivated user not to log in, got %v", err)
+	}
+
+	if _, err := runCommand(t, "", "user", "activate", "alice", "-c", cfgFile); err != nil {
+		t.Fatalf("could not activate the user: %s", err)
+	}
+	if _, err := svc.Users.Authenticate(t.Context(), "alice", "hunter2"); err != nil {
+		t.Fatalf("could not log in once activated: %s", err)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+	createUser(t, cfgFile, "alice")
+	createUser(t, cfgFile, "bob")
+
+	for _, answer := range []string{"", "n\n", "whatever\n"} {
+		if _, err := runCommand(t, answer, "user", "delete", "alice", "-c", cfgFile); err == nil || err.Error() != "aborted" {
+			t.Fatalf("expected the deletion to be aborted on %q, got %v", answer, err)
+		}
+	}
+	if _, err := svc.Users.GetUserByUsername(t.Context(), "alice"); err != nil {
+		t.Fatalf("the user was deleted without a confirmation: %s", err)
+	}
+
+	out, err := runCommand(t, "yes\n", "user", "delete", "alice", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not delete the user: %s", err)
+	}
+	if !strings.HasSuffix(out, "alice deleted\n") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	if _, err := runCommand(t, "", "user", "delete", "bob", "--yes", "-c", cfgFile); err != nil {
+		t.Fatalf("could not delete the user without a confirmation: %s", err)
+	}
+
+	users, err := svc.Users.ListUsers(t.Context())
+	if err != nil {
+		t.Fatalf("could not list the users: %s", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("expected every user to be deleted, got %+v", users)
+	}
+}
+
+func TestSessionRevokeAll(t *testing.T) {
+	cfgFile, svc := offlineConfig(t)
+	createUser(t, cfgFile, "alice")
+	createUser(t, cfgFile, "bob")
+
+	tokens := make(map[string]string)
+	for _, username := range []string{"alice", "bob"} {
+		user, err := svc.Users.GetUserByUsername(t.Context(), username)
+		if err != nil {
+			t.Fatalf("could not get the user: %s", err)
+		}
+		if tokens[username], err = svc.Users.GenerateSessionToken(t.Context(), user); err != nil {
+			t.Fatalf("could not log in: %s", err)
+		}
+	}
+	valid := func(username string) bool {
+		_, _, err := svc.Users.VerifySessionToken(t.Context(), tokens[username])
+		return err == nil
+	}
+
+	for _, args := range [][]string{{}, {"alice", "--all-users"}} {
+		if _, err := runCommand(t, "", append([]string{"session", "revoke-all", "-c", cfgFile}, args...)...); err == nil {
+			t.Fatalf("expected %v to be rejected", args)
+		}
+	}
+
+	out, err := runCommand(t, "", "session", "revoke-all", "alice", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not revoke the sessions: %s", err)
+	}
+	if out != "1 sessions revoked\n" || valid("alice") || !valid("bob") {
+		t.Fatalf("expected the sessions of alice only to be revoked: %q", out)
+	}
+
+	out, err = runCommand(t, "", "session", "revoke-all", "--all-users", "-c", cfgFile)
+	if err != nil {
+		t.Fatalf("could not revoke the sessions: %s", err)
+	}
+	if out != "1 sessions revoked\n" || valid("bob") {
+		t.Fatalf("expected the sessions of every user to be revoked: %q", out)
+	}
+}
+
+func TestSessionCacheInvalidations(t *testing.T) {
+	cfgFile, svc := offlineConfig(t, func(cfg *config.Config) {
+		cfg.SessionCache = config.SessionCacheConfig{Enabled: true, Notifier: cacheuserservice.NotifierSQL}
+	})
+	createUser(t, cfgFile, "alice")
+
+	cached, ok := svc.Users.(*cacheuserservice.UserService)
+	if !ok {
+		t.Fatalf("expected the users to publish the invalidations, got %T", svc.Users)
+	}
+
+	alice, err := svc.Users.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("could not get the user: %s", err)
+	}
+	token, err := svc.Users.GenerateSessionToken(t.Context(), alice)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+	for range 2 {
+		if _, _, err := svc.Users.VerifySessionToken(t.Context(), token); err != nil {
+			t.Fatalf("could not verify the session: %s", err)
+		}
+	}
+	if stats := cached.Stats(); stats.Hits != 0 || stats.Sessions != 0 || stats.Users != 0 {
+		t.Fatalf("expected nothing to be cached, got %+v", stats)
+	}
+
+	if _, err := runCommand(t, "", "user", "deactivate", "alice", "-c", cfgFile); err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+
+	cfg, err := config.LoadFromFile(cfgFile)
+	if err != nil {
+		t.Fatalf("could not load the configuration: %s", err)
+	}
+	db, closeDB, err := openDatabase(cfg)
+	if err != nil {
+		t.Fatalf("could not open the database: %s", err)
+	}
+	defer closeDB()
+
+	var published int64
+	if err := db.Table("cache_invalidations").Where("kind = ? AND invalidation_key = ?", cacheuserservice.InvalidateUser, alice.Id).Count(&published).Error; err != nil {
+		t.Fatalf("could not count the invalidations: %s", err)
+	}
+	if published == 0 {
+		t.Fatal("expected the deactivation to be published to the running instances")
+	}
+
+	if _, _, err := svc.Users.VerifySessionToken(t.Context(), token); !errors.Is(err, userservice.ErrInvalidSession) {
+		t.Fatalf("expected the session not to be served from a cache, got %v", err)
+	}
+}
+
+func TestReadNewPassword(t *testing.T) {
+	for input, expected := range map[string]string{
+		"hunter2\n":       "hunter2",
+		"hunter2\r\n":     "hunter2",
+		"hunter2":         "hunter2",
+		" spaced out \n":  " spaced out ",
+		"first\nsecond\n": "first",
+	} {
+		cmd := &cobra.Command{}
+		cmd.SetIn(strings.NewReader(input))
+
+		password, err := readNewPassword(cmd)
+		if err != nil {
+			t.Fatalf("could not read the password from %q: %s", input, err)
+		}
+		if password != expected {
+			t.Fatalf("expected %q from %q, got %q", expected, input, password)
+		}
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader(""))
+	if _, err := readNewPassword(cmd); err == nil {
+		t.Fatal("expected an empty input to be rejected")
+	}
+}
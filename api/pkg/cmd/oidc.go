@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+)
+
+var flagOIDCAdd configservice.OIDCProvider
+
+var oidcCmd = &cobra.Command{
+	Use:   "oidc",
+	Short: "Manages the OIDC providers directly in the database",
+	Long: `Manages the OIDC providers directly in the database. The providers of
+the configuration file are written back on every start of the server.`,
+}
+
+var oidcListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the OIDC providers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		providers, err := svc.Config.GetOIDCProviders(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(providers))
+		for i := range providers {
+			p := &providers[i]
+			rows = append(rows, []string{
+				p.Name,
+				p.DisplayName,
+				p.Issuer,
+				p.ClientID,
+				strings.Join(p.Scopes, ","),
+				strconv.FormatBool(p.Active),
+			})
+			// never print the secrets
+			p.ClientSecret = ""
+		}
+
+		return printOutput(cmd.OutOrStdout(), providers, []string{"NAME", "DISPLAY NAME", "ISSUER", "CLIENT ID", "SCOPES", "ACTIVE"}, rows)
+	},
+}
+
+var oidcAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Adds an OIDC provider",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		prov := flagOIDCAdd
+		prov.Name = args[0]
+		prov.Active = true
+		prov.Created = time.Now()
+		if prov.DisplayName == "" {
+			prov.DisplayName = prov.Name
+		}
+
+		if _, err := svc.Config.CreateOIDCProvider(cmd.Context(), &prov); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "OIDC provider %s added\n", prov.Name)
+		return nil
+	},
+}
+
+var oidcRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Removes an OIDC provider",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		if err := svc.Config.DeleteOIDCProvider(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("%w: %s", err, args[0])
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "OIDC provider %s removed\n", args[0])
+		return nil
+	},
+}
+
+func initOIDCCmd() {
+	addOfflineFlags(oidcCmd)
+
+	oidcAddCmd.Flags().StringVar(&flagOIDCAdd.DisplayName, "display-name", "", "Name of the provider shown to the users, defaults to its name")
+	oidcAddCmd.Flags().StringVar(&flagOIDCAdd.Issuer, "issuer", "", "Issuer URL")
+	oidcAddCmd.Flags().StringVar(&flagOIDCAdd.ClientID, "client-id", "", "Client ID")
+	oidcAddCmd.Flags().StringVar(&flagOIDCAdd.ClientSecret, "client-secret", "", "Client secret")
+	oidcAddCmd.Flags().StringSliceVar(&flagOIDCAdd.Scopes, "scopes", []string{"openid", "profile", "email"}, "Scopes to request")
+	for _, flag := range []string{"issuer", "client-id", "client-secret"} {
+		_ = oidcAddCmd.MarkFlagRequired(flag)
+	}
+
+	oidcCmd.AddCommand(oidcListCmd)
+	oidcCmd.AddCommand(oidcAddCmd)
+	oidcCmd.AddCommand(oidcRemoveCmd)
+}
@@ -16,8 +16,19 @@ func init() {
 	initGenKeyCmd()
 	initServerCmd()
 	initHashPassCmd()
+	initClientCmd()
+	initUserCmd()
+	initSessionCmd()
+	initOIDCCmd()
+	initBackupCmds()
 
 	rootCmd.AddCommand(genKeyCmd)
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(hashPassCmd)
+	rootCmd.AddCommand(clientCmd)
+	rootCmd.AddCommand(userCmd)
+	rootCmd.AddCommand(sessionCmd)
+	rootCmd.AddCommand(oidcCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var flagSessionAllUsers bool
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Manages the sessions directly in the database",
+}
+
+var sessionRevokeAllCmd = &cobra.Command{
+	Use:   "revoke-all [<user>]",
+	Short: "Revokes the sessions of a user, or of every user",
+	Long: `Revokes the sessions of a user, given by username or id, or of every
+user with --all-users, logging them out. API keys are not affected.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (len(args) == 0) != flagSessionAllUsers {
+			return fmt.Errorf("either a user or --all-users is required")
+		}
+
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		userId := ""
+		if len(args) == 1 {
+			user, err := findUser(cmd.Context(), svc.Users, args[0])
+			if err != nil {
+				return err
+			}
+			userId = user.Id
+		}
+
+		revoked, err := svc.Users.RevokeAllSessions(cmd.Context(), userId)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%d sessions revoked\n", revoked)
+		return nil
+	},
+}
+
+func initSessionCmd() {
+	addOfflineFlags(sessionCmd)
+
+	sessionRevokeAllCmd.Flags().BoolVar(&flagSessionAllUsers, "all-users", false, "Revoke the sessions of every user")
+
+	sessionCmd.AddCommand(sessionRevokeAllCmd)
+}
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+var (
+	flagUserEmail       string
+	flagUserDisplayName string
+	flagUserAdmin       bool
+	flagUserNoPassword  bool
+	flagUserYes         bool
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manages the users directly in the database",
+	Long: `Manages the users directly in the database, without going through a
+running server, for instance to recover a locked out admin.`,
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <username>",
+	Short: "Creates a local user",
+	Long: `Creates a local user. The password is prompted for, or read from the
+first line of the standard input when it is not a terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		password := ""
+		if !flagUserNoPassword {
+			password, err = readNewPassword(cmd)
+			if err != nil {
+				return err
+			}
+		}
+
+		email := flagUserEmail
+		if email == "" {
+			email = args[0] + "@localhost"
+		}
+
+		user, err := svc.Users.CreateUser(cmd.Context(), args[0], email, password, string(userservice.UserKindLocal), flagUserAdmin, flagUserDisplayName)
+		if err != nil {
+			return err
+		}
+
+		return printUsers(cmd.OutOrStdout(), []userservice.User{*user}, user)
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		users, err := svc.Users.ListUsers(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return printUsers(cmd.OutOrStdout(), users, users)
+	},
+}
+
+var userSetPasswordCmd = &cobra.Command{
+	Use:   "set-password <user>",
+	Short: "Sets the password of a local user",
+	Long: `Sets the password of a local user, given by username or id. The
+password is prompted for, or read from the first line of the standard
+input when it is not a terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		user, err := findUser(cmd.Context(), svc.Users, args[0])
+		if err != nil {
+			return err
+		}
+
+		if user.Kind != userservice.UserKindLocal {
+			return fmt.Errorf("%s is a %s user, it cannot log in with a password", user.Username, user.Kind)
+		}
+
+		password, err := readNewPassword(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Users.SetPassword(cmd.Context(), user.Id, password); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Password of %s updated\n", user.Username)
+		return nil
+	},
+}
+
+// newUserAdminCmd returns the command granting or removing the admin
+// rights of a user
+func newUserAdminCmd(use string, short string, admin bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <user>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, closeDB, err := loadServices(flagConfigFile)
+			if err != nil {
+				return err
+			}
+			defer closeDB()
+
+			user, err := findUser(cmd.Context(), svc.Users, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := svc.Users.UpdateUser(cmd.Context(), user.Id, "", admin, ""); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is now admin: %t\n", user.Username, admin)
+			return nil
+		},
+	}
+}
+
+var userPromoteCmd = newUserAdminCmd("promote", "Grants the admin rights to a user", true)
+var userDemoteCmd = newUserAdminCmd("demote", "Removes the admin rights of a user", false)
+
+var userDeactivateCmd = &cobra.Command{
+	Use:   "deactivate <user>",
+	Short: "Deactivates a user and revokes its sessions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		user, err := findUser(cmd.Context(), svc.Users, args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Users.SetActive(cmd.Context(), user.Id, false); err != nil {
+			return err
+		}
+
+		revoked, err := svc.Users.RevokeAllSessions(cmd.Context(), user.Id)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s deactivated, %d sessions revoked\n", user.Username, revoked)
+		return nil
+	},
+}
+
+var userActivateCmd = &cobra.Command{
+	Use:   "activate <user>",
+	Short: "Activates a deactivated user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		user, err := findUser(cmd.Context(), svc.Users, args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := svc.Users.SetActive(cmd.Context(), user.Id, true); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s activated\n", user.Username)
+		return nil
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <user>",
+	Short: "Deletes a user along with its sessions and API keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc, closeDB, err := loadServices(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		user, err := findUser(cmd.Context(), svc.Users, args[0])
+		if err != nil {
+			return err
+		}
+
+		if !flagUserYes && !confirm(cmd, fmt.Sprintf("Delete %s (%s)?", user.Username, user.Id)) {
+			return fmt.Errorf("aborted")
+		}
+
+		if err := svc.Users.DeleteUser(cmd.Context(), user.Id); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s deleted\n", user.Username)
+		return nil
+	},
+}
+
+func printUsers(w io.Writer, users []userservice.User, v any) error {
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{
+			u.Id,
+			u.Username,
+			u.Email,
+			string(u.Kind),
+			strconv.FormatBool(u.Admin),
+			strconv.FormatBool(u.Active),
+			formatTime(u.LastLogin),
+		})
+	}
+
+	return printOutput(w, v, []string{"ID", "USERNAME", "EMAIL", "KIND", "ADMIN", "ACTIVE", "LAST LOGIN"}, rows)
+}
+
+func initUserCmd() {
+	addOfflineFlags(userCmd)
+
+	userCreateCmd.Flags().StringVar(&flagUserEmail, "email", "", "Email of the user, defaults to <username>@localhost")
+	userCreateCmd.Flags().StringVar(&flagUserDisplayName, "display-name", "", "Name of the user shown in the UI")
+	userCreateCmd.Flags().BoolVar(&flagUserAdmin, "admin", false, "Grant the admin rights to the user")
+	userCreateCmd.Flags().BoolVar(&flagUserNoPassword, "no-password", false, "Create the user without a password, it cannot log in until one is set")
+
+	userDeleteCmd.Flags().BoolVarP(&flagUserYes, "yes", "y", false, "Do not ask for a confirmation")
+
+	userCmd.AddCommand(userCreateCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userSetPasswordCmd)
+	userCmd.AddCommand(userPromoteCmd)
+	userCmd.AddCommand(userDemoteCmd)
+	userCmd.AddCommand(userDeactivateCmd)
+	userCmd.AddCommand(userActivateCmd)
+	userCmd.AddCommand(userDeleteCmd)
+}
@@ -1,7 +1,12 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,26 +19,256 @@ type OIDCConfig struct {
 	Scopes       []string `yaml:"scopes"`
 }
 
+// AdminConfig configures the built-in admin, created on the first start.
+// Username defaults to `admin` and Email to `<username>@localhost`.
+// Disabled deactivates the built-in admin, once other admins exist, for
+// instance admins logging in with OIDC.
+type AdminConfig struct {
+	Username string `yaml:"username"`
+	Email    string `yaml:"email"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+// SessionCookieConfig lets the browsers keep their session in an
+// HttpOnly cookie, out of reach of JavaScript. The logins asking for it
+// with the `X-Session-Mode: cookie` header get their session in a cookie
+// rather than in the response, along with a CSRF token the requests
+// changing state must send back in the `X-CSRF-Token` header. Name
+// defaults to `session` and SameSite, one of `lax`, `strict` or `none`,
+// to `lax`. Insecure drops the Secure flag, for development over plain
+// http only.
+type SessionCookieConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Name     string `yaml:"name"`
+	Domain   string `yaml:"domain"`
+	SameSite string `yaml:"sameSite"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// SecurityConfig holds the secrets of the application. AdminPassword is
+// the password the built-in admin is created with, either in clear or
+// hashed with bcrypt, such as the output of the hashpass command. When
+// it is empty a random password is generated and printed once.
 type SecurityConfig struct {
 	SigninigKey   string                `yaml:"signingKey"`
 	AdminPassword string                `yaml:"adminPassword"`
+	Admin         AdminConfig           `yaml:"admin"`
 	OIDC          map[string]OIDCConfig `yaml:"oidc"`
+	SessionCookie SessionCookieConfig   `yaml:"sessionCookie"`
+}
+
+// ParseSigningKey decodes the PEM encoded EC private key the sessions
+// are signed with
+func (c SecurityConfig) ParseSigningKey() (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(c.SigninigKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid signing key: no PEM block found")
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// CORSConfig is the CORS policy of the API. Origins are either exact,
+// such as `https://app.example.com`, wildcard subdomains, such as
+// `https://*.example.com`, or `*`. Setting Preset to `debug` allows any
+// origin, which is also the default in debug mode when no origin is
+// configured. AllowCredentials requires explicit origins. MaxAge is in
+// seconds.
+type CORSConfig struct {
+	Preset           string   `yaml:"preset"`
+	AllowedOrigins   []string `yaml:"allowedOrigins"`
+	AllowedMethods   []string `yaml:"allowedMethods"`
+	AllowedHeaders   []string `yaml:"allowedHeaders"`
+	ExposedHeaders   []string `yaml:"exposedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials"`
+	MaxAge           int      `yaml:"maxAge"`
+}
+
+// SecurityHeadersConfig tunes the security headers sent with every
+// response. HSTSMaxAge is in seconds, it defaults to a year and a
+// negative value disables HSTS. FrameAncestors and ConnectSrc are CSP
+// sources added to the policy of the UI, by default it cannot be framed
+// and only talks to its own origin.
+type SecurityHeadersConfig struct {
+	Disabled              bool     `yaml:"disabled"`
+	HSTSMaxAge            int      `yaml:"hstsMaxAge"`
+	HSTSIncludeSubdomains bool     `yaml:"hstsIncludeSubdomains"`
+	ReferrerPolicy        string   `yaml:"referrerPolicy"`
+	FrameAncestors        []string `yaml:"frameAncestors"`
+	ConnectSrc            []string `yaml:"connectSrc"`
+}
+
+// RateLimitRule is a token bucket allowing Requests per Period, with
+// bursts of up to Burst requests (defaults to Requests). Key tells what
+// the buckets are keyed on: `ip`, `user` or `apikey`, the latter two
+// only on the groups authenticating their requests first, `user`,
+// `admin` and `config`, and falling back to the user then the ip.
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+	Key      string        `yaml:"key"`
 }
 
+// RateLimitConfig configures the rate limiter. Store is either `memory`,
+// the default, or `sql` to share the limits between instances. Groups
+// maps the route groups (`global`, `auth`, `oidc`, `user`, `admin`,
+// `config` and `misc`) to their rule, groups without a rule are not
+// limited.
+type RateLimitConfig struct {
+	Enabled bool                     `yaml:"enabled"`
+	Store   string                   `yaml:"store"`
+	Groups  map[string]RateLimitRule `yaml:"groups"`
+}
+
+// HTTPConfig configures the http server. MaxBodySize is the maximum
+// size in bytes of a request body, it defaults to 1MiB. LegacyAPISunset
+// is the date after which the unversioned `/api` routes will be removed,
+// it is advertised in their Sunset header. TrustedProxies are the
+// addresses or CIDRs of the reverse proxies whose `X-Forwarded-For` is
+// used as the client ip, none by default.
 type HTTPConfig struct {
-	Listen string `yaml:"listen"`
+	Listen          string                `yaml:"listen"`
+	TrustedProxies  []string              `yaml:"trustedProxies"`
+	MaxBodySize     int64                 `yaml:"maxBodySize"`
+	LegacyAPISunset time.Time             `yaml:"legacyApiSunset"`
+	CORS            CORSConfig            `yaml:"cors"`
+	SecurityHeaders SecurityHeadersConfig `yaml:"securityHeaders"`
+	RateLimit       RateLimitConfig       `yaml:"rateLimit"`
+}
+
+// SQLiteConfig tunes sqlite. JournalMode defaults to `WAL`, letting
+// reads go on during writes, and BusyTimeout, how long a write waits for
+// the database to be unlocked, to 5s. Synchronous is left to the sqlite
+// default unless set, `NORMAL` being safe in WAL mode.
+type SQLiteConfig struct {
+	JournalMode string        `yaml:"journalMode"`
+	BusyTimeout time.Duration `yaml:"busyTimeout"`
+	Synchronous string        `yaml:"synchronous"`
 }
 
+// StorageConfig configures the database. The pool settings left to zero
+// keep the defaults of database/sql. StatementTimeout aborts the
+// statements running for longer on postgres and the reads on mysql, it
+// is not supported by sqlite. The queries slower than SlowQueryThreshold
+// are logged as warnings. StartupTimeout is how long to retry connecting
+// to a database that is not ready yet, it defaults to 30s.
 type StorageConfig struct {
-	Driver string `yaml:"driver"`
-	URL    string `yaml:"url"`
+	Driver             string        `yaml:"driver"`
+	URL                string        `yaml:"url"`
+	MaxOpenConns       int           `yaml:"maxOpenConns"`
+	MaxIdleConns       int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime    time.Duration `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime    time.Duration `yaml:"connMaxIdleTime"`
+	StatementTimeout   time.Duration `yaml:"statementTimeout"`
+	SlowQueryThreshold time.Duration `yaml:"slowQueryThreshold"`
+	StartupTimeout     time.Duration `yaml:"startupTimeout"`
+	SQLite             SQLiteConfig  `yaml:"sqlite"`
+}
+
+// LogConfig configures the application logger. Level is one of `debug`,
+// `info`, `warn` or `error` and Format is either `text` or `json`.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig configures the OpenTelemetry exporter. Exporter can be
+// one of `otlp-grpc`, `otlp-http` or `stdout`.
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter"`
+	Endpoint    string            `yaml:"endpoint"`
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	ServiceName string            `yaml:"serviceName"`
+	SampleRatio *float64          `yaml:"sampleRatio"`
+}
+
+// HealthConfig configures the readiness checks. Timeout bounds every
+// check, it defaults to 5s. CheckOIDC enables the discovery of every
+// active OIDC provider, which is reported but does not make the instance
+// unready when failing. Its result is kept for OIDCInterval, 1m by
+// default, rather than hitting the providers on every probe.
+type HealthConfig struct {
+	Timeout      time.Duration `yaml:"timeout"`
+	CheckOIDC    bool          `yaml:"checkOIDC"`
+	OIDCInterval time.Duration `yaml:"oidcInterval"`
+}
+
+// BrandingConfig customises the look of the UI
+type BrandingConfig struct {
+	Title   string `yaml:"title"`
+	LogoURL string `yaml:"logoUrl"`
+}
+
+// UIDevConfig serves the UI from outside of the binary while working on
+// it. Dir serves a build from disk, for instance the output of `vite
+// build --watch`, and ProxyURL forwards everything but the API to the
+// Vite dev server, hot reloading included. ProxyURL wins when both are
+// set.
+type UIDevConfig struct {
+	Dir      string `yaml:"dir"`
+	ProxyURL string `yaml:"proxyUrl"`
+}
+
+// UIConfig is handed to the UI at runtime through `/config.json`, so
+// that the same build can be served from any environment. APIBaseURL
+// defaults to the origin the UI is served from. DisablePasswordLogin
+// hides the login form and rejects password logins on the API.
+type UIConfig struct {
+	APIBaseURL           string         `yaml:"apiBaseUrl"`
+	DisablePasswordLogin bool           `yaml:"disablePasswordLogin"`
+	Branding             BrandingConfig `yaml:"branding"`
+	Dev                  UIDevConfig    `yaml:"dev"`
+}
+
+// SchedulerJobConfig overrides the interval of a maintenance job, or
+// disables it
+type SchedulerJobConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Disabled bool          `yaml:"disabled"`
+}
+
+// SchedulerConfig configures the maintenance jobs. Tick is how often
+// the instances compete for running them and check whether they are
+// due, it defaults to 30s. Jobs maps the jobs (`sessionCleanup`,
+// `apiKeyCleanup` and `staleOidcUsers`) to their settings. The OIDC
+// users that did not log in for StaleOIDCUsersAfter are deactivated,
+// the job being disabled when it is zero.
+type SchedulerConfig struct {
+	Disabled            bool                          `yaml:"disabled"`
+	Tick                time.Duration                 `yaml:"tick"`
+	StaleOIDCUsersAfter time.Duration                 `yaml:"staleOidcUsersAfter"`
+	Jobs                map[string]SchedulerJobConfig `yaml:"jobs"`
+}
+
+// SessionCacheConfig caches the sessions and their users in memory, so
+// that authenticating a request does not hit the database every time.
+// Entries are kept for TTL, 30s by default, and at most Size sessions,
+// 10000 by default. Notifier tells the other instances to drop the
+// sessions revoked and the users changed: `sql` polls the database
+// every PollInterval, 1s by default, and `none` only suits a single
+// instance. It defaults to `sql` when the services use a database.
+type SessionCacheConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Size         int           `yaml:"size"`
+	TTL          time.Duration `yaml:"ttl"`
+	Notifier     string        `yaml:"notifier"`
+	PollInterval time.Duration `yaml:"pollInterval"`
 }
 
 type Config struct {
-	Debug    bool           `yaml:"debug"`
-	Storage  StorageConfig  `yaml:"storage"`
-	HTTP     HTTPConfig     `yaml:"http"`
-	Security SecurityConfig `yaml:"security"`
+	Debug        bool               `yaml:"debug"`
+	Storage      StorageConfig      `yaml:"storage"`
+	HTTP         HTTPConfig         `yaml:"http"`
+	Security     SecurityConfig     `yaml:"security"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Log          LogConfig          `yaml:"log"`
+	Health       HealthConfig       `yaml:"health"`
+	UI           UIConfig           `yaml:"ui"`
+	Scheduler    SchedulerConfig    `yaml:"scheduler"`
+	SessionCache SessionCacheConfig `yaml:"sessionCache"`
 }
 
 func LoadFromFile(pth string) (*Config, error) {
@@ -0,0 +1,94 @@
+// Package deviceauth implements the state of the device authorization
+// grant (RFC 8628), which lets a client without a browser, such as the
+// command line client, get a session approved by a user logged into the
+// UI.
+package deviceauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// userCodeCharset avoids vowels, so that codes cannot spell words, and
+// characters that are easily mistaken for one another.
+const (
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+var (
+	ErrNotFound = fmt.Errorf("unknown or expired device authorization")
+	ErrPending  = fmt.Errorf("authorization pending")
+	ErrSlowDown = fmt.Errorf("polling too fast")
+)
+
+// Authorization is a pending device authorization. DeviceCode is only
+// known to the device, which polls with it, UserCode is what the user
+// types in the UI to approve it.
+type Authorization struct {
+	DeviceCode string
+	UserCode   string
+	Expires    time.Time
+	Interval   time.Duration
+}
+
+// Store keeps the pending authorizations. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Create starts a new authorization, valid for ttl, that the device
+	// may poll every interval.
+	Create(ctx context.Context, ttl time.Duration, interval time.Duration) (*Authorization, error)
+	// Approve grants the authorization to the user
+	Approve(ctx context.Context, userCode string, userId string) error
+	// Poll returns the user that approved the authorization, which can
+	// then no longer be used, or ErrPending while it was not approved.
+	Poll(ctx context.Context, deviceCode string) (string, error)
+}
+
+// NormalizeUserCode uppercases the code and removes the separators, so
+// that `bcdf-ghjk` and `BCDFGHJK` match.
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// FormatUserCode splits the code in two halves for readability
+func FormatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+func newDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newUserCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(userCodeCharset)))
+	for range userCodeLength {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(userCodeCharset[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,121 @@
+package deviceauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryAuthorization struct {
+	Authorization
+	userId   string
+	lastPoll time.Time
+}
+
+// MemoryStore keeps the authorizations in the memory of the process, the
+// device and the user therefore have to reach the same instance.
+type MemoryStore struct {
+	lock       sync.Mutex
+	byDevice   map[string]*memoryAuthorization
+	byUserCode map[string]*memoryAuthorization
+
+	// Now returns the current time, time.Now unless testing
+	Now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byDevice:   make(map[string]*memoryAuthorization),
+		byUserCode: make(map[string]*memoryAuthorization),
+		Now:        time.Now,
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, ttl time.Duration, interval time.Duration) (*Authorization, error) {
+	deviceCode, err := newDeviceCode()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.Now()
+	s.cleanup(now)
+
+	var userCode string
+	for {
+		userCode, err = newUserCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, taken := s.byUserCode[userCode]; !taken {
+			break
+		}
+	}
+
+	auth := &memoryAuthorization{
+		Authorization: Authorization{
+			DeviceCode: deviceCode,
+			UserCode:   userCode,
+			Expires:    now.Add(ttl),
+			Interval:   interval,
+		},
+	}
+
+	s.byDevice[deviceCode] = auth
+	s.byUserCode[userCode] = auth
+
+	res := auth.Authorization
+	return &res, nil
+}
+
+func (s *MemoryStore) Approve(ctx context.Context, userCode string, userId string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	auth, ok := s.byUserCode[NormalizeUserCode(userCode)]
+	if !ok || s.Now().After(auth.Expires) || auth.userId != "" {
+		return ErrNotFound
+	}
+
+	auth.userId = userId
+	return nil
+}
+
+func (s *MemoryStore) Poll(ctx context.Context, deviceCode string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.Now()
+	auth, ok := s.byDevice[deviceCode]
+	if !ok || now.After(auth.Expires) {
+		return "", ErrNotFound
+	}
+
+	if auth.userId == "" {
+		last := auth.lastPoll
+		auth.lastPoll = now
+		if now.Sub(last) < auth.Interval {
+			return "", ErrSlowDown
+		}
+		return "", ErrPending
+	}
+
+	s.delete(auth)
+	return auth.userId, nil
+}
+
+func (s *MemoryStore) delete(auth *memoryAuthorization) {
+	delete(s.byDevice, auth.DeviceCode)
+	delete(s.byUserCode, auth.UserCode)
+}
+
+// cleanup drops the expired authorizations, must be called with the lock held
+func (s *MemoryStore) cleanup(now time.Time) {
+	for _, auth := range s.byDevice {
+		if now.After(auth.Expires) {
+			s.delete(auth)
+		}
+	}
+}
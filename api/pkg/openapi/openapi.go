@@ -0,0 +1,346 @@
+// Package openapi converts the Swagger 2.0 document generated by swag into
+// an OpenAPI 3.1 document. It covers the subset of Swagger 2.0 swag
+// produces: path, query and header parameters, json bodies, api key
+// security schemes and json schema definitions.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	Version = "3.1.0"
+
+	swaggerRefPrefix = "#/definitions/"
+	schemasRefPrefix = "#/components/schemas/"
+
+	defaultMediaType = "application/json"
+)
+
+// Options tunes the conversion
+type Options struct {
+	// ServerURL is the url the API is served from, it defaults to the
+	// host and base path of the swagger document.
+	ServerURL string
+	// MediaTypes overrides the media type of the responses using the
+	// given definition, such as `application/problem+json` for errors.
+	MediaTypes map[string]string
+}
+
+// swagger is the part of a Swagger 2.0 document that is converted, the
+// schemas are kept as is and only have their references rewritten.
+type swagger struct {
+	Swagger             string                          `json:"swagger"`
+	Info                map[string]any                  `json:"info"`
+	Host                string                          `json:"host"`
+	BasePath            string                          `json:"basePath"`
+	Schemes             []string                        `json:"schemes"`
+	Consumes            []string                        `json:"consumes"`
+	Produces            []string                        `json:"produces"`
+	Paths               map[string]map[string]operation `json:"paths"`
+	Definitions         map[string]any                  `json:"definitions"`
+	SecurityDefinitions map[string]map[string]any       `json:"securityDefinitions"`
+	Security            []map[string][]string           `json:"security,omitempty"`
+	Tags                []map[string]any                `json:"tags,omitempty"`
+	ExternalDocs        map[string]any                  `json:"externalDocs,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Consumes    []string              `json:"consumes,omitempty"`
+	Produces    []string              `json:"produces,omitempty"`
+	Parameters  []map[string]any      `json:"parameters,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type response struct {
+	Description string                    `json:"description"`
+	Schema      any                       `json:"schema,omitempty"`
+	Headers     map[string]map[string]any `json:"headers,omitempty"`
+}
+
+// parameterFields are the fields of a Swagger 2.0 parameter that are
+// not part of its schema in OpenAPI 3
+var parameterFields = map[string]bool{
+	"name":             true,
+	"in":               true,
+	"description":      true,
+	"required":         true,
+	"allowEmptyValue":  true,
+	"collectionFormat": true,
+}
+
+// Convert turns a Swagger 2.0 document into an OpenAPI 3.1 one
+func Convert(doc []byte, opts Options) ([]byte, error) {
+	var in swagger
+	if err := json.Unmarshal(doc, &in); err != nil {
+		return nil, fmt.Errorf("invalid swagger document: %w", err)
+	}
+	if in.Swagger != "2.0" {
+		return nil, fmt.Errorf("unsupported swagger version: %q", in.Swagger)
+	}
+
+	serverURL := opts.ServerURL
+	if serverURL == "" {
+		serverURL = in.BasePath
+		if in.Host != "" {
+			scheme := "https"
+			if len(in.Schemes) != 0 {
+				scheme = in.Schemes[0]
+			}
+			serverURL = scheme + "://" + in.Host + in.BasePath
+		}
+	}
+	if serverURL == "" {
+		serverURL = "/"
+	}
+
+	out := map[string]any{
+		"openapi": Version,
+		"info":    in.Info,
+		"servers": []map[string]any{{"url": serverURL}},
+	}
+
+	paths := make(map[string]any, len(in.Paths))
+	for path, item := range in.Paths {
+		ops := make(map[string]any, len(item))
+		for method, op := range item {
+			converted, err := convertOperation(in, op, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			ops[method] = converted
+		}
+		paths[path] = ops
+	}
+	out["paths"] = paths
+
+	components := map[string]any{}
+	if len(in.Definitions) != 0 {
+		schemas := make(map[string]any, len(in.Definitions))
+		for name, schema := range in.Definitions {
+			schemas[name] = convertSchema(schema)
+		}
+		components["schemas"] = schemas
+	}
+	if len(in.SecurityDefinitions) != 0 {
+		schemes := make(map[string]any, len(in.SecurityDefinitions))
+		for name, def := range in.SecurityDefinitions {
+			scheme, err := convertSecurityScheme(def)
+			if err != nil {
+				return nil, fmt.Errorf("security scheme %s: %w", name, err)
+			}
+			schemes[name] = scheme
+		}
+		components["securitySchemes"] = schemes
+	}
+	if len(components) != 0 {
+		out["components"] = components
+	}
+
+	if len(in.Security) != 0 {
+		out["security"] = in.Security
+	}
+	if len(in.Tags) != 0 {
+		out["tags"] = in.Tags
+	}
+	if in.ExternalDocs != nil {
+		out["externalDocs"] = in.ExternalDocs
+	}
+
+	return json.Marshal(out)
+}
+
+func convertOperation(doc swagger, op operation, opts Options) (map[string]any, error) {
+	out := map[string]any{}
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if op.OperationID != "" {
+		out["operationId"] = op.OperationID
+	}
+	if len(op.Tags) != 0 {
+		out["tags"] = op.Tags
+	}
+	if op.Security != nil {
+		out["security"] = op.Security
+	}
+	if op.Deprecated {
+		out["deprecated"] = true
+	}
+
+	consumes := firstNonEmpty(op.Consumes, doc.Consumes)
+	produces := firstNonEmpty(op.Produces, doc.Produces)
+
+	var params []map[string]any
+	for _, param := range op.Parameters {
+		switch param["in"] {
+		case "body":
+			body := map[string]any{
+				"content": mediaTypes(consumes, convertSchema(param["schema"])),
+			}
+			if desc, ok := param["description"]; ok {
+				body["description"] = desc
+			}
+			if required, ok := param["required"]; ok {
+				body["required"] = required
+			}
+			out["requestBody"] = body
+		case "formData":
+			return nil, fmt.Errorf("form parameters are not supported")
+		default:
+			params = append(params, convertParameter(param))
+		}
+	}
+	if len(params) != 0 {
+		out["parameters"] = params
+	}
+
+	responses := make(map[string]any, len(op.Responses))
+	for code, resp := range op.Responses {
+		converted := map[string]any{"description": resp.Description}
+		if resp.Schema != nil {
+			types := produces
+			if mt, ok := opts.MediaTypes[refName(resp.Schema)]; ok {
+				types = []string{mt}
+			}
+			converted["content"] = mediaTypes(types, convertSchema(resp.Schema))
+		}
+		if len(resp.Headers) != 0 {
+			headers := make(map[string]any, len(resp.Headers))
+			for name, header := range resp.Headers {
+				headers[name] = convertParameter(header)
+			}
+			converted["headers"] = headers
+		}
+		responses[code] = converted
+	}
+	out["responses"] = responses
+
+	return out, nil
+}
+
+// convertParameter moves the type related fields of a Swagger 2.0
+// parameter or header into its schema
+func convertParameter(param map[string]any) map[string]any {
+	out := map[string]any{}
+	schema := map[string]any{}
+
+	for key, value := range param {
+		switch {
+		case key == "collectionFormat":
+			if value == "multi" {
+				out["explode"] = true
+			}
+		case parameterFields[key]:
+			out[key] = value
+		default:
+			schema[key] = value
+		}
+	}
+
+	if len(schema) != 0 {
+		out["schema"] = convertSchema(schema)
+	}
+
+	return out
+}
+
+func convertSecurityScheme(def map[string]any) (map[string]any, error) {
+	switch def["type"] {
+	case "apiKey":
+		out := map[string]any{
+			"type": "apiKey",
+			"name": def["name"],
+			"in":   def["in"],
+		}
+		if desc, ok := def["description"]; ok {
+			out["description"] = desc
+		}
+		return out, nil
+	case "basic":
+		return map[string]any{"type": "http", "scheme": "basic"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported security scheme type: %v", def["type"])
+	}
+}
+
+// convertSchema rewrites the references to the definitions and turns
+// the `x-nullable` extension into a JSON schema type union.
+func convertSchema(schema any) any {
+	switch s := schema.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(s))
+		for key, value := range s {
+			switch key {
+			case "$ref":
+				if ref, ok := value.(string); ok && strings.HasPrefix(ref, swaggerRefPrefix) {
+					value = schemasRefPrefix + strings.TrimPrefix(ref, swaggerRefPrefix)
+				}
+				out[key] = value
+			case "x-nullable":
+				// handled with the type below
+			default:
+				out[key] = convertSchema(value)
+			}
+		}
+		if nullable, _ := s["x-nullable"].(bool); nullable {
+			if t, ok := out["type"].(string); ok {
+				out["type"] = []string{t, "null"}
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(s))
+		for i, value := range s {
+			out[i] = convertSchema(value)
+		}
+		return out
+	default:
+		return schema
+	}
+}
+
+// refName returns the name of the definition a schema references, if any
+func refName(schema any) string {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	ref, _ := s["$ref"].(string)
+	return strings.TrimPrefix(ref, swaggerRefPrefix)
+}
+
+func mediaTypes(types []string, schema any) map[string]any {
+	if len(types) == 0 {
+		types = []string{defaultMediaType}
+	}
+
+	content := make(map[string]any, len(types))
+	for _, t := range types {
+		content[t] = map[string]any{"schema": schema}
+	}
+
+	return content
+}
+
+func firstNonEmpty(lists ...[]string) []string {
+	for _, l := range lists {
+		if len(l) != 0 {
+			return l
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memoryCleanupInterval = time.Minute
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will be full again, past that point it
+	// can be forgotten since a new bucket would be identical.
+	full time.Time
+}
+
+// MemoryStore keeps the buckets in the memory of the process, limits
+// are therefore enforced per instance.
+type MemoryStore struct {
+	// Now returns the current time, time.Now unless testing
+	Now func() time.Time
+
+	lock        sync.Mutex
+	buckets     map[string]*memoryBucket
+	lastCleanup time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Now:         time.Now,
+		buckets:     make(map[string]*memoryBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.Now()
+	if now.Sub(s.lastCleanup) > memoryCleanupInterval {
+		s.cleanup(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: limit.capacity(), updated: now}
+		s.buckets[key] = b
+	}
+
+	tokens, res := take(b.tokens, b.updated, now, limit)
+	b.tokens = tokens
+	b.updated = now
+	b.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// cleanup drops the buckets that are full, must be called with the lock held
+func (s *MemoryStore) cleanup(now time.Time) {
+	for key, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastCleanup = now
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it holds at most Burst tokens and
+// refills by Requests tokens every Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time left until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time left until a token is available, it is
+	// zero when the request is allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of the buckets. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return float64(l.Requests)
+}
+
+// rate is the number of tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// take refills a bucket holding `tokens` tokens last updated at `last`,
+// then tries to take one token from it. It returns the new amount of
+// tokens in the bucket along with the result.
+func take(tokens float64, last time.Time, now time.Time, limit Limit) (float64, Result) {
+	capacity := limit.capacity()
+	rate := limit.rate()
+
+	elapsed := now.Sub(last).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: int(capacity)}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = secondsToDuration((capacity - tokens) / rate)
+
+	return tokens, res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+func TestTake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 2 tokens per second, up to 4
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 4}
+
+	cases := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    Result
+		left    float64
+	}{
+		{"Full", 4, 0, Result{Allowed: true, Limit: 4, Remaining: 3, Reset: 500 * time.Millisecond}, 3},
+		{"LastToken", 1, 0, Result{Allowed: true, Limit: 4, Remaining: 0, Reset: 2 * time.Second}, 0},
+		{"Empty", 0, 0, Result{Limit: 4, Remaining: 0, Reset: 2 * time.Second, RetryAfter: 500 * time.Millisecond}, 0},
+		{"HalfToken", 0.5, 0, Result{Limit: 4, Remaining: 0, Reset: 1750 * time.Millisecond, RetryAfter: 250 * time.Millisecond}, 0.5},
+		{"Refilled", 0, 750 * time.Millisecond, Result{Allowed: true, Limit: 4, Remaining: 0, Reset: 1750 * time.Millisecond}, 0.5},
+		{"RefillCapped", 1, time.Hour, Result{Allowed: true, Limit: 4, Remaining: 3, Reset: 500 * time.Millisecond}, 3},
+		{"ClockBackwards", 2, -time.Second, Result{Allowed: true, Limit: 4, Remaining: 1, Reset: 1500 * time.Millisecond}, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			left, res := take(c.tokens, start, start.Add(c.elapsed), limit)
+			if res != c.want {
+				t.Fatalf("expected %+v, got %+v", c.want, res)
+			}
+			if left != c.left {
+				t.Fatalf("expected %v tokens left, got %v", c.left, left)
+			}
+		})
+	}
+
+	// without a burst the bucket holds a period worth of requests
+	if _, res := take(Limit{Requests: 10, Period: time.Minute}.capacity(), start, start, Limit{Requests: 10, Period: time.Minute}); res.Limit != 10 || res.Remaining != 9 {
+		t.Fatalf("unexpected result without a burst: %+v", res)
+	}
+}
+
+// clock is a clock only moving when told to
+type clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name string
+		new  func(t *testing.T, now func() time.Time) Store
+	}{
+		{"memory", func(t *testing.T, now func() time.Time) Store {
+			s := NewMemoryStore()
+			s.Now = now
+			return s
+		}},
+		{"sqlite", func(t *testing.T, now func() time.Time) Store {
+			s, err := NewSQLStore(storetest.SQLite(t))
+			if err != nil {
+				t.Fatalf("could not create the store: %s", err)
+			}
+			s.Now = now
+			return s
+		}},
+	}
+
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+			s := st.new(t, c.Now)
+			limit := Limit{Requests: 1, Period: time.Minute, Burst: 3}
+
+			take := func(key string) Result {
+				t.Helper()
+				res, err := s.Take(t.Context(), key, limit)
+				if err != nil {
+					t.Fatalf("could not take a token: %s", err)
+				}
+				return res
+			}
+
+			for i := 2; i >= 0; i-- {
+				if res := take("a"); !res.Allowed || res.Remaining != i {
+					t.Fatalf("expected %d remaining tokens, got %+v", i, res)
+				}
+			}
+			if res := take("a"); res.Allowed || res.RetryAfter != time.Minute {
+				t.Fatalf("expected the bucket to be empty, got %+v", res)
+			}
+
+			// the buckets are independent
+			if res := take("b"); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("expected a new bucket, got %+v", res)
+			}
+
+			c.Advance(time.Minute)
+			if res := take("a"); !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("expected a token to be refilled, got %+v", res)
+			}
+
+			// an idle bucket fills up again, up to the burst
+			c.Advance(time.Hour)
+			if res := take("a"); !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("expected a full bucket, got %+v", res)
+			}
+		})
+	}
+}
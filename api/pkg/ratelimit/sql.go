@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const sqlCleanupInterval = time.Minute
+
+type bucket struct {
+	Key     string    `gorm:"primaryKey;column:bucket_key;size:255"`
+	Tokens  float64   `gorm:"column:tokens;not null"`
+	Updated time.Time `gorm:"column:updated;not null"`
+	Full    time.Time `gorm:"column:full_at;not null;index"`
+}
+
+func (o *bucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// SQLStore keeps the buckets in the database so that every instance
+// shares the same limits. Buckets are locked for the duration of the
+// update.
+type SQLStore struct {
+	DB *gorm.DB
+	// Now returns the current time, time.Now unless testing
+	Now func() time.Time
+
+	lock        sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewSQLStore(db *gorm.DB) (*SQLStore, error) {
+	if err := db.AutoMigrate(bucket{}); err != nil {
+		return nil, err
+	}
+
+	return &SQLStore{
+		DB:          db,
+		Now:         time.Now,
+		lastCleanup: time.Now(),
+	}, nil
+}
+
+func (s *SQLStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var res Result
+	now := s.Now()
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket{
+			Key:     key,
+			Tokens:  limit.capacity(),
+			Updated: now,
+			Full:    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var b bucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&bucket{Key: key}).First(&b).Error; err != nil {
+			return err
+		}
+
+		b.Tokens, res = take(b.Tokens, b.Updated, now, limit)
+		b.Updated = now
+		b.Full = now.Add(res.Reset)
+
+		return tx.Save(&b).Error
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	s.lock.Lock()
+	cleanup := now.Sub(s.lastCleanup) > sqlCleanupInterval
+	if cleanup {
+		s.lastCleanup = now
+	}
+	s.lock.Unlock()
+
+	// full buckets are identical to new ones, they can be dropped
+	if cleanup {
+		s.DB.WithContext(ctx).Where("full_at < ?", now).Delete(&bucket{})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+// MemoryStore keeps the lease and the runs in the memory of the process,
+// every instance is therefore the leader of its own jobs.
+type MemoryStore struct {
+	lock   sync.Mutex
+	leases map[string]memoryLease
+	runs   map[string]Run
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		leases: make(map[string]memoryLease),
+		runs:   make(map[string]Run),
+	}
+}
+
+func (s *MemoryStore) Acquire(ctx context.Context, name string, holder string, now time.Time, expires time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if l, ok := s.leases[name]; ok && l.holder != holder && now.Before(l.expires) {
+		return false, nil
+	}
+
+	s.leases[name] = memoryLease{holder: holder, expires: expires}
+
+	return true, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, name string, holder string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if l, ok := s.leases[name]; ok && l.holder == holder {
+		delete(s.leases, name)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) SaveRun(ctx context.Context, run *Run) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.runs[run.Job] = *run
+
+	return nil
+}
+
+func (s *MemoryStore) LastRuns(ctx context.Context) ([]Run, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	runs := make([]Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Job < runs[j].Job })
+
+	return runs, nil
+}
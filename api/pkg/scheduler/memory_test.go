@@ -0,0 +1,13 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/scheduler"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) scheduler.Store {
+		return scheduler.NewMemoryStore()
+	})
+}
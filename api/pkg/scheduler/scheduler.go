@@ -0,0 +1,337 @@
+// Package scheduler runs periodic maintenance jobs. Every instance runs
+// a scheduler, but only the one holding the lease of the Store runs the
+// jobs, the others take over when it stops renewing it. The last run of
+// every job is kept in the Store too, so that the interval of a job is
+// honoured across restarts and instances.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
+)
+
+const (
+	// DefaultTick is how often the lease is renewed and the jobs are
+	// checked
+	DefaultTick = 30 * time.Second
+
+	// leaseName is the name of the lease of the scheduler in the Store
+	leaseName = "scheduler"
+	// leaseTicks is how many ticks a lease lasts, so that a leader can
+	// miss a couple of renewals before being replaced
+	leaseTicks = 3
+)
+
+// Job is a periodic task. Run returns a short summary of what it did,
+// such as how many rows were deleted. Jobs run one after the other and
+// should not take longer than a tick.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Run is the outcome of a run of a job
+type Run struct {
+	Job      string
+	Holder   string
+	Started  time.Time
+	Duration time.Duration
+	Result   string
+	Error    string
+}
+
+// Store keeps the lease of the leader and the last run of every job.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Acquire takes the lease called name for holder until expires, or
+	// renews it if holder already has it. It returns false when another
+	// holder has a lease that did not expire at now.
+	Acquire(ctx context.Context, name string, holder string, now time.Time, expires time.Time) (bool, error)
+	// Release gives the lease up, if holder has it
+	Release(ctx context.Context, name string, holder string) error
+	// SaveRun replaces the last run of the job
+	SaveRun(ctx context.Context, run *Run) error
+	// LastRuns returns the last run of every job that ran
+	LastRuns(ctx context.Context) ([]Run, error)
+}
+
+// JobStatus is the state of a job as seen by a scheduler. Next is zero
+// when the job never ran, it runs as soon as a leader is elected.
+type JobStatus struct {
+	Name     string
+	Interval time.Duration
+	Running  bool
+	Next     time.Time
+	LastRun  *Run
+}
+
+// Status is the state of a scheduler, Leader telling whether it is the
+// one running the jobs
+type Status struct {
+	Instance string
+	Leader   bool
+	Jobs     []JobStatus
+}
+
+// Scheduler runs the jobs added to it. Now defaults to time.Now and
+// Instance, the name of the holder of the lease, to the hostname
+// followed by a random suffix.
+type Scheduler struct {
+	Store    Store
+	Logger   *slog.Logger
+	Now      func() time.Time
+	Instance string
+	Tick     time.Duration
+
+	lock    sync.Mutex
+	jobs    []Job
+	leader  bool
+	running string
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func New(store Store) *Scheduler {
+	return &Scheduler{
+		Store:    store,
+		Logger:   slog.Default(),
+		Now:      time.Now,
+		Instance: instanceName(),
+		Tick:     DefaultTick,
+	}
+}
+
+// instanceName names the instance after the host, with a random suffix
+// telling apart the instances running on the same one
+func instanceName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return hostname + "-" + hex.EncodeToString(b)
+}
+
+// Add registers a job, it must be called before Start
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("a job needs a name and a function to run")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("invalid interval for job %s: %s", job.Name, job.Interval)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("job %s already exists", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, job)
+	sort.Slice(s.jobs, func(i, j int) bool { return s.jobs[i].Name < s.jobs[j].Name })
+
+	return nil
+}
+
+// Start runs the scheduler in the background until Stop is called or
+// ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+func (s *Scheduler) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.Tick)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Step(ctx); err != nil && ctx.Err() == nil {
+			s.Logger.Error("scheduler step failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop waits for the running job, if any, and gives up the lease so
+// that another instance can take over right away
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.lock.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.lock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.lock.Lock()
+	s.leader = false
+	s.lock.Unlock()
+
+	return s.Store.Release(ctx, leaseName, s.Instance)
+}
+
+// Step takes or renews the lease then, if it got it, runs the jobs that
+// are due. It is what the scheduler does on every tick.
+func (s *Scheduler) Step(ctx context.Context) error {
+	now := s.Now()
+	leader, err := s.Store.Acquire(ctx, leaseName, s.Instance, now, now.Add(leaseTicks*s.Tick))
+
+	s.lock.Lock()
+	if err != nil {
+		leader = false
+	}
+	if leader != s.leader {
+		s.Logger.Info("scheduler leadership changed", "instance", s.Instance, "leader", leader)
+	}
+	s.leader = leader
+	jobs := append([]Job(nil), s.jobs...)
+	s.lock.Unlock()
+
+	if err != nil || !leader {
+		return err
+	}
+
+	last, err := s.lastRuns(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if run, ok := last[job.Name]; ok && s.Now().Before(run.Started.Add(job.Interval)) {
+			continue
+		}
+
+		if err := s.run(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs a job and saves its outcome, the error returned is the one of
+// the Store, the one of the job being part of the run
+func (s *Scheduler) run(ctx context.Context, job Job) error {
+	s.lock.Lock()
+	s.running = job.Name
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		s.running = ""
+		s.lock.Unlock()
+	}()
+
+	logger := s.Logger.With("job", job.Name)
+	run := &Run{
+		Job:     job.Name,
+		Holder:  s.Instance,
+		Started: s.Now(),
+	}
+
+	result, err := job.Run(logging.WithLogger(ctx, logger))
+	run.Duration = s.Now().Sub(run.Started)
+	run.Result = result
+	if err != nil {
+		run.Error = err.Error()
+		logger.Error("job failed", "error", err, "duration", run.Duration)
+	} else {
+		logger.Info("job done", "result", result, "duration", run.Duration)
+	}
+
+	return s.Store.SaveRun(ctx, run)
+}
+
+func (s *Scheduler) lastRuns(ctx context.Context) (map[string]Run, error) {
+	runs, err := s.Store.LastRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	last := make(map[string]Run, len(runs))
+	for _, run := range runs {
+		last[run.Job] = run
+	}
+
+	return last, nil
+}
+
+// Status returns the state of the jobs, their last runs being shared by
+// every instance
+func (s *Scheduler) Status(ctx context.Context) (*Status, error) {
+	last, err := s.lastRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := &Status{
+		Instance: s.Instance,
+		Leader:   s.leader,
+		Jobs:     make([]JobStatus, 0, len(s.jobs)),
+	}
+
+	for _, job := range s.jobs {
+		js := JobStatus{
+			Name:     job.Name,
+			Interval: job.Interval,
+			Running:  s.running == job.Name,
+		}
+
+		if run, ok := last[job.Name]; ok {
+			js.LastRun = &run
+			js.Next = run.Started.Add(job.Interval)
+		}
+
+		status.Jobs = append(status.Jobs, js)
+	}
+
+	return status, nil
+}
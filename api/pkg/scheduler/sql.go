@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type lease struct {
+	Name    string    `gorm:"primaryKey;column:name;size:255"`
+	Holder  string    `gorm:"column:holder;size:255;not null"`
+	Expires time.Time `gorm:"column:expires;not null"`
+}
+
+func (o *lease) TableName() string {
+	return "scheduler_leases"
+}
+
+type run struct {
+	Job      string        `gorm:"primaryKey;column:job;size:255"`
+	Holder   string        `gorm:"column:holder;size:255;not null"`
+	Started  time.Time     `gorm:"column:started;not null"`
+	Duration time.Duration `gorm:"column:duration;not null"`
+	Result   string        `gorm:"column:result"`
+	Error    string        `gorm:"column:error"`
+}
+
+func (o *run) TableName() string {
+	return "scheduler_runs"
+}
+
+// SQLStore keeps the lease and the runs in the database, so that a
+// single instance runs the jobs at a time.
+type SQLStore struct {
+	DB *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) (*SQLStore, error) {
+	if err := db.AutoMigrate(lease{}, run{}); err != nil {
+		return nil, err
+	}
+
+	return &SQLStore{DB: db}, nil
+}
+
+func (s *SQLStore) Acquire(ctx context.Context, name string, holder string, now time.Time, expires time.Time) (bool, error) {
+	db := s.DB.WithContext(ctx)
+
+	// take the lease over if it expired, or renew it
+	if err := db.Model(&lease{}).
+		Where("name = ? AND (holder = ? OR expires <= ?)", name, holder, now).
+		Updates(map[string]any{"holder": holder, "expires": expires}).Error; err != nil {
+		return false, err
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease{
+		Name:    name,
+		Holder:  holder,
+		Expires: expires,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	// the affected rows cannot be relied on, mysql does not count the
+	// rows left unchanged, so the holder is read back
+	var l lease
+	if err := db.Where(&lease{Name: name}).First(&l).Error; err != nil {
+		return false, err
+	}
+
+	return l.Holder == holder, nil
+}
+
+func (s *SQLStore) Release(ctx context.Context, name string, holder string) error {
+	return s.DB.WithContext(ctx).Where("name = ? AND holder = ?", name, holder).Delete(&lease{}).Error
+}
+
+func (s *SQLStore) SaveRun(ctx context.Context, r *Run) error {
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&run{
+		Job:      r.Job,
+		Holder:   r.Holder,
+		Started:  r.Started,
+		Duration: r.Duration,
+		Result:   r.Result,
+		Error:    r.Error,
+	}).Error
+}
+
+func (s *SQLStore) LastRuns(ctx context.Context) ([]Run, error) {
+	var rows []run
+	if err := s.DB.WithContext(ctx).Order("job").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	runs := make([]Run, 0, len(rows))
+	for _, r := range rows {
+		runs = append(runs, Run{
+			Job:      r.Job,
+			Holder:   r.Holder,
+			Started:  r.Started,
+			Duration: r.Duration,
+			Result:   r.Result,
+			Error:    r.Error,
+		})
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,21 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/scheduler"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+func TestSQLStore(t *testing.T) {
+	testStore(t, func(t *testing.T) scheduler.Store {
+		t.Helper()
+
+		s, err := scheduler.NewSQLStore(storetest.SQLite(t))
+		if err != nil {
+			t.Fatalf("could not create the store: %s", err)
+		}
+
+		return s
+	})
+}
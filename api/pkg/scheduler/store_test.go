@@ -0,0 +1,307 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/scheduler"
+)
+
+// testStore runs the cases every Store implementation must pass against
+// the ones built by newStore, a new store is built for every case.
+func testStore(t *testing.T, newStore func(t *testing.T) scheduler.Store) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s scheduler.Store)
+	}{
+		{"Lease", testLease},
+		{"LeaseRelease", testLeaseRelease},
+		{"Runs", testRuns},
+		{"Leader", testLeader},
+		{"Failover", testFailover},
+		{"FailedJob", testFailedJob},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newStore(t))
+		})
+	}
+}
+
+// clock only moves when told to
+type clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Now().Truncate(time.Second)}
+}
+
+func (c *clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func acquire(t *testing.T, s scheduler.Store, holder string, now time.Time, expected bool) {
+	t.Helper()
+
+	ok, err := s.Acquire(context.Background(), "lease", holder, now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("%s: could not acquire the lease: %s", holder, err)
+	}
+	if ok != expected {
+		t.Fatalf("%s: expected the lease to be acquired: %v, got %v", holder, expected, ok)
+	}
+}
+
+func testLease(t *testing.T, s scheduler.Store) {
+	now := time.Now().Truncate(time.Second)
+
+	acquire(t, s, "a", now, true)
+	acquire(t, s, "b", now, false)
+
+	// renewing extends the lease
+	acquire(t, s, "a", now.Add(30*time.Second), true)
+	acquire(t, s, "a", now.Add(30*time.Second), true)
+	acquire(t, s, "b", now.Add(time.Minute), false)
+
+	// an expired lease is taken over
+	acquire(t, s, "b", now.Add(90*time.Second), true)
+	acquire(t, s, "a", now.Add(90*time.Second), false)
+}
+
+func testLeaseRelease(t *testing.T, s scheduler.Store) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	acquire(t, s, "a", now, true)
+
+	if err := s.Release(ctx, "lease", "b"); err != nil {
+		t.Fatalf("could not release the lease: %s", err)
+	}
+	acquire(t, s, "b", now, false)
+
+	if err := s.Release(ctx, "lease", "a"); err != nil {
+		t.Fatalf("could not release the lease: %s", err)
+	}
+	acquire(t, s, "b", now, true)
+
+	ok, err := s.Acquire(ctx, "other", "a", now, now.Add(time.Minute))
+	if err != nil || !ok {
+		t.Fatalf("the leases are not independent: %v, %v", ok, err)
+	}
+}
+
+func testRuns(t *testing.T, s scheduler.Store) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	runs, err := s.LastRuns(ctx)
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("expected no runs, got %+v, %v", runs, err)
+	}
+
+	for _, run := range []scheduler.Run{
+		{Job: "b", Holder: "a", Started: now, Duration: time.Second, Result: "first"},
+		{Job: "a", Holder: "a", Started: now, Duration: time.Second, Error: "failed"},
+		{Job: "b", Holder: "b", Started: now.Add(time.Minute), Duration: 2 * time.Second, Result: "second"},
+	} {
+		if err := s.SaveRun(ctx, &run); err != nil {
+			t.Fatalf("could not save the run: %s", err)
+		}
+	}
+
+	runs, err = s.LastRuns(ctx)
+	if err != nil {
+		t.Fatalf("could not list the runs: %s", err)
+	}
+	if len(runs) != 2 || runs[0].Job != "a" || runs[1].Job != "b" {
+		t.Fatalf("expected the last run of a and b, got %+v", runs)
+	}
+	if runs[0].Error != "failed" || runs[0].Result != "" {
+		t.Fatalf("unexpected run of a: %+v", runs[0])
+	}
+
+	b := runs[1]
+	if b.Holder != "b" || !b.Started.Equal(now.Add(time.Minute)) || b.Duration != 2*time.Second || b.Result != "second" || b.Error != "" {
+		t.Fatalf("unexpected run of b: %+v", b)
+	}
+}
+
+// newScheduler returns a scheduler running a job counting its runs
+func newScheduler(t *testing.T, s scheduler.Store, c *clock, instance string, runs *int) *scheduler.Scheduler {
+	t.Helper()
+
+	sch := scheduler.New(s)
+	sch.Logger = slog.New(slog.DiscardHandler)
+	sch.Now = c.Now
+	sch.Instance = instance
+	sch.Tick = time.Minute
+
+	err := sch.Add(scheduler.Job{
+		Name:     "count",
+		Interval: 10 * time.Minute,
+		Run: func(ctx context.Context) (string, error) {
+			*runs++
+			return "counted", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not add the job: %s", err)
+	}
+
+	return sch
+}
+
+func step(t *testing.T, sch *scheduler.Scheduler) {
+	t.Helper()
+
+	if err := sch.Step(context.Background()); err != nil {
+		t.Fatalf("%s: step failed: %s", sch.Instance, err)
+	}
+}
+
+func status(t *testing.T, sch *scheduler.Scheduler) *scheduler.Status {
+	t.Helper()
+
+	st, err := sch.Status(context.Background())
+	if err != nil {
+		t.Fatalf("%s: could not get the status: %s", sch.Instance, err)
+	}
+
+	return st
+}
+
+func testLeader(t *testing.T, s scheduler.Store) {
+	c := newClock()
+	var runs int
+	a := newScheduler(t, s, c, "a", &runs)
+	b := newScheduler(t, s, c, "b", &runs)
+
+	step(t, a)
+	step(t, b)
+	if runs != 1 {
+		t.Fatalf("expected the job to run once, got %d", runs)
+	}
+	if !status(t, a).Leader || status(t, b).Leader {
+		t.Fatal("expected a to be the only leader")
+	}
+
+	// the job is not due before its interval
+	c.Advance(9 * time.Minute)
+	step(t, a)
+	step(t, b)
+	if runs != 1 {
+		t.Fatalf("the job ran before its interval, %d runs", runs)
+	}
+
+	c.Advance(time.Minute)
+	step(t, b)
+	step(t, a)
+	if runs != 2 {
+		t.Fatalf("expected the job to run again, got %d runs", runs)
+	}
+
+	st := status(t, b)
+	if len(st.Jobs) != 1 || st.Jobs[0].LastRun == nil {
+		t.Fatalf("expected the run to be shared, got %+v", st)
+	}
+	job := st.Jobs[0]
+	if job.LastRun.Holder != "a" || job.LastRun.Result != "counted" || !job.Next.Equal(c.Now().Add(10*time.Minute)) {
+		t.Fatalf("unexpected job status: %+v, last run %+v", job, job.LastRun)
+	}
+}
+
+func testFailover(t *testing.T, s scheduler.Store) {
+	c := newClock()
+	var runs int
+	a := newScheduler(t, s, c, "a", &runs)
+	b := newScheduler(t, s, c, "b", &runs)
+
+	step(t, a)
+
+	// a stops renewing its lease, b takes over once it expired
+	c.Advance(2 * time.Minute)
+	step(t, b)
+	if status(t, b).Leader {
+		t.Fatal("b took over before the lease expired")
+	}
+
+	c.Advance(time.Minute)
+	step(t, b)
+	if !status(t, b).Leader {
+		t.Fatal("b did not take over the expired lease")
+	}
+
+	// the interval is honoured across instances
+	if runs != 1 {
+		t.Fatalf("expected the job to run once, got %d", runs)
+	}
+
+	step(t, a)
+	if status(t, a).Leader {
+		t.Fatal("a is still the leader")
+	}
+
+	// stopping gives the lease up right away
+	b.Start(context.Background())
+	if err := b.Stop(context.Background()); err != nil {
+		t.Fatalf("could not stop b: %s", err)
+	}
+	step(t, a)
+	if !status(t, a).Leader {
+		t.Fatal("a did not take over the released lease")
+	}
+}
+
+func testFailedJob(t *testing.T, s scheduler.Store) {
+	c := newClock()
+	sch := scheduler.New(s)
+	sch.Logger = slog.New(slog.DiscardHandler)
+	sch.Now = c.Now
+
+	var runs int
+	err := sch.Add(scheduler.Job{
+		Name:     "fail",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			runs++
+			return "", errors.New("boom")
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not add the job: %s", err)
+	}
+
+	if err := sch.Add(scheduler.Job{Name: "fail", Interval: time.Hour, Run: func(ctx context.Context) (string, error) { return "", nil }}); err == nil {
+		t.Fatal("expected a duplicate job to be rejected")
+	}
+
+	// a failure does not fail the step, it is recorded and retried on
+	// the next interval
+	step(t, sch)
+	step(t, sch)
+	if runs != 1 {
+		t.Fatalf("expected the failed job to run once, got %d", runs)
+	}
+
+	st := status(t, sch)
+	if st.Jobs[0].LastRun == nil || st.Jobs[0].LastRun.Error != "boom" {
+		t.Fatalf("expected the failure to be recorded, got %+v", st.Jobs[0])
+	}
+}
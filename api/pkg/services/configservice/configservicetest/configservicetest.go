@@ -0,0 +1,226 @@
+// Package configservicetest is the conformance suite of the
+// ConfigService implementations, they must all pass it. It is meant to be
+// called from the tests of every implementation, with its factory:
+//
+//	func TestConfigService(t *testing.T) {
+//		configservicetest.Run(t, newService)
+//	}
+package configservicetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+)
+
+// Factory returns a new empty ConfigService
+type Factory func(t *testing.T) configservice.ConfigService
+
+// Run runs the suite against the implementation built by newService, a
+// new service is built for every case.
+func Run(t *testing.T, newService Factory) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s configservice.ConfigService)
+	}{
+		{"CreateOIDCProvider", testCreate},
+		{"CreateOIDCProviderDefaults", testCreateDefaults},
+		{"CreateOIDCProviderDuplicate", testCreateDuplicate},
+		{"GetOIDCProvider", testGet},
+		{"GetOIDCProviders", testList},
+		{"UpsertOIDCProvider", testUpsert},
+		{"DeleteOIDCProvider", testDelete},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newService(t))
+		})
+	}
+}
+
+func provider(name string) *configservice.OIDCProvider {
+	return &configservice.OIDCProvider{
+		Name:         name,
+		DisplayName:  "Provider " + name,
+		Issuer:       "https://" + name + ".example.com",
+		ClientID:     name + "-client",
+		ClientSecret: name + "-secret",
+		Scopes:       []string{"openid", "email"},
+		Created:      time.Now(),
+	}
+}
+
+func create(t *testing.T, s configservice.ConfigService, name string) *configservice.OIDCProvider {
+	t.Helper()
+
+	prov, err := s.CreateOIDCProvider(context.Background(), provider(name))
+	if err != nil {
+		t.Fatalf("could not create provider %s: %s", name, err)
+	}
+
+	return prov
+}
+
+func expectError(t *testing.T, what string, err error, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: expected %q, got %v", what, target, err)
+	}
+}
+
+func expectNoError(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %s", what, err)
+	}
+}
+
+// expectProvider compares the stored provider to the expected one, the
+// creation times with a tolerance since the databases do not all keep the
+// same precision
+func expectProvider(t *testing.T, got *configservice.OIDCProvider, want *configservice.OIDCProvider) {
+	t.Helper()
+
+	if got.Name != want.Name || got.Active != want.Active || got.DisplayName != want.DisplayName ||
+		got.Issuer != want.Issuer || got.ClientID != want.ClientID || got.ClientSecret != want.ClientSecret ||
+		!slices.Equal(got.Scopes, want.Scopes) {
+		t.Fatalf("expected provider %+v, got %+v", want, got)
+	}
+
+	if d := got.Created.Sub(want.Created); d <= -time.Second || d >= time.Second {
+		t.Fatalf("expected creation time %s, got %s", want.Created, got.Created)
+	}
+}
+
+func testCreate(t *testing.T, s configservice.ConfigService) {
+	want := provider("google")
+	create(t, s, "google")
+
+	got, err := s.GetOIDCProvider(context.Background(), "google")
+	expectNoError(t, "get", err)
+
+	want.Active = true
+	expectProvider(t, got, want)
+}
+
+func testCreateDefaults(t *testing.T, s configservice.ConfigService) {
+	ctx := context.Background()
+
+	prov := provider("google")
+	prov.Scopes = nil
+	prov.Active = false
+	_, err := s.CreateOIDCProvider(ctx, prov)
+	expectNoError(t, "create", err)
+
+	got, err := s.GetOIDCProvider(ctx, "google")
+	expectNoError(t, "get", err)
+	if !got.Active {
+		t.Fatal("the provider was not created active")
+	}
+	if !slices.Equal(got.Scopes, configservice.DefaultScopes) {
+		t.Fatalf("expected the default scopes, got %v", got.Scopes)
+	}
+}
+
+func testCreateDuplicate(t *testing.T, s configservice.ConfigService) {
+	create(t, s, "google")
+
+	_, err := s.CreateOIDCProvider(context.Background(), provider("google"))
+	expectError(t, "duplicate", err, configservice.ErrOIDCProviderExists)
+}
+
+func testGet(t *testing.T, s configservice.ConfigService) {
+	ctx := context.Background()
+
+	_, err := s.GetOIDCProvider(ctx, "google")
+	expectError(t, "unknown provider", err, configservice.ErrOIDCProviderNotFound)
+
+	create(t, s, "google")
+
+	// the returned provider must not alias the stored one
+	got, err := s.GetOIDCProvider(ctx, "google")
+	expectNoError(t, "get", err)
+	got.Scopes[0] = "changed"
+	got.Active = false
+
+	got, err = s.GetOIDCProvider(ctx, "google")
+	expectNoError(t, "get", err)
+	if got.Scopes[0] != "openid" || !got.Active {
+		t.Fatalf("the stored provider was modified: %+v", got)
+	}
+}
+
+func testList(t *testing.T, s configservice.ConfigService) {
+	ctx := context.Background()
+
+	providers, err := s.GetOIDCProviders(ctx)
+	expectNoError(t, "list", err)
+	if len(providers) != 0 {
+		t.Fatalf("expected no providers, got %d", len(providers))
+	}
+
+	create(t, s, "google")
+	create(t, s, "github")
+
+	providers, err = s.GetOIDCProviders(ctx)
+	expectNoError(t, "list", err)
+
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"github", "google"}) {
+		t.Fatalf("unexpected providers: %v", names)
+	}
+}
+
+func testUpsert(t *testing.T, s configservice.ConfigService) {
+	ctx := context.Background()
+
+	// creates the missing providers, active
+	prov := provider("google")
+	prov.Active = false
+	_, err := s.UpsertOIDCProvider(ctx, prov)
+	expectNoError(t, "insert", err)
+
+	got, err := s.GetOIDCProvider(ctx, "google")
+	expectNoError(t, "get", err)
+	if !got.Active {
+		t.Fatal("the provider was not created active")
+	}
+
+	// updates the existing ones, active flag included
+	prov.DisplayName = "Google"
+	prov.Scopes = []string{"openid"}
+	prov.Active = false
+	_, err = s.UpsertOIDCProvider(ctx, prov)
+	expectNoError(t, "update", err)
+
+	got, err = s.GetOIDCProvider(ctx, "google")
+	expectNoError(t, "get", err)
+	expectProvider(t, got, prov)
+}
+
+func testDelete(t *testing.T, s configservice.ConfigService) {
+	ctx := context.Background()
+	create(t, s, "google")
+	create(t, s, "github")
+
+	expectNoError(t, "delete", s.DeleteOIDCProvider(ctx, "google"))
+
+	_, err := s.GetOIDCProvider(ctx, "google")
+	expectError(t, "get deleted provider", err, configservice.ErrOIDCProviderNotFound)
+
+	_, err = s.GetOIDCProvider(ctx, "github")
+	expectNoError(t, "get another provider", err)
+
+	expectError(t, "delete twice", s.DeleteOIDCProvider(ctx, "google"), configservice.ErrOIDCProviderNotFound)
+}
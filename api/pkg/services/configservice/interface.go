@@ -1,8 +1,13 @@
 package configservice
 
+import "context"
+
+// ConfigService manages the OIDC providers. The providers are always
+// created active, and with the DefaultScopes when they have none.
 type ConfigService interface {
-	GetOIDCProvider(name string) (*OIDCProvider, error)
-	UpsertOIDCProvider(prov *OIDCProvider) (*OIDCProvider, error)
-	GetOIDCProviders() ([]OIDCProvider, error)
-	CreateOIDCProvider(prov *OIDCProvider) (*OIDCProvider, error)
+	GetOIDCProvider(ctx context.Context, name string) (*OIDCProvider, error)
+	UpsertOIDCProvider(ctx context.Context, prov *OIDCProvider) (*OIDCProvider, error)
+	GetOIDCProviders(ctx context.Context) ([]OIDCProvider, error)
+	CreateOIDCProvider(ctx context.Context, prov *OIDCProvider) (*OIDCProvider, error)
+	DeleteOIDCProvider(ctx context.Context, name string) error
 }
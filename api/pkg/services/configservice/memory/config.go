@@ -0,0 +1,106 @@
+// Package memoryconfigservice is a ConfigService keeping everything in
+// memory, with the same semantics as the sql one. It is meant for tests
+// and development, nothing survives a restart.
+package memoryconfigservice
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+)
+
+type ConfigService struct {
+	lock      sync.RWMutex
+	providers map[string]configservice.OIDCProvider
+}
+
+var _ configservice.ConfigService = &ConfigService{}
+
+func NewConfigService() *ConfigService {
+	return &ConfigService{
+		providers: make(map[string]configservice.OIDCProvider),
+	}
+}
+
+// clone copies the provider so that the callers cannot alter the stored
+// one through its scopes
+func clone(prov *configservice.OIDCProvider) *configservice.OIDCProvider {
+	c := *prov
+	c.Scopes = append([]string(nil), prov.Scopes...)
+	if len(c.Scopes) == 0 {
+		c.Scopes = append([]string(nil), configservice.DefaultScopes...)
+	}
+
+	return &c
+}
+
+func (s *ConfigService) GetOIDCProvider(ctx context.Context, name string) (*configservice.OIDCProvider, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	prov, ok := s.providers[name]
+	if !ok {
+		return nil, configservice.ErrOIDCProviderNotFound
+	}
+
+	return clone(&prov), nil
+}
+
+func (s *ConfigService) UpsertOIDCProvider(ctx context.Context, prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stored := clone(prov)
+	if _, ok := s.providers[prov.Name]; !ok {
+		stored.Active = true
+	}
+	s.providers[prov.Name] = *stored
+
+	return prov, nil
+}
+
+func (s *ConfigService) GetOIDCProviders(ctx context.Context) ([]configservice.OIDCProvider, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	plist := make([]configservice.OIDCProvider, 0, len(s.providers))
+	for _, p := range s.providers {
+		plist = append(plist, *clone(&p))
+	}
+
+	sort.Slice(plist, func(i, j int) bool {
+		return plist[i].Name < plist[j].Name
+	})
+
+	return plist, nil
+}
+
+func (s *ConfigService) CreateOIDCProvider(ctx context.Context, prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.providers[prov.Name]; ok {
+		return nil, configservice.ErrOIDCProviderExists
+	}
+
+	stored := clone(prov)
+	stored.Active = true
+	s.providers[prov.Name] = *stored
+
+	return prov, nil
+}
+
+func (s *ConfigService) DeleteOIDCProvider(ctx context.Context, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.providers[name]; !ok {
+		return configservice.ErrOIDCProviderNotFound
+	}
+
+	delete(s.providers, name)
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package memoryconfigservice_test
+
+import (
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice/configservicetest"
+	memoryconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/memory"
+)
+
+func newService(t *testing.T) configservice.ConfigService {
+	return memoryconfigservice.NewConfigService()
+}
+
+func TestConfigService(t *testing.T) {
+	configservicetest.Run(t, newService)
+}
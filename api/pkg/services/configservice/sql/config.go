@@ -1,6 +1,7 @@
 package configservice
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"errors"
 	"strings"
@@ -39,12 +40,20 @@ func oidcProviderToModel(input *configservice.OIDCProvider) *models.OIDCProvider
 		Issuer:       input.Issuer,
 		ClientID:     input.ClientID,
 		ClientSecret: input.ClientSecret,
-		Scopes:       strings.Join(input.Scopes, ","),
+		Scopes:       strings.Join(scopesOrDefault(input.Scopes), ","),
 
 		Created: input.Created,
 	}
 }
 
+func scopesOrDefault(scopes []string) []string {
+	if len(scopes) == 0 {
+		return configservice.DefaultScopes
+	}
+
+	return scopes
+}
+
 func NewConfigService(db *gorm.DB) (configservice.ConfigService, error) {
 	if err := db.AutoMigrate(models.OIDCProvider{}); err != nil {
 		return nil, err
@@ -55,34 +64,38 @@ func NewConfigService(db *gorm.DB) (configservice.ConfigService, error) {
 	}, nil
 }
 
-func (s *ConfigService) GetOIDCProvider(name string) (*configservice.OIDCProvider, error) {
+func (s *ConfigService) GetOIDCProvider(ctx context.Context, name string) (*configservice.OIDCProvider, error) {
 	var prov models.OIDCProvider
-	if err := s.DB.Where(&models.OIDCProvider{Name: name}).First(&prov).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.OIDCProvider{Name: name}).First(&prov).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, configservice.ErrOIDCProviderNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
 	return oidcProviderFromModel(&prov), nil
 }
 
-func (s *ConfigService) UpsertOIDCProvider(prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
+func (s *ConfigService) UpsertOIDCProvider(ctx context.Context, prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
 	var prv models.OIDCProvider
-	if err := s.DB.Where(&models.OIDCProvider{Name: prov.Name}).First(&prv).Error; err == nil {
-		if err := s.DB.Save(oidcProviderToModel(prov)).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.OIDCProvider{Name: prov.Name}).First(&prv).Error; err == nil {
+		if err := s.DB.WithContext(ctx).Save(oidcProviderToModel(prov)).Error; err != nil {
 			return nil, err
 		}
 		return prov, nil
 	} else if errors.Is(err, gorm.ErrRecordNotFound) {
-		if err := s.DB.Create(oidcProviderToModel(prov)).Error; err != nil {
+		if err := s.DB.WithContext(ctx).Create(oidcProviderToModel(prov)).Error; err != nil {
 			return prov, err
 		}
+	} else {
+		return nil, err
 	}
 
-	return oidcProviderFromModel(&prv), nil
+	return prov, nil
 }
 
-func (s *ConfigService) GetOIDCProviders() ([]configservice.OIDCProvider, error) {
+func (s *ConfigService) GetOIDCProviders(ctx context.Context) ([]configservice.OIDCProvider, error) {
 	var providers []models.OIDCProvider
-	if err := s.DB.Find(&providers).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Find(&providers).Error; err != nil {
 		return nil, err
 	}
 
@@ -94,10 +107,25 @@ func (s *ConfigService) GetOIDCProviders() ([]configservice.OIDCProvider, error)
 	return plist, nil
 }
 
-func (s *ConfigService) CreateOIDCProvider(prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
-	if err := s.DB.Create(oidcProviderToModel(prov)).Error; err != nil {
+func (s *ConfigService) CreateOIDCProvider(ctx context.Context, prov *configservice.OIDCProvider) (*configservice.OIDCProvider, error) {
+	if err := s.DB.WithContext(ctx).Create(oidcProviderToModel(prov)).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, configservice.ErrOIDCProviderExists
+	} else if err != nil {
 		return nil, err
 	}
 
 	return prov, nil
 }
+
+func (s *ConfigService) DeleteOIDCProvider(ctx context.Context, name string) error {
+	res := s.DB.WithContext(ctx).Where(&models.OIDCProvider{Name: name}).Delete(&models.OIDCProvider{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return configservice.ErrOIDCProviderNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package configservice_test
+
+import (
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/configservice/configservicetest"
+	sqlconfigservice "github.com/thomas-maurice/api/go-vue/pkg/services/configservice/sql"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+func newService(t *testing.T) configservice.ConfigService {
+	t.Helper()
+
+	s, err := sqlconfigservice.NewConfigService(storetest.SQLite(t))
+	if err != nil {
+		t.Fatalf("could not create the service: %s", err)
+	}
+
+	return s
+}
+
+func TestConfigService(t *testing.T) {
+	configservicetest.Run(t, newService)
+}
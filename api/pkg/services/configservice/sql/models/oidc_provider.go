@@ -3,17 +3,17 @@ package models
 import "time"
 
 type OIDCProvider struct {
-	Name   string `gorm:"primaryKey;column:name"`
-	Active bool   `gorm:"column:active;not null;default:true"`
+	Name   string `gorm:"primaryKey;column:name" json:"name"`
+	Active bool   `gorm:"column:active;not null;default:true" json:"active"`
 
-	DisplayName string `gorm:"column:display_name"`
+	DisplayName string `gorm:"column:display_name" json:"display_name"`
 
-	Issuer       string `gorm:"column:issuer;not null"`
-	ClientID     string `gorm:"column:client_id;not null"`
-	ClientSecret string `gorm:"column:client_secret;not null"`
-	Scopes       string `gorm:"column:scopes;not null;default:openid,profile,email,groups"`
+	Issuer       string `gorm:"column:issuer;not null" json:"issuer"`
+	ClientID     string `gorm:"column:client_id;not null" json:"client_id"`
+	ClientSecret string `gorm:"column:client_secret;not null" json:"client_secret"`
+	Scopes       string `gorm:"column:scopes;not null;default:openid,profile,email,groups" json:"scopes"`
 
-	Created time.Time `gorm:"created,default:null"`
+	Created time.Time `gorm:"created,default:null" json:"created"`
 }
 
 func (o *OIDCProvider) TableName() string {
@@ -0,0 +1,72 @@
+package configservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/thomas-maurice/api/go-vue/pkg/services/configservice"
+
+type tracingConfigService struct {
+	next   ConfigService
+	tracer trace.Tracer
+}
+
+// NewTracingConfigService wraps a ConfigService so that every call is
+// recorded as a span of tp, child of the span found in the context.
+func NewTracingConfigService(next ConfigService, tp trace.TracerProvider) ConfigService {
+	return &tracingConfigService{
+		next:   next,
+		tracer: tp.Tracer(tracerName),
+	}
+}
+
+func (s *tracingConfigService) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "ConfigService."+name, trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracingConfigService) GetOIDCProvider(ctx context.Context, name string) (*OIDCProvider, error) {
+	ctx, span := s.start(ctx, "GetOIDCProvider", attribute.String("oidc.provider", name))
+	prov, err := s.next.GetOIDCProvider(ctx, name)
+	end(span, err)
+	return prov, err
+}
+
+func (s *tracingConfigService) UpsertOIDCProvider(ctx context.Context, prov *OIDCProvider) (*OIDCProvider, error) {
+	ctx, span := s.start(ctx, "UpsertOIDCProvider", attribute.String("oidc.provider", prov.Name))
+	res, err := s.next.UpsertOIDCProvider(ctx, prov)
+	end(span, err)
+	return res, err
+}
+
+func (s *tracingConfigService) GetOIDCProviders(ctx context.Context) ([]OIDCProvider, error) {
+	ctx, span := s.start(ctx, "GetOIDCProviders")
+	provs, err := s.next.GetOIDCProviders(ctx)
+	end(span, err)
+	return provs, err
+}
+
+func (s *tracingConfigService) CreateOIDCProvider(ctx context.Context, prov *OIDCProvider) (*OIDCProvider, error) {
+	ctx, span := s.start(ctx, "CreateOIDCProvider", attribute.String("oidc.provider", prov.Name))
+	res, err := s.next.CreateOIDCProvider(ctx, prov)
+	end(span, err)
+	return res, err
+}
+
+func (s *tracingConfigService) DeleteOIDCProvider(ctx context.Context, name string) error {
+	ctx, span := s.start(ctx, "DeleteOIDCProvider", attribute.String("oidc.provider", name))
+	err := s.next.DeleteOIDCProvider(ctx, name)
+	end(span, err)
+	return err
+}
@@ -1,6 +1,18 @@
 package configservice
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	ErrOIDCProviderNotFound = fmt.Errorf("unknown oidc provider")
+	ErrOIDCProviderExists   = fmt.Errorf("oidc provider already exists")
+)
+
+// DefaultScopes are the scopes requested from the providers created
+// without any
+var DefaultScopes = []string{"openid", "profile", "email", "groups"}
 
 type OIDCProvider struct {
 	Name   string `json:"name"`
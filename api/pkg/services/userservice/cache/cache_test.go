@@ -0,0 +1,354 @@
+package cacheuserservice_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+	memoryuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/memory"
+)
+
+const password = "p4ssw0rd"
+
+// testCache runs the cases of the cache with the notifiers built by
+// newNotifier, a new one is built for every case, shared by the instances
+// of the case.
+func testCache(t *testing.T, newNotifier func(t *testing.T) cacheuserservice.Notifier) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, e *env)
+	}{
+		{"Hits", testHits},
+		{"ForgedToken", testForgedToken},
+		{"TTL", testTTL},
+		{"Expiry", testExpiry},
+		{"Eviction", testEviction},
+		{"Invalidation", testInvalidation},
+		{"CrossInstance", testCrossInstance},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+			if err != nil {
+				t.Fatalf("could not generate the signing key: %s", err)
+			}
+
+			e := &env{
+				clock:    &clock{now: time.Now().Truncate(time.Second)},
+				notifier: newNotifier(t),
+			}
+			e.backend = &counting{UserService: memoryuserservice.NewUserService(key)}
+			e.backend.UserService.(*memoryuserservice.UserService).Now = e.clock.Now
+			c.fn(t, e)
+		})
+	}
+}
+
+// env is what a case runs against: the caches of every instance wrap
+// the same backend, and share the clock and the notifier
+type env struct {
+	clock    *clock
+	notifier cacheuserservice.Notifier
+	backend  *counting
+}
+
+// newCache returns the cache of a new instance, listening to the
+// invalidations of the others
+func (e *env) newCache(t *testing.T, size int) *cacheuserservice.UserService {
+	t.Helper()
+
+	c := cacheuserservice.NewUserService(e.backend, e.notifier, size, time.Minute)
+	c.Now = e.clock.Now
+	c.Start(context.Background())
+	t.Cleanup(func() { _ = c.Stop(context.Background()) })
+
+	return c
+}
+
+// counting counts the verifications reaching the backend
+type counting struct {
+	userservice.UserService
+	verifications atomic.Int64
+}
+
+func (c *counting) VerifySessionToken(ctx context.Context, token string) (*userservice.Session, *userservice.User, error) {
+	c.verifications.Add(1)
+	return c.UserService.VerifySessionToken(ctx, token)
+}
+
+type clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func login(t *testing.T, s userservice.UserService, username string) (*userservice.User, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	u, err := s.CreateUser(ctx, username, username+"@example.com", password, string(userservice.UserKindLocal), false, "")
+	if err != nil {
+		t.Fatalf("could not create %s: %s", username, err)
+	}
+
+	token, err := s.GenerateSessionToken(ctx, u)
+	if err != nil {
+		t.Fatalf("could not log %s in: %s", username, err)
+	}
+
+	return u, token
+}
+
+func verify(t *testing.T, s userservice.UserService, token string) *userservice.User {
+	t.Helper()
+
+	_, u, err := s.VerifySessionToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("could not verify the token: %s", err)
+	}
+
+	return u
+}
+
+func expectInvalid(t *testing.T, s userservice.UserService, token string) {
+	t.Helper()
+
+	if _, _, err := s.VerifySessionToken(context.Background(), token); !errors.Is(err, userservice.ErrInvalidSession) {
+		t.Fatalf("expected the session to be invalid, got %v", err)
+	}
+}
+
+func expectVerifications(t *testing.T, e *env, expected int64) {
+	t.Helper()
+
+	if got := e.backend.verifications.Load(); got != expected {
+		t.Fatalf("expected %d verifications by the backend, got %d", expected, got)
+	}
+}
+
+// eventually retries check until it passes or a second elapsed, the
+// invalidations of the other instances being delivered asynchronously
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func testHits(t *testing.T, e *env) {
+	c := e.newCache(t, cacheuserservice.DefaultSize)
+	u, token := login(t, c, "alice")
+
+	for range 3 {
+		if got := verify(t, c, token); got.Id != u.Id {
+			t.Fatalf("unexpected user: %+v", got)
+		}
+	}
+	expectVerifications(t, e, 1)
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Sessions != 1 || stats.Users != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if ratio := stats.HitRatio(); ratio < 0.66 || ratio > 0.67 {
+		t.Fatalf("unexpected hit ratio: %f", ratio)
+	}
+
+	// the returned values are copies
+	got := verify(t, c, token)
+	got.Admin = true
+	if verify(t, c, token).Admin {
+		t.Fatal("the cached user was modified by the caller")
+	}
+}
+
+func testForgedToken(t *testing.T, e *env) {
+	c := e.newCache(t, cacheuserservice.DefaultSize)
+	_, token := login(t, c, "alice")
+	verify(t, c, token)
+
+	// same claims, another signature
+	forged := token[:len(token)-4] + "AAAA"
+	if forged == token {
+		forged = token[:len(token)-4] + "BBBB"
+	}
+	expectInvalid(t, c, forged)
+	expectVerifications(t, e, 2)
+}
+
+func testTTL(t *testing.T, e *env) {
+	c := e.newCache(t, cacheuserservice.DefaultSize)
+	_, token := login(t, c, "alice")
+	verify(t, c, token)
+
+	e.clock.Advance(time.Minute - time.Second)
+	verify(t, c, token)
+	expectVerifications(t, e, 1)
+
+	e.clock.Advance(time.Second)
+	verify(t, c, token)
+	expectVerifications(t, e, 2)
+}
+
+func testExpiry(t *testing.T, e *env) {
+	c := cacheuserservice.NewUserService(e.backend, e.notifier, cacheuserservice.DefaultSize, 2*userservice.SessionDuration)
+	c.Now = e.clock.Now
+	_, token := login(t, c, "alice")
+	verify(t, c, token)
+
+	// the cache outlives the session, which must not
+	e.clock.Advance(userservice.SessionDuration + userservice.ClockSkewLeeway)
+	expectInvalid(t, c, token)
+}
+
+func testEviction(t *testing.T, e *env) {
+	c := e.newCache(t, 2)
+	_, alice := login(t, c, "alice")
+	_, bob := login(t, c, "bob")
+
+	verify(t, c, alice)
+	verify(t, c, bob)
+	verify(t, c, alice)
+	expectVerifications(t, e, 2)
+
+	// one session and one user of bob make room for carol
+	_, carol := login(t, c, "carol")
+	verify(t, c, carol)
+	stats := c.Stats()
+	if stats.Evictions != 2 || stats.Sessions != 2 || stats.Users != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	verify(t, c, alice)
+	expectVerifications(t, e, 3)
+	verify(t, c, bob)
+	expectVerifications(t, e, 4)
+}
+
+func testInvalidation(t *testing.T, e *env) {
+	ctx := context.Background()
+	c := e.newCache(t, cacheuserservice.DefaultSize)
+	u, token := login(t, c, "alice")
+	verify(t, c, token)
+
+	if err := c.UpdateUser(ctx, u.Id, u.Email, true, "Alice"); err != nil {
+		t.Fatalf("could not update the user: %s", err)
+	}
+	if got := verify(t, c, token); !got.Admin || got.DisplayName != "Alice" {
+		t.Fatalf("the cached user was not updated: %+v", got)
+	}
+
+	if err := c.SetActive(ctx, u.Id, false); err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+	expectInvalid(t, c, token)
+
+	if err := c.SetActive(ctx, u.Id, true); err != nil {
+		t.Fatalf("could not activate the user: %s", err)
+	}
+	session, _, err := c.VerifySessionToken(ctx, token)
+	if err != nil {
+		t.Fatalf("could not verify the token: %s", err)
+	}
+
+	if err := c.RevokeSession(ctx, u.Id, session.Id); err != nil {
+		t.Fatalf("could not revoke the session: %s", err)
+	}
+	expectInvalid(t, c, token)
+
+	token, err = c.GenerateSessionToken(ctx, u)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+	verify(t, c, token)
+	if err := c.LogoutFromToken(ctx, token); err != nil {
+		t.Fatalf("could not log out: %s", err)
+	}
+	expectInvalid(t, c, token)
+
+	token, err = c.GenerateSessionToken(ctx, u)
+	if err != nil {
+		t.Fatalf("could not log in: %s", err)
+	}
+	verify(t, c, token)
+	if _, err := c.RevokeAllSessions(ctx, ""); err != nil {
+		t.Fatalf("could not revoke the sessions: %s", err)
+	}
+	expectInvalid(t, c, token)
+}
+
+func testCrossInstance(t *testing.T, e *env) {
+	ctx := context.Background()
+	a := e.newCache(t, cacheuserservice.DefaultSize)
+	b := e.newCache(t, cacheuserservice.DefaultSize)
+
+	u, token := login(t, a, "alice")
+	verify(t, a, token)
+	verify(t, b, token)
+
+	if err := a.SetActive(ctx, u.Id, false); err != nil {
+		t.Fatalf("could not deactivate the user: %s", err)
+	}
+	expectInvalid(t, a, token)
+	eventually(t, func() error {
+		if _, _, err := b.VerifySessionToken(ctx, token); !errors.Is(err, userservice.ErrInvalidSession) {
+			return errors.New("the other instance still accepts the session of the deactivated user")
+		}
+		return nil
+	})
+
+	// the session may be rejected by a cache flushed by the invalidation
+	// of the login, every invalidation of a has to be applied by b first
+	eventually(t, func() error {
+		if b.Stats().Invalidations != a.Stats().Invalidations {
+			return errors.New("the invalidations of the other instance were not all applied")
+		}
+		return nil
+	})
+
+	// the instances ignore their own invalidations coming back
+	before, own := a.Stats().Invalidations, b.Stats().Invalidations
+	if err := b.SetActive(ctx, u.Id, true); err != nil {
+		t.Fatalf("could not activate the user: %s", err)
+	}
+	eventually(t, func() error {
+		if a.Stats().Invalidations != before+1 {
+			return errors.New("the invalidation did not reach the other instance")
+		}
+		return nil
+	})
+	if got := b.Stats().Invalidations; got != own+1 {
+		t.Fatalf("expected the own invalidation to be applied once, got %d invalidations", got)
+	}
+}
@@ -0,0 +1,91 @@
+package cacheuserservice
+
+import "container/list"
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// lru is a map holding at most size entries, the least recently used
+// ones being evicted first. It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	size  int
+	items map[K]*list.Element
+	order *list.List
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	return &lru[K, V]{
+		size:  size,
+		items: make(map[K]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (l *lru[K, V]) Get(key K) (V, bool) {
+	e, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	l.order.MoveToFront(e)
+	return e.Value.(*lruEntry[K, V]).value, true
+}
+
+// Add adds or replaces an entry, and returns how many were evicted to
+// make room for it
+func (l *lru[K, V]) Add(key K, value V) int {
+	if l.size <= 0 {
+		return 0
+	}
+
+	if e, ok := l.items[key]; ok {
+		e.Value.(*lruEntry[K, V]).value = value
+		l.order.MoveToFront(e)
+		return 0
+	}
+
+	l.items[key] = l.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	evicted := 0
+	for l.order.Len() > l.size {
+		l.remove(l.order.Back())
+		evicted++
+	}
+
+	return evicted
+}
+
+func (l *lru[K, V]) Remove(key K) {
+	if e, ok := l.items[key]; ok {
+		l.remove(e)
+	}
+}
+
+// RemoveFunc removes the entries fn returns true for
+func (l *lru[K, V]) RemoveFunc(fn func(key K, value V) bool) {
+	for e := l.order.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*lruEntry[K, V])
+		if fn(entry.key, entry.value) {
+			l.remove(e)
+		}
+		e = next
+	}
+}
+
+func (l *lru[K, V]) Clear() {
+	l.items = make(map[K]*list.Element)
+	l.order.Init()
+}
+
+func (l *lru[K, V]) Len() int {
+	return l.order.Len()
+}
+
+func (l *lru[K, V]) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.items, e.Value.(*lruEntry[K, V]).key)
+}
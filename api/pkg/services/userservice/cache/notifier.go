@@ -0,0 +1,88 @@
+package cacheuserservice
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// InvalidateSession drops the session of id Key
+	InvalidateSession = "session"
+	// InvalidateUser drops the user of id Key, along with its sessions
+	InvalidateUser = "user"
+	// InvalidateAll drops everything
+	InvalidateAll = "all"
+)
+
+const (
+	// NotifierNone is the name of NopNotifier in the configuration
+	NotifierNone = "none"
+	// NotifierSQL is the name of SQLNotifier in the configuration
+	NotifierSQL = "sql"
+)
+
+// Invalidation tells the caches to drop entries. Origin is the instance
+// it comes from, which already dropped them.
+type Invalidation struct {
+	Origin string
+	Kind   string
+	Key    string
+}
+
+// Notifier carries the invalidations between the instances.
+// Implementations must be safe for concurrent use.
+type Notifier interface {
+	// Publish sends an invalidation to every listener
+	Publish(ctx context.Context, inv Invalidation) error
+	// Listen calls fn with the invalidations published, including the
+	// ones of the listener itself, until ctx is cancelled
+	Listen(ctx context.Context, fn func(Invalidation)) error
+}
+
+// NopNotifier drops the invalidations, it only suits a single instance
+type NopNotifier struct{}
+
+func (NopNotifier) Publish(ctx context.Context, inv Invalidation) error {
+	return nil
+}
+
+func (NopNotifier) Listen(ctx context.Context, fn func(Invalidation)) error {
+	<-ctx.Done()
+	return nil
+}
+
+// MemoryNotifier carries the invalidations between the caches of the
+// same process, it is meant for tests.
+type MemoryNotifier struct {
+	lock      sync.Mutex
+	listeners map[*func(Invalidation)]struct{}
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{listeners: make(map[*func(Invalidation)]struct{})}
+}
+
+func (n *MemoryNotifier) Publish(ctx context.Context, inv Invalidation) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for fn := range n.listeners {
+		(*fn)(inv)
+	}
+
+	return nil
+}
+
+func (n *MemoryNotifier) Listen(ctx context.Context, fn func(Invalidation)) error {
+	n.lock.Lock()
+	n.listeners[&fn] = struct{}{}
+	n.lock.Unlock()
+
+	<-ctx.Done()
+
+	n.lock.Lock()
+	delete(n.listeners, &fn)
+	n.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package cacheuserservice_test
+
+import (
+	"testing"
+
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+)
+
+func TestMemoryNotifier(t *testing.T) {
+	testCache(t, func(t *testing.T) cacheuserservice.Notifier {
+		return cacheuserservice.NewMemoryNotifier()
+	})
+}
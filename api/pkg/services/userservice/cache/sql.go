@@ -0,0 +1,112 @@
+package cacheuserservice
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPollInterval is how often the SQLNotifier looks for new
+	// invalidations
+	DefaultPollInterval = time.Second
+
+	// retentionPolls is how many polls the invalidations are kept for
+	retentionPolls = 60
+)
+
+type invalidation struct {
+	Id      uint64    `gorm:"primaryKey;autoIncrement;column:id"`
+	Origin  string    `gorm:"column:origin;size:255;not null"`
+	Kind    string    `gorm:"column:kind;size:32;not null"`
+	Key     string    `gorm:"column:invalidation_key;size:255"`
+	Created time.Time `gorm:"column:created;not null;index"`
+}
+
+func (o *invalidation) TableName() string {
+	return "cache_invalidations"
+}
+
+// SQLNotifier shares the invalidations through a table of the database,
+// that every listener polls. The invalidations are kept for 60 polls.
+// Listeners drop their whole cache after failing to poll, since they may
+// have missed some, and may miss the ones committed out of order, the
+// TTL of the caches bounding how long they serve stale entries for.
+type SQLNotifier struct {
+	DB       *gorm.DB
+	Logger   *slog.Logger
+	Interval time.Duration
+}
+
+func NewSQLNotifier(db *gorm.DB) (*SQLNotifier, error) {
+	if err := db.AutoMigrate(invalidation{}); err != nil {
+		return nil, err
+	}
+
+	return &SQLNotifier{
+		DB:       db,
+		Logger:   slog.Default(),
+		Interval: DefaultPollInterval,
+	}, nil
+}
+
+func (n *SQLNotifier) Publish(ctx context.Context, inv Invalidation) error {
+	return n.DB.WithContext(ctx).Create(&invalidation{
+		Origin:  inv.Origin,
+		Kind:    inv.Kind,
+		Key:     inv.Key,
+		Created: time.Now(),
+	}).Error
+}
+
+func (n *SQLNotifier) Listen(ctx context.Context, fn func(Invalidation)) error {
+	var last uint64
+	if err := n.DB.WithContext(ctx).Model(&invalidation{}).Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(n.Interval)
+	defer ticker.Stop()
+
+	retention := retentionPolls * n.Interval
+	lastCleanup := time.Now()
+	failed := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var rows []invalidation
+		if err := n.DB.WithContext(ctx).Where("id > ?", last).Order("id").Find(&rows).Error; err != nil {
+			if ctx.Err() == nil && !failed {
+				n.Logger.Warn("could not poll the cache invalidations", "error", err)
+			}
+			failed = true
+			continue
+		}
+
+		// invalidations may have been missed while the database was
+		// unreachable
+		if failed {
+			fn(Invalidation{Kind: InvalidateAll})
+			failed = false
+		}
+
+		for _, row := range rows {
+			fn(Invalidation{Origin: row.Origin, Kind: row.Kind, Key: row.Key})
+			last = row.Id
+		}
+
+		if now := time.Now(); now.Sub(lastCleanup) > retention {
+			lastCleanup = now
+			if err := n.DB.WithContext(ctx).Where("created < ?", now.Add(-retention)).Delete(&invalidation{}).Error; err != nil && ctx.Err() == nil {
+				n.Logger.Warn("could not delete the old cache invalidations", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package cacheuserservice_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+// TestSQLNotifier polls every 10ms
+func TestSQLNotifier(t *testing.T) {
+	testCache(t, func(t *testing.T) cacheuserservice.Notifier {
+		t.Helper()
+
+		n, err := cacheuserservice.NewSQLNotifier(storetest.SQLite(t))
+		if err != nil {
+			t.Fatalf("could not create the notifier: %s", err)
+		}
+		n.Logger = slog.New(slog.DiscardHandler)
+		n.Interval = 10 * time.Millisecond
+
+		return n
+	})
+}
@@ -0,0 +1,430 @@
+// Package cacheuserservice is a UserService keeping the sessions it
+// verified, and their users, in memory for a while, so that
+// authenticating a request does not hit the database every time. The
+// calls changing a user or a session drop the affected entries, and
+// publish an invalidation telling the other instances to do the same.
+package cacheuserservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+)
+
+const (
+	DefaultSize = 10000
+	DefaultTTL  = 30 * time.Second
+)
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Sessions      int
+	Users         int
+}
+
+// HitRatio is the share of the verifications served from the cache
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type cachedSession struct {
+	session userservice.Session
+	userId  string
+	// token is the hash of the token the session was verified with,
+	// only that token can hit the entry
+	token  string
+	cached time.Time
+}
+
+type cachedUser struct {
+	user   userservice.User
+	cached time.Time
+}
+
+// UserService caches the sessions verified by another UserService. A
+// session is served from the cache only when its user is too, so that
+// dropping a user drops its sessions. Now defaults to time.Now and
+// Origin, the name of the instance in the invalidations, to the
+// hostname followed by a random suffix.
+type UserService struct {
+	Now    userservice.Clock
+	Origin string
+
+	next     userservice.UserService
+	notifier Notifier
+	ttl      time.Duration
+
+	lock     sync.Mutex
+	sessions *lru[string, cachedSession]
+	users    *lru[string, cachedUser]
+	// generation changes on every invalidation, so that the entries read
+	// before one are not cached after it
+	generation uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	invalidations atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ userservice.UserService = &UserService{}
+
+// NewUserService caches up to size sessions of next for ttl. With a size
+// of zero nothing is cached, but the invalidations are still published,
+// which suits the tools changing the users of running instances.
+func NewUserService(next userservice.UserService, notifier Notifier, size int, ttl time.Duration) *UserService {
+	return &UserService{
+		Now:      time.Now,
+		Origin:   originName(),
+		next:     next,
+		notifier: notifier,
+		ttl:      ttl,
+		sessions: newLRU[string, cachedSession](size),
+		users:    newLRU[string, cachedUser](size),
+	}
+}
+
+func originName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return hostname + "-" + hex.EncodeToString(b)
+}
+
+// Start listens to the invalidations of the other instances in the
+// background, until Stop is called or ctx is cancelled
+func (s *UserService) Start(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		listen := func(inv Invalidation) {
+			if inv.Origin != s.Origin {
+				s.apply(inv)
+			}
+		}
+
+		if err := s.notifier.Listen(ctx, listen); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("could not listen to the cache invalidations, dropping the cache", "error", err)
+			s.apply(Invalidation{Kind: InvalidateAll})
+		}
+	}(s.done)
+}
+
+// Stop stops listening to the invalidations
+func (s *UserService) Stop(ctx context.Context) error {
+	s.lock.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.lock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *UserService) Stats() Stats {
+	s.lock.Lock()
+	sessions, users := s.sessions.Len(), s.users.Len()
+	s.lock.Unlock()
+
+	return Stats{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Evictions:     s.evictions.Load(),
+		Invalidations: s.invalidations.Load(),
+		Sessions:      sessions,
+		Users:         users,
+	}
+}
+
+// apply drops the entries of an invalidation
+func (s *UserService) apply(inv Invalidation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch inv.Kind {
+	case InvalidateSession:
+		s.sessions.Remove(inv.Key)
+	case InvalidateUser:
+		s.users.Remove(inv.Key)
+		s.sessions.RemoveFunc(func(_ string, cs cachedSession) bool { return cs.userId == inv.Key })
+	default:
+		s.sessions.Clear()
+		s.users.Clear()
+	}
+
+	s.generation++
+	s.invalidations.Add(1)
+}
+
+// invalidate drops the entries locally, then tells the other instances.
+// The change being made already, failing to publish is only logged.
+func (s *UserService) invalidate(ctx context.Context, kind string, key string) {
+	inv := Invalidation{Origin: s.Origin, Kind: kind, Key: key}
+	s.apply(inv)
+
+	if err := s.notifier.Publish(ctx, inv); err != nil {
+		logging.FromContext(ctx).Warn("could not publish a cache invalidation", "error", err, "kind", kind, "key", key)
+	}
+}
+
+// tokenSessionId reads the id of the session of a token, without verifying
+// it, a cached session only being used when the hash of the token
+// matches
+func tokenSessionId(token string) string {
+	var claims userservice.TokenClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return ""
+	}
+
+	return claims.SessionId
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// cached returns the session of the token and its user when both are
+// cached and still usable at now
+func (s *UserService) cached(sid string, hash string, now time.Time) (*userservice.Session, *userservice.User, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cs, ok := s.sessions.Get(sid)
+	if !ok || cs.token != hash {
+		return nil, nil, false
+	}
+
+	// expired sessions, and the ones issued in the future of a clock
+	// going backwards, are left to the next service to reject
+	issued := cs.session.Expires.Add(-userservice.SessionDuration)
+	if now.Sub(cs.cached) >= s.ttl || userservice.SessionExpired(cs.session.Expires, now) || now.Before(issued.Add(-userservice.ClockSkewLeeway)) {
+		s.sessions.Remove(sid)
+		return nil, nil, false
+	}
+
+	cu, ok := s.users.Get(cs.userId)
+	if !ok || now.Sub(cu.cached) >= s.ttl {
+		s.users.Remove(cs.userId)
+		return nil, nil, false
+	}
+
+	session, user := cs.session, cu.user
+	return &session, &user, true
+}
+
+func (s *UserService) VerifySessionToken(ctx context.Context, token string) (*userservice.Session, *userservice.User, error) {
+	now := s.Now()
+	sid := tokenSessionId(token)
+	hash := tokenHash(token)
+
+	if sid != "" {
+		if session, user, ok := s.cached(sid, hash, now); ok {
+			s.hits.Add(1)
+			return session, user, nil
+		}
+	}
+	s.misses.Add(1)
+
+	s.lock.Lock()
+	generation := s.generation
+	s.lock.Unlock()
+
+	session, user, err := s.next.VerifySessionToken(ctx, token)
+	if err != nil || sid == "" || session.Id != sid {
+		return session, user, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.generation != generation {
+		return session, user, nil
+	}
+
+	evicted := s.sessions.Add(sid, cachedSession{session: *session, userId: user.Id, token: hash, cached: now})
+	evicted += s.users.Add(user.Id, cachedUser{user: *user, cached: now})
+	s.evictions.Add(uint64(evicted))
+
+	return session, user, nil
+}
+
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*userservice.User, error) {
+	return s.next.GetUserByUsername(ctx, username)
+}
+
+func (s *UserService) GetUserById(ctx context.Context, id string) (*userservice.User, error) {
+	return s.next.GetUserById(ctx, id)
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, id string, email string, admin bool, displayName string) error {
+	if err := s.next.UpdateUser(ctx, id, email, admin, displayName); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateUser, id)
+	return nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, username string, email string, password string, kind string, admin bool, displayName string) (*userservice.User, error) {
+	return s.next.CreateUser(ctx, username, email, password, kind, admin, displayName)
+}
+
+func (s *UserService) ListUsers(ctx context.Context) ([]userservice.User, error) {
+	return s.next.ListUsers(ctx)
+}
+
+func (s *UserService) SetPassword(ctx context.Context, id string, password string) error {
+	if err := s.next.SetPassword(ctx, id, password); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateUser, id)
+	return nil
+}
+
+func (s *UserService) SetPasswordHash(ctx context.Context, id string, hash string) error {
+	if err := s.next.SetPasswordHash(ctx, id, hash); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateUser, id)
+	return nil
+}
+
+func (s *UserService) SetActive(ctx context.Context, id string, active bool) error {
+	if err := s.next.SetActive(ctx, id, active); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateUser, id)
+	return nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	if err := s.next.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateUser, id)
+	return nil
+}
+
+func (s *UserService) Authenticate(ctx context.Context, username, password string) (*userservice.User, error) {
+	return s.next.Authenticate(ctx, username, password)
+}
+
+func (s *UserService) LogoutFromToken(ctx context.Context, token string) error {
+	if err := s.next.LogoutFromToken(ctx, token); err != nil {
+		return err
+	}
+
+	if sid := tokenSessionId(token); sid != "" {
+		s.invalidate(ctx, InvalidateSession, sid)
+	}
+	return nil
+}
+
+// GenerateSessionToken drops the user, whose last login changes, its
+// other sessions being verified again on their next use
+func (s *UserService) GenerateSessionToken(ctx context.Context, user *userservice.User) (string, error) {
+	token, err := s.next.GenerateSessionToken(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	s.invalidate(ctx, InvalidateUser, user.Id)
+	return token, nil
+}
+
+func (s *UserService) ListSessions(ctx context.Context, userId string) ([]userservice.Session, error) {
+	return s.next.ListSessions(ctx, userId)
+}
+
+func (s *UserService) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	if err := s.next.RevokeSession(ctx, userId, sessionId); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, InvalidateSession, sessionId)
+	return nil
+}
+
+func (s *UserService) RevokeAllSessions(ctx context.Context, userId string) (int64, error) {
+	count, err := s.next.RevokeAllSessions(ctx, userId)
+	if err != nil {
+		return count, err
+	}
+
+	if userId == "" {
+		s.invalidate(ctx, InvalidateAll, "")
+	} else {
+		s.invalidate(ctx, InvalidateUser, userId)
+	}
+	return count, nil
+}
+
+// DeleteExpiredSessions leaves the cache alone, the expired sessions
+// are not served from it
+func (s *UserService) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	return s.next.DeleteExpiredSessions(ctx)
+}
+
+func (s *UserService) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
+	return s.next.DeleteExpiredAPIKeys(ctx)
+}
+
+func (s *UserService) DeactivateStaleUsers(ctx context.Context, kind userservice.UserKind, before time.Time) (int64, error) {
+	count, err := s.next.DeactivateStaleUsers(ctx, kind, before)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	s.invalidate(ctx, InvalidateAll, "")
+	return count, nil
+}
@@ -0,0 +1,34 @@
+package cacheuserservice_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	cacheuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/cache"
+	sqluserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice/userservicetest"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+// newService returns the cache on top of the sql implementation, the
+// cache must not change the semantics of the service it wraps
+func newService(t *testing.T, key *ecdsa.PrivateKey, now userservice.Clock, newID userservice.IDGenerator) userservice.UserService {
+	t.Helper()
+
+	next, err := sqluserservice.NewUserService(storetest.SQLite(t), key)
+	if err != nil {
+		t.Fatalf("could not create the service: %s", err)
+	}
+	next.Now = now
+	next.NewID = newID
+
+	s := cacheuserservice.NewUserService(next, cacheuserservice.NopNotifier{}, cacheuserservice.DefaultSize, cacheuserservice.DefaultTTL)
+	s.Now = now
+
+	return s
+}
+
+func TestUserService(t *testing.T) {
+	userservicetest.Run(t, newService)
+}
@@ -1,13 +1,43 @@
 package userservice
 
+import (
+	"context"
+	"time"
+)
+
+// UserService manages the users and their sessions. Passwords are given
+// in clear and hashed with bcrypt, only SetPasswordHash takes a hash.
+// Deactivated users can neither log in nor use their existing sessions.
 type UserService interface {
-	GetUserByUsername(username string) (*User, error)
-	GetUserById(id string) (*User, error)
-	UpdateUser(id string, email string, admin bool, displayName string) error
-	CreateUser(username string, email string, password string, kind string, admin bool, displayName string) (*User, error)
-	ListUsers() ([]User, error)
-	Authenticate(username, password string) (*User, error)
-	LogoutFromToken(token string) error
-	GenerateSessionToken(user *User) (string, error)
-	VerifySessionToken(token string) (*Session, *User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserById(ctx context.Context, id string) (*User, error)
+	UpdateUser(ctx context.Context, id string, email string, admin bool, displayName string) error
+	CreateUser(ctx context.Context, username string, email string, password string, kind string, admin bool, displayName string) (*User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	SetPassword(ctx context.Context, id string, password string) error
+	// SetPasswordHash sets a password already hashed with bcrypt, such as
+	// the one of the built-in admin in the configuration, and fails on
+	// anything else
+	SetPasswordHash(ctx context.Context, id string, hash string) error
+	SetActive(ctx context.Context, id string, active bool) error
+	DeleteUser(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, username, password string) (*User, error)
+	LogoutFromToken(ctx context.Context, token string) error
+	GenerateSessionToken(ctx context.Context, user *User) (string, error)
+	VerifySessionToken(ctx context.Context, token string) (*Session, *User, error)
+	ListSessions(ctx context.Context, userId string) ([]Session, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string) error
+	// RevokeAllSessions revokes the sessions of a user, or of every user
+	// when userId is empty, and returns how many were revoked
+	RevokeAllSessions(ctx context.Context, userId string) (int64, error)
+	// DeleteExpiredSessions deletes the sessions past their expiry and
+	// leeway, and returns how many were deleted
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+	// DeleteExpiredAPIKeys deletes the API keys past their expiry, the
+	// keys without one never expire, and returns how many were deleted
+	DeleteExpiredAPIKeys(ctx context.Context) (int64, error)
+	// DeactivateStaleUsers deactivates the active users of the kind that
+	// did not log in since before, or were created before it when they
+	// never did, and returns how many were deactivated
+	DeactivateStaleUsers(ctx context.Context, kind UserKind, before time.Time) (int64, error)
 }
@@ -0,0 +1,435 @@
+// Package memoryuserservice is a UserService keeping everything in
+// memory, with the same semantics as the sql one. It is meant for tests
+// and development, nothing survives a restart.
+package memoryuserservice
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type user struct {
+	userservice.User
+	password string
+}
+
+type session struct {
+	userId  string
+	expires time.Time
+}
+
+type UserService struct {
+	SigningKey *ecdsa.PrivateKey
+	Now        userservice.Clock
+	NewID      userservice.IDGenerator
+
+	lock     sync.RWMutex
+	users    map[string]*user
+	sessions map[string]*session
+}
+
+var _ userservice.UserService = &UserService{}
+
+func NewUserService(sk *ecdsa.PrivateKey) *UserService {
+	return &UserService{
+		SigningKey: sk,
+		Now:        time.Now,
+		NewID:      uuid.NewString,
+		users:      make(map[string]*user),
+		sessions:   make(map[string]*session),
+	}
+}
+
+// hashPassword hashes the password with bcrypt
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// byUsername returns the user with the given username, the lock must be
+// held
+func (s *UserService) byUsername(username string) *user {
+	for _, u := range s.users {
+		if u.Username == username {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*userservice.User, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	u := s.byUsername(username)
+	if u == nil {
+		return nil, userservice.ErrUserNotFound
+	}
+
+	user := u.User
+	return &user, nil
+}
+
+func (s *UserService) GetUserById(ctx context.Context, id string) (*userservice.User, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, userservice.ErrUserNotFound
+	}
+
+	user := u.User
+	return &user, nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, id string, email string, admin bool, displayName string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return userservice.ErrUserNotFound
+	}
+
+	if email != "" && email != u.Email {
+		for _, other := range s.users {
+			if other.Email == email {
+				return userservice.ErrUserExists
+			}
+		}
+		u.Email = email
+	}
+
+	u.Admin = admin
+	if displayName != "" {
+		u.DisplayName = displayName
+	}
+
+	return nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, username string, email string, password string, kind string, admin bool, displayName string) (*userservice.User, error) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == username || u.Email == email {
+			return nil, userservice.ErrUserExists
+		}
+	}
+
+	u := &user{
+		User: userservice.User{
+			Id:          s.NewID(),
+			Username:    username,
+			Email:       email,
+			DisplayName: displayName,
+			Admin:       admin,
+			Active:      true,
+			Kind:        userservice.UserKind(kind),
+			Created:     s.Now(),
+		},
+		password: hashed,
+	}
+	s.users[u.Id] = u
+
+	user := u.User
+	return &user, nil
+}
+
+func (s *UserService) ListUsers(ctx context.Context) ([]userservice.User, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ulist := make([]userservice.User, 0, len(s.users))
+	for _, u := range s.users {
+		ulist = append(ulist, u.User)
+	}
+
+	sort.Slice(ulist, func(i, j int) bool {
+		return ulist[i].Created.Before(ulist[j].Created)
+	})
+
+	return ulist, nil
+}
+
+func (s *UserService) SetPassword(ctx context.Context, id string, password string) error {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return userservice.ErrUserNotFound
+	}
+	u.password = hashed
+
+	return nil
+}
+
+func (s *UserService) SetPasswordHash(ctx context.Context, id string, hash string) error {
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return fmt.Errorf("invalid password hash: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return userservice.ErrUserNotFound
+	}
+	u.password = hash
+
+	return nil
+}
+
+func (s *UserService) SetActive(ctx context.Context, id string, active bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return userservice.ErrUserNotFound
+	}
+	u.Active = active
+
+	return nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return userservice.ErrUserNotFound
+	}
+
+	delete(s.users, id)
+	for sid, sess := range s.sessions {
+		if sess.userId == id {
+			delete(s.sessions, sid)
+		}
+	}
+
+	return nil
+}
+
+func (s *UserService) Authenticate(ctx context.Context, username, password string) (*userservice.User, error) {
+	s.lock.RLock()
+	u := s.byUsername(username)
+	var found user
+	if u != nil {
+		found = *u
+	}
+	s.lock.RUnlock()
+
+	if u == nil {
+		return nil, userservice.ErrInvalidCredentials
+	}
+
+	if found.Kind != userservice.UserKindLocal {
+		return nil, fmt.Errorf("%w: cannot authenticate with a password on a non-local user", userservice.ErrInvalidCredentials)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(found.password), []byte(password)); err != nil {
+		return nil, fmt.Errorf("%w: %s", userservice.ErrInvalidCredentials, err)
+	}
+
+	if !found.Active {
+		return nil, userservice.ErrUserDeactivated
+	}
+
+	return &found.User, nil
+}
+
+func (s *UserService) LogoutFromToken(ctx context.Context, token string) error {
+	claims, err := userservice.ParseSessionToken(s.SigningKey, token, s.Now())
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.sessions, claims.SessionId)
+
+	return nil
+}
+
+func (s *UserService) GenerateSessionToken(ctx context.Context, user *userservice.User) (string, error) {
+	if !user.Active {
+		return "", userservice.ErrUserDeactivated
+	}
+
+	now := s.Now()
+	sessionId := s.NewID()
+
+	sig, err := userservice.SignSessionToken(s.SigningKey, user, sessionId, now)
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sessions[sessionId] = &session{
+		userId:  user.Id,
+		expires: now.Add(userservice.SessionDuration),
+	}
+
+	if u, ok := s.users[user.Id]; ok {
+		u.LastLogin = now
+	}
+
+	return sig, nil
+}
+
+func (s *UserService) VerifySessionToken(ctx context.Context, token string) (*userservice.Session, *userservice.User, error) {
+	now := s.Now()
+	claims, err := userservice.ParseSessionToken(s.SigningKey, token, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	sess, ok := s.sessions[claims.SessionId]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unknown session", userservice.ErrInvalidSession)
+	}
+
+	if userservice.SessionExpired(sess.expires, now) {
+		return nil, nil, fmt.Errorf("%w: expired session", userservice.ErrInvalidSession)
+	}
+
+	u := s.byUsername(claims.Subject)
+	if u == nil {
+		return nil, nil, fmt.Errorf("%w: unknown user", userservice.ErrInvalidSession)
+	}
+
+	if !u.Active {
+		return nil, nil, fmt.Errorf("%w: %w", userservice.ErrInvalidSession, userservice.ErrUserDeactivated)
+	}
+
+	user := u.User
+	return &userservice.Session{Id: claims.SessionId, Expires: sess.expires}, &user, nil
+}
+
+func (s *UserService) ListSessions(ctx context.Context, userId string) ([]userservice.Session, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	now := s.Now()
+	slist := make([]userservice.Session, 0)
+	for sid, sess := range s.sessions {
+		if sess.userId == userId && !userservice.SessionExpired(sess.expires, now) {
+			slist = append(slist, userservice.Session{Id: sid, Expires: sess.expires})
+		}
+	}
+
+	sort.Slice(slist, func(i, j int) bool {
+		return slist[i].Expires.Before(slist[j].Expires)
+	})
+
+	return slist, nil
+}
+
+func (s *UserService) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sess, ok := s.sessions[sessionId]
+	if !ok || sess.userId != userId {
+		return userservice.ErrSessionNotFound
+	}
+
+	delete(s.sessions, sessionId)
+
+	return nil
+}
+
+func (s *UserService) RevokeAllSessions(ctx context.Context, userId string) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var count int64
+	for sid, sess := range s.sessions {
+		if userId == "" || sess.userId == userId {
+			delete(s.sessions, sid)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *UserService) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.Now()
+	var count int64
+	for sid, sess := range s.sessions {
+		if userservice.SessionExpired(sess.expires, now) {
+			delete(s.sessions, sid)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// DeleteExpiredAPIKeys has nothing to delete, the API keys are only kept
+// in the database
+func (s *UserService) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (s *UserService) DeactivateStaleUsers(ctx context.Context, kind userservice.UserKind, before time.Time) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var count int64
+	for _, u := range s.users {
+		last := u.LastLogin
+		if last.IsZero() {
+			last = u.Created
+		}
+
+		if u.Active && u.Kind == kind && last.Before(before) {
+			u.Active = false
+			count++
+		}
+	}
+
+	return count, nil
+}
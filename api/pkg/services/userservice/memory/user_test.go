@@ -0,0 +1,22 @@
+package memoryuserservice_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	memoryuserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/memory"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice/userservicetest"
+)
+
+func newService(t *testing.T, key *ecdsa.PrivateKey, now userservice.Clock, newID userservice.IDGenerator) userservice.UserService {
+	s := memoryuserservice.NewUserService(key)
+	s.Now = now
+	s.NewID = newID
+
+	return s
+}
+
+func TestUserService(t *testing.T) {
+	userservicetest.Run(t, newService)
+}
@@ -8,13 +8,13 @@ import (
 )
 
 type APIKey struct {
-	Id      string    `gorm:"primaryKey;column:id"`
-	Hash    string    `gorm:"column:hash;not null"`
-	Name    string    `gorm:"column:string;not null"`
-	Active  bool      `gorm:"column:active;not null;default:true"`
-	UserId  string    `gorm:"column:user_id;not null"`
-	User    User      `gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE"`
-	Expires time.Time `gorm:"column:expires;default:null"`
+	Id      string    `gorm:"primaryKey;column:id" json:"id"`
+	Hash    string    `gorm:"column:hash;not null" json:"hash"`
+	Name    string    `gorm:"column:string;not null" json:"name"`
+	Active  bool      `gorm:"column:active;not null;default:true" json:"active"`
+	UserId  string    `gorm:"column:user_id;not null" json:"user_id"`
+	User    User      `gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE" json:"-"`
+	Expires time.Time `gorm:"column:expires;default:null" json:"expires"`
 }
 
 func (o *APIKey) TableName() string {
@@ -8,14 +8,10 @@ import (
 )
 
 type Session struct {
-	Id      string    `gorm:"primaryKey;column:id"`
-	UserId  string    `gorm:"column:user_id;not null"`
-	User    User      `gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE"`
-	Expires time.Time `gorm:"column:expires"`
-}
-
-func (o *Session) AfterFind(tx *gorm.DB) error {
-	return tx.First(&o.User, &User{Id: o.UserId}).Error
+	Id      string    `gorm:"primaryKey;column:id" json:"id"`
+	UserId  string    `gorm:"column:user_id;not null" json:"user_id"`
+	User    User      `gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE" json:"-"`
+	Expires time.Time `gorm:"column:expires" json:"expires"`
 }
 
 func (o *Session) BeforeCreate(tx *gorm.DB) (err error) {
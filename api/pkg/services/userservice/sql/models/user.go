@@ -9,16 +9,16 @@ import (
 )
 
 type User struct {
-	Id          string               `gorm:"column:id;primary_key"`
-	Username    string               `gorm:"column:username;unique;not null"`
-	Email       string               `gorm:"column:email;unique;not null"`
-	Active      bool                 `gorm:"column:active;not null;default:true"`
-	DisplayName string               `gorm:"column:display_name"`
-	Kind        userservice.UserKind `gorm:"column:kind;not null"`
-	Admin       bool                 `gorm:"column:admin;default:false"`
-	Password    string               `gorm:"column:password"`
-	Created     time.Time            `gorm:"column:created;default:null"`
-	LastLogin   time.Time            `gorm:"column:last_login;default:null"`
+	Id          string               `gorm:"column:id;primary_key" json:"id"`
+	Username    string               `gorm:"column:username;unique;not null" json:"username"`
+	Email       string               `gorm:"column:email;unique;not null" json:"email"`
+	Active      bool                 `gorm:"column:active;not null;default:true" json:"active"`
+	DisplayName string               `gorm:"column:display_name" json:"display_name"`
+	Kind        userservice.UserKind `gorm:"column:kind;not null" json:"kind"`
+	Admin       bool                 `gorm:"column:admin;default:false" json:"admin"`
+	Password    string               `gorm:"column:password" json:"password"`
+	Created     time.Time            `gorm:"column:created;default:null" json:"created"`
+	LastLogin   time.Time            `gorm:"column:last_login;default:null" json:"last_login"`
 }
 
 func (o *User) TableName() string {
@@ -1,11 +1,12 @@
 package sqluserservice
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
 	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql/models"
@@ -13,17 +14,11 @@ import (
 	"gorm.io/gorm"
 )
 
-type CustomClaims struct {
-	SessionId string `json:"session_id"`
-	Name      string `json:"name"`
-	Admin     bool   `json:"admin"`
-
-	jwt.RegisteredClaims
-}
-
 type UserService struct {
 	DB         *gorm.DB
 	SigningKey *ecdsa.PrivateKey
+	Now        userservice.Clock
+	NewID      userservice.IDGenerator
 }
 
 func userFromModel(input *models.User) *userservice.User {
@@ -84,12 +79,14 @@ func NewUserService(db *gorm.DB, sk *ecdsa.PrivateKey) (*UserService, error) {
 	return &UserService{
 		DB:         db,
 		SigningKey: sk,
+		Now:        time.Now,
+		NewID:      uuid.NewString,
 	}, nil
 }
 
-func (s *UserService) GetUserByUsername(username string) (*userservice.User, error) {
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*userservice.User, error) {
 	var user models.User
-	if err := s.DB.Where(&models.User{Username: username}).First(&user).Error; err == gorm.ErrRecordNotFound {
+	if err := s.DB.WithContext(ctx).Where(&models.User{Username: username}).First(&user).Error; err == gorm.ErrRecordNotFound {
 		return nil, userservice.ErrUserNotFound
 	} else if err != nil {
 		return nil, err
@@ -98,16 +95,18 @@ func (s *UserService) GetUserByUsername(username string) (*userservice.User, err
 	return userFromModel(&user), nil
 }
 
-func (s *UserService) GetUserById(id string) (*userservice.User, error) {
+func (s *UserService) GetUserById(ctx context.Context, id string) (*userservice.User, error) {
 	var user models.User
-	if err := s.DB.Where(&models.User{Id: id}).First(&user).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.User{Id: id}).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, userservice.ErrUserNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
 	return userFromModel(&user), nil
 }
 
-func (s *UserService) UpdateUser(id string, email string, admin bool, displayName string) error {
+func (s *UserService) UpdateUser(ctx context.Context, id string, email string, admin bool, displayName string) error {
 	updates := make(map[string]any)
 
 	updates["admin"] = admin
@@ -120,39 +119,54 @@ func (s *UserService) UpdateUser(id string, email string, admin bool, displayNam
 		updates["display_name"] = displayName
 	}
 
-	return s.DB.Model(&models.User{}).Where(&models.User{Id: id}).Updates(updates).Error
+	return s.updateUser(ctx, id, updates)
 }
 
-func (s *UserService) CreateUser(username string, email string, password string, kind string, admin bool, displayName string) (*userservice.User, error) {
-	hashed := ""
-	if password != "" {
-		b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, err
-		}
-		hashed = string(b)
+// hashPassword hashes the password with bcrypt. An empty password stays
+// empty, the user cannot log in with a password then.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, username string, email string, password string, kind string, admin bool, displayName string) (*userservice.User, error) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return nil, err
 	}
 
 	user := models.User{
+		Id:          s.NewID(),
 		Username:    username,
 		Password:    hashed,
 		Email:       email,
 		Admin:       admin,
+		Active:      true,
 		Kind:        userservice.UserKind(kind),
 		DisplayName: displayName,
-		Created:     time.Now(),
+		Created:     s.Now(),
 	}
 
-	if err := s.DB.Create(&user).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Create(&user).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, userservice.ErrUserExists
+	} else if err != nil {
 		return nil, err
 	}
 
 	return userFromModel(&user), nil
 }
 
-func (s *UserService) ListUsers() ([]userservice.User, error) {
+func (s *UserService) ListUsers(ctx context.Context) ([]userservice.User, error) {
 	var users []models.User
-	if err := s.DB.Find(&users).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
@@ -164,113 +178,211 @@ func (s *UserService) ListUsers() ([]userservice.User, error) {
 	return ulist, nil
 }
 
-func (s *UserService) Authenticate(username, password string) (*userservice.User, error) {
+// updateUser applies the updates to the user, failing when it does not
+// exist. The existence is checked beforehand since MySQL does not count
+// the rows left unchanged as affected.
+func (s *UserService) updateUser(ctx context.Context, id string, updates map[string]any) error {
+	if _, err := s.GetUserById(ctx, id); err != nil {
+		return err
+	}
+
+	return s.DB.WithContext(ctx).Model(&models.User{}).Where(&models.User{Id: id}).Updates(updates).Error
+}
+
+func (s *UserService) SetPassword(ctx context.Context, id string, password string) error {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return s.updateUser(ctx, id, map[string]any{"password": hashed})
+}
+
+func (s *UserService) SetPasswordHash(ctx context.Context, id string, hash string) error {
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return fmt.Errorf("invalid password hash: %w", err)
+	}
+
+	return s.updateUser(ctx, id, map[string]any{"password": hash})
+}
+
+func (s *UserService) SetActive(ctx context.Context, id string, active bool) error {
+	return s.updateUser(ctx, id, map[string]any{"active": active})
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&models.Session{UserId: id}).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where(&models.APIKey{UserId: id}).Delete(&models.APIKey{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where(&models.User{Id: id}).Delete(&models.User{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return userservice.ErrUserNotFound
+		}
+
+		return nil
+	})
+}
+
+func (s *UserService) Authenticate(ctx context.Context, username, password string) (*userservice.User, error) {
 	var user models.User
-	if err := s.DB.Where(&models.User{Username: username}).First(&user).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.User{Username: username}).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, userservice.ErrInvalidCredentials
+	} else if err != nil {
 		return nil, err
 	}
 
 	if user.Kind != "local" {
-		return nil, fmt.Errorf("cannot authenticate with a password on a non-local user")
+		return nil, fmt.Errorf("%w: cannot authenticate with a password on a non-local user", userservice.ErrInvalidCredentials)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", userservice.ErrInvalidCredentials, err)
+	}
+
+	if !user.Active {
+		return nil, userservice.ErrUserDeactivated
 	}
 
 	return userFromModel(&user), nil
 }
 
-func (s *UserService) LogoutFromToken(token string) error {
-	var claims CustomClaims
-	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return &s.SigningKey.PublicKey, nil
-	})
+func (s *UserService) LogoutFromToken(ctx context.Context, token string) error {
+	claims, err := userservice.ParseSessionToken(s.SigningKey, token, s.Now())
 	if err != nil {
-		return fmt.Errorf("failed to verify auth token: %w", err)
+		return err
 	}
 
-	if claims.SessionId == "" {
-		return fmt.Errorf("no session id provided")
-	}
-	if err := s.DB.Delete(&models.Session{Id: claims.SessionId}).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Delete(&models.Session{Id: claims.SessionId}).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *UserService) GenerateSessionToken(user *userservice.User) (string, error) {
-	sessionId := uuid.NewString()
-
-	c := CustomClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "webapp",
-			Audience:  jwt.ClaimStrings{"webapp"},
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
-			Subject:   user.Username,
-			ID:        sessionId,
-		},
-		SessionId: sessionId,
-		Admin:     user.Admin,
-	}
-
-	if err := s.DB.Where("expires < ?", time.Now()).Delete(&models.Session{}).Error; err != nil {
-		fmt.Println("failed to cleanup old sessions")
+func (s *UserService) GenerateSessionToken(ctx context.Context, user *userservice.User) (string, error) {
+	if !user.Active {
+		return "", userservice.ErrUserDeactivated
 	}
 
-	t := jwt.New(jwt.SigningMethodES512)
-	t.Claims = &c
+	now := s.Now()
+	sessionId := s.NewID()
 
-	sig, err := t.SignedString(s.SigningKey)
+	sig, err := userservice.SignSessionToken(s.SigningKey, user, sessionId, now)
 	if err != nil {
 		return "", err
 	}
 
-	if err := s.DB.Create(&models.Session{
+	if err := s.DB.WithContext(ctx).Create(&models.Session{
 		Id:      sessionId,
 		UserId:  user.Id,
-		Expires: time.Now().Add(time.Hour),
+		Expires: now.Add(userservice.SessionDuration),
 	}).Error; err != nil {
 		return "", err
 	}
 
-	if err := s.DB.Model(&user).Update("last_login", time.Now()).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Model(&models.User{}).Where(&models.User{Id: user.Id}).Update("last_login", now).Error; err != nil {
 		return "", err
 	}
 
 	return sig, nil
 }
 
-func (s *UserService) VerifySessionToken(token string) (*userservice.Session, *userservice.User, error) {
-	var claims CustomClaims
-	parsedToken, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return &s.SigningKey.PublicKey, nil
-	})
+func (s *UserService) VerifySessionToken(ctx context.Context, token string) (*userservice.Session, *userservice.User, error) {
+	now := s.Now()
+	claims, err := userservice.ParseSessionToken(s.SigningKey, token, now)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to verify auth token: %w", err)
-	}
-
-	if !parsedToken.Valid {
-		return nil, nil, fmt.Errorf("failed to verify token claims: %w", err)
+		return nil, nil, err
 	}
 
 	var session models.Session
-	if err := s.DB.Where(&models.Session{Id: claims.SessionId}).First(&session).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.Session{Id: claims.SessionId}).First(&session).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("%w: unknown session", userservice.ErrInvalidSession)
+	} else if err != nil {
 		return nil, nil, err
 	}
 
+	if userservice.SessionExpired(session.Expires, now) {
+		return nil, nil, fmt.Errorf("%w: expired session", userservice.ErrInvalidSession)
+	}
+
 	var user models.User
-	if err := s.DB.Where(&models.User{Username: claims.RegisteredClaims.Subject}).First(&user).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where(&models.User{Username: claims.RegisteredClaims.Subject}).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("%w: unknown user", userservice.ErrInvalidSession)
+	} else if err != nil {
 		return nil, nil, err
 	}
 
+	if !user.Active {
+		return nil, nil, fmt.Errorf("%w: %w", userservice.ErrInvalidSession, userservice.ErrUserDeactivated)
+	}
+
 	return sessionFromModel(&session), userFromModel(&user), nil
 }
+
+func (s *UserService) ListSessions(ctx context.Context, userId string) ([]userservice.Session, error) {
+	var sessions []models.Session
+	if err := s.DB.WithContext(ctx).Where("user_id = ? AND expires > ?", userId, s.Now().Add(-userservice.ClockSkewLeeway)).Order("expires").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	slist := make([]userservice.Session, 0, len(sessions))
+	for _, sess := range sessions {
+		slist = append(slist, *(sessionFromModel(&sess)))
+	}
+
+	return slist, nil
+}
+
+func (s *UserService) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	res := s.DB.WithContext(ctx).Where("id = ? AND user_id = ?", sessionId, userId).Delete(&models.Session{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return userservice.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *UserService) RevokeAllSessions(ctx context.Context, userId string) (int64, error) {
+	query := s.DB.WithContext(ctx)
+	if userId != "" {
+		query = query.Where(&models.Session{UserId: userId})
+	} else {
+		// gorm refuses deletes without conditions
+		query = query.Where("1 = 1")
+	}
+
+	res := query.Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
+
+func (s *UserService) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	res := s.DB.WithContext(ctx).Where("expires <= ?", s.Now().Add(-userservice.ClockSkewLeeway)).Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
+
+func (s *UserService) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
+	res := s.DB.WithContext(ctx).Where("expires IS NOT NULL AND expires <= ?", s.Now()).Delete(&models.APIKey{})
+	return res.RowsAffected, res.Error
+}
+
+func (s *UserService) DeactivateStaleUsers(ctx context.Context, kind userservice.UserKind, before time.Time) (int64, error) {
+	res := s.DB.WithContext(ctx).Model(&models.User{}).
+		Where("active = ? AND kind = ?", true, kind).
+		Where("(last_login IS NOT NULL AND last_login < ?) OR (last_login IS NULL AND created < ?)", before, before).
+		Update("active", false)
+	return res.RowsAffected, res.Error
+}
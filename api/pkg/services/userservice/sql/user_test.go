@@ -0,0 +1,28 @@
+package sqluserservice_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	sqluserservice "github.com/thomas-maurice/api/go-vue/pkg/services/userservice/sql"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice/userservicetest"
+	"github.com/thomas-maurice/api/go-vue/pkg/store/storetest"
+)
+
+func newService(t *testing.T, key *ecdsa.PrivateKey, now userservice.Clock, newID userservice.IDGenerator) userservice.UserService {
+	t.Helper()
+
+	s, err := sqluserservice.NewUserService(storetest.SQLite(t), key)
+	if err != nil {
+		t.Fatalf("could not create the service: %s", err)
+	}
+	s.Now = now
+	s.NewID = newID
+
+	return s
+}
+
+func TestUserService(t *testing.T) {
+	userservicetest.Run(t, newService)
+}
@@ -0,0 +1,95 @@
+package userservice
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// SessionDuration is how long the sessions, and their tokens, are
+	// valid for
+	SessionDuration = time.Hour
+
+	// ClockSkewLeeway is how far apart the clocks of the instances issuing
+	// and verifying the tokens may drift. Tokens are accepted that long
+	// after their expiry or before their issue time, and so are sessions.
+	ClockSkewLeeway = 30 * time.Second
+
+	tokenIssuer   = "webapp"
+	tokenAudience = "webapp"
+)
+
+// Clock returns the current time, it is time.Now outside of tests
+type Clock func() time.Time
+
+// IDGenerator returns a new unique id, it is uuid.NewString outside of
+// tests
+type IDGenerator func() string
+
+// TokenClaims are the claims of the session tokens, shared by the
+// implementations so that their tokens are interchangeable
+type TokenClaims struct {
+	SessionId string `json:"session_id"`
+	Name      string `json:"name"`
+	Admin     bool   `json:"admin"`
+
+	jwt.RegisteredClaims
+}
+
+// SignSessionToken issues the token of a new session of the user
+func SignSessionToken(key *ecdsa.PrivateKey, user *User, sessionId string, now time.Time) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodES512, &TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{tokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(SessionDuration)),
+			Subject:   user.Username,
+			ID:        sessionId,
+		},
+		SessionId: sessionId,
+		Admin:     user.Admin,
+	})
+
+	return t.SignedString(key)
+}
+
+// ParseSessionToken verifies the signature and the validity of a
+// session token at the time now, errors wrap ErrInvalidSession
+func ParseSessionToken(key *ecdsa.PrivateKey, token string, now time.Time) (*TokenClaims, error) {
+	var claims TokenClaims
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return &key.PublicKey, nil
+	},
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithLeeway(ClockSkewLeeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to verify auth token: %s", ErrInvalidSession, err)
+	}
+
+	if !parsed.Valid {
+		return nil, fmt.Errorf("%w: failed to verify token claims", ErrInvalidSession)
+	}
+
+	if claims.SessionId == "" {
+		return nil, fmt.Errorf("%w: no session id provided", ErrInvalidSession)
+	}
+
+	return &claims, nil
+}
+
+// SessionExpired tells whether a session expiring at expires can no
+// longer be used at the time now, like its token it is valid up to the
+// end of the leeway excluded
+func SessionExpired(expires time.Time, now time.Time) bool {
+	return !now.Before(expires.Add(ClockSkewLeeway))
+}
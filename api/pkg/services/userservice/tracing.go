@@ -0,0 +1,174 @@
+package userservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+
+type tracingUserService struct {
+	next   UserService
+	tracer trace.Tracer
+}
+
+// NewTracingUserService wraps a UserService so that every call is
+// recorded as a span of tp, child of the span found in the context.
+func NewTracingUserService(next UserService, tp trace.TracerProvider) UserService {
+	return &tracingUserService{
+		next:   next,
+		tracer: tp.Tracer(tracerName),
+	}
+}
+
+func (s *tracingUserService) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "UserService."+name, trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracingUserService) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	ctx, span := s.start(ctx, "GetUserByUsername", attribute.String("user.username", username))
+	user, err := s.next.GetUserByUsername(ctx, username)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracingUserService) GetUserById(ctx context.Context, id string) (*User, error) {
+	ctx, span := s.start(ctx, "GetUserById", attribute.String("user.id", id))
+	user, err := s.next.GetUserById(ctx, id)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracingUserService) UpdateUser(ctx context.Context, id string, email string, admin bool, displayName string) error {
+	ctx, span := s.start(ctx, "UpdateUser", attribute.String("user.id", id))
+	err := s.next.UpdateUser(ctx, id, email, admin, displayName)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) CreateUser(ctx context.Context, username string, email string, password string, kind string, admin bool, displayName string) (*User, error) {
+	ctx, span := s.start(ctx, "CreateUser", attribute.String("user.username", username), attribute.String("user.kind", kind))
+	user, err := s.next.CreateUser(ctx, username, email, password, kind, admin, displayName)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracingUserService) ListUsers(ctx context.Context) ([]User, error) {
+	ctx, span := s.start(ctx, "ListUsers")
+	users, err := s.next.ListUsers(ctx)
+	end(span, err)
+	return users, err
+}
+
+func (s *tracingUserService) SetPassword(ctx context.Context, id string, password string) error {
+	ctx, span := s.start(ctx, "SetPassword", attribute.String("user.id", id))
+	err := s.next.SetPassword(ctx, id, password)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) SetPasswordHash(ctx context.Context, id string, hash string) error {
+	ctx, span := s.start(ctx, "SetPasswordHash", attribute.String("user.id", id))
+	err := s.next.SetPasswordHash(ctx, id, hash)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) SetActive(ctx context.Context, id string, active bool) error {
+	ctx, span := s.start(ctx, "SetActive", attribute.String("user.id", id), attribute.Bool("user.active", active))
+	err := s.next.SetActive(ctx, id, active)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) DeleteUser(ctx context.Context, id string) error {
+	ctx, span := s.start(ctx, "DeleteUser", attribute.String("user.id", id))
+	err := s.next.DeleteUser(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	ctx, span := s.start(ctx, "Authenticate", attribute.String("user.username", username))
+	user, err := s.next.Authenticate(ctx, username, password)
+	end(span, err)
+	return user, err
+}
+
+func (s *tracingUserService) LogoutFromToken(ctx context.Context, token string) error {
+	ctx, span := s.start(ctx, "LogoutFromToken")
+	err := s.next.LogoutFromToken(ctx, token)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) GenerateSessionToken(ctx context.Context, user *User) (string, error) {
+	ctx, span := s.start(ctx, "GenerateSessionToken", attribute.String("user.id", user.Id))
+	token, err := s.next.GenerateSessionToken(ctx, user)
+	end(span, err)
+	return token, err
+}
+
+func (s *tracingUserService) VerifySessionToken(ctx context.Context, token string) (*Session, *User, error) {
+	ctx, span := s.start(ctx, "VerifySessionToken")
+	session, user, err := s.next.VerifySessionToken(ctx, token)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", user.Id), attribute.String("session.id", session.Id))
+	}
+	end(span, err)
+	return session, user, err
+}
+
+func (s *tracingUserService) ListSessions(ctx context.Context, userId string) ([]Session, error) {
+	ctx, span := s.start(ctx, "ListSessions", attribute.String("user.id", userId))
+	sessions, err := s.next.ListSessions(ctx, userId)
+	end(span, err)
+	return sessions, err
+}
+
+func (s *tracingUserService) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	ctx, span := s.start(ctx, "RevokeSession", attribute.String("user.id", userId), attribute.String("session.id", sessionId))
+	err := s.next.RevokeSession(ctx, userId, sessionId)
+	end(span, err)
+	return err
+}
+
+func (s *tracingUserService) RevokeAllSessions(ctx context.Context, userId string) (int64, error) {
+	ctx, span := s.start(ctx, "RevokeAllSessions", attribute.String("user.id", userId))
+	count, err := s.next.RevokeAllSessions(ctx, userId)
+	end(span, err)
+	return count, err
+}
+
+func (s *tracingUserService) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	ctx, span := s.start(ctx, "DeleteExpiredSessions")
+	count, err := s.next.DeleteExpiredSessions(ctx)
+	end(span, err)
+	return count, err
+}
+
+func (s *tracingUserService) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
+	ctx, span := s.start(ctx, "DeleteExpiredAPIKeys")
+	count, err := s.next.DeleteExpiredAPIKeys(ctx)
+	end(span, err)
+	return count, err
+}
+
+func (s *tracingUserService) DeactivateStaleUsers(ctx context.Context, kind UserKind, before time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "DeactivateStaleUsers", attribute.String("user.kind", string(kind)))
+	count, err := s.next.DeactivateStaleUsers(ctx, kind, before)
+	end(span, err)
+	return count, err
+}
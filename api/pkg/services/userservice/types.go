@@ -13,7 +13,14 @@ const (
 	UserKindService UserKind = "service"
 )
 
-var ErrUserNotFound = fmt.Errorf("unknown user")
+var (
+	ErrUserNotFound       = fmt.Errorf("unknown user")
+	ErrInvalidCredentials = fmt.Errorf("invalid credentials")
+	ErrInvalidSession     = fmt.Errorf("invalid session")
+	ErrSessionNotFound    = fmt.Errorf("unknown session")
+	ErrUserDeactivated    = fmt.Errorf("deactivated user")
+	ErrUserExists         = fmt.Errorf("username or email already taken")
+)
 
 type User struct {
 	Id          string    `json:"id"`
@@ -0,0 +1,53 @@
+package userservicetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// clock only moves when told to. It starts on a whole second, the
+// precision of the timestamps of the tokens, so that the boundaries of
+// their validity can be hit exactly.
+type clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Now().Truncate(time.Second)}
+}
+
+func (c *clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock by d, backwards when negative
+func (c *clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// ids generates predictable uuids, in sequence
+type ids struct {
+	lock sync.Mutex
+	last int
+}
+
+func (i *ids) Next() string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.last++
+	return i.id(i.last)
+}
+
+// id returns the nth id generated
+func (i *ids) id(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+}
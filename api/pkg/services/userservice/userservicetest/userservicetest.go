@@ -0,0 +1,693 @@
+// Package userservicetest is the conformance suite of the UserService
+// implementations, they must all pass it. It is meant to be called from
+// the tests of every implementation, with its factory:
+//
+//	func TestUserService(t *testing.T) {
+//		userservicetest.Run(t, newService)
+//	}
+package userservicetest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/thomas-maurice/api/go-vue/pkg/services/userservice"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const password = "s3cr3t-p4ssw0rd"
+
+// Factory returns a new empty UserService signing its tokens with key,
+// reading the time from now and generating its ids with newID
+type Factory func(t *testing.T, key *ecdsa.PrivateKey, now userservice.Clock, newID userservice.IDGenerator) userservice.UserService
+
+// env is what a case runs against, the service reads the time from the
+// clock and generates its ids with ids
+type env struct {
+	key   *ecdsa.PrivateKey
+	clock *clock
+	ids   *ids
+}
+
+// Run runs the suite against the implementation built by newService, a
+// new service is built for every case.
+func Run(t *testing.T, newService Factory) {
+	key := newKey(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s userservice.UserService, e *env)
+	}{
+		{"CreateUser", testCreateUser},
+		{"CreateUserHashLikePassword", testCreateUserHashLikePassword},
+		{"CreateUserDuplicate", testCreateUserDuplicate},
+		{"GetUser", testGetUser},
+		{"UpdateUser", testUpdateUser},
+		{"ListUsers", testListUsers},
+		{"SetPassword", testSetPassword},
+		{"SetPasswordHash", testSetPasswordHash},
+		{"SetActive", testSetActive},
+		{"DeleteUser", testDeleteUser},
+		{"Authenticate", testAuthenticate},
+		{"Sessions", testSessions},
+		{"SessionOfDeactivatedUser", testSessionOfDeactivatedUser},
+		{"InvalidTokens", testInvalidTokens},
+		{"Logout", testLogout},
+		{"RevokeSession", testRevokeSession},
+		{"RevokeAllSessions", testRevokeAllSessions},
+		{"GeneratedIds", testGeneratedIds},
+		{"SessionExpiry", testSessionExpiry},
+		{"ClockSkew", testClockSkew},
+		{"SessionCleanup", testSessionCleanup},
+		{"DeactivateStaleUsers", testDeactivateStaleUsers},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &env{key: key, clock: newClock(), ids: &ids{}}
+			c.fn(t, newService(t, key, e.clock.Now, e.ids.Next), e)
+		})
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the signing key: %s", err)
+	}
+
+	return key
+}
+
+func createUser(t *testing.T, s userservice.UserService, username string, admin bool) *userservice.User {
+	t.Helper()
+
+	u, err := s.CreateUser(context.Background(), username, username+"@example.com", password, string(userservice.UserKindLocal), admin, "User "+username)
+	if err != nil {
+		t.Fatalf("could not create user %s: %s", username, err)
+	}
+
+	return u
+}
+
+func login(t *testing.T, s userservice.UserService, u *userservice.User) string {
+	t.Helper()
+
+	token, err := s.GenerateSessionToken(context.Background(), u)
+	if err != nil {
+		t.Fatalf("could not generate a session token: %s", err)
+	}
+
+	return token
+}
+
+func expectError(t *testing.T, what string, err error, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: expected %q, got %v", what, target, err)
+	}
+}
+
+func expectNoError(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %s", what, err)
+	}
+}
+
+// sameTime compares times with a tolerance, the databases do not all
+// keep the same precision
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Second && d < time.Second
+}
+
+func testCreateUser(t *testing.T, s userservice.UserService, e *env) {
+	before := e.clock.Now()
+	u := createUser(t, s, "alice", true)
+
+	if u.Id == "" {
+		t.Fatal("the user has no id")
+	}
+	if u.Username != "alice" || u.Email != "alice@example.com" || u.DisplayName != "User alice" {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+	if !u.Admin || !u.Active || u.Kind != userservice.UserKindLocal {
+		t.Fatalf("unexpected flags: %+v", u)
+	}
+	if !sameTime(u.Created, before) {
+		t.Fatalf("unexpected creation time: %s", u.Created)
+	}
+	if !u.LastLogin.IsZero() {
+		t.Fatalf("the user should not have logged in: %s", u.LastLogin)
+	}
+}
+
+// testCreateUserHashLikePassword checks a password looking like a bcrypt
+// hash is hashed like any other, only SetPasswordHash taking hashes
+func testCreateUserHashLikePassword(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	expectNoError(t, "hash", err)
+
+	_, err = s.CreateUser(ctx, "alice", "alice@example.com", string(hashed), string(userservice.UserKindLocal), false, "")
+	expectNoError(t, "create", err)
+
+	_, err = s.Authenticate(ctx, "alice", password)
+	expectError(t, "authenticate with the password of the hash", err, userservice.ErrInvalidCredentials)
+
+	_, err = s.Authenticate(ctx, "alice", string(hashed))
+	expectNoError(t, "authenticate with the hash itself", err)
+}
+
+func testCreateUserDuplicate(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	createUser(t, s, "alice", false)
+
+	_, err := s.CreateUser(ctx, "alice", "other@example.com", password, string(userservice.UserKindLocal), false, "")
+	expectError(t, "duplicate username", err, userservice.ErrUserExists)
+
+	_, err = s.CreateUser(ctx, "bob", "alice@example.com", password, string(userservice.UserKindLocal), false, "")
+	expectError(t, "duplicate email", err, userservice.ErrUserExists)
+}
+
+func testGetUser(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	byName, err := s.GetUserByUsername(ctx, "alice")
+	expectNoError(t, "get by username", err)
+	if byName.Id != u.Id {
+		t.Fatalf("expected user %s, got %s", u.Id, byName.Id)
+	}
+
+	byId, err := s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get by id", err)
+	if byId.Username != "alice" {
+		t.Fatalf("expected alice, got %s", byId.Username)
+	}
+
+	_, err = s.GetUserByUsername(ctx, "bob")
+	expectError(t, "unknown username", err, userservice.ErrUserNotFound)
+
+	_, err = s.GetUserById(ctx, "unknown")
+	expectError(t, "unknown id", err, userservice.ErrUserNotFound)
+}
+
+func testUpdateUser(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	expectNoError(t, "update", s.UpdateUser(ctx, u.Id, "new@example.com", true, "Alice"))
+
+	got, err := s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get", err)
+	if got.Email != "new@example.com" || !got.Admin || got.DisplayName != "Alice" {
+		t.Fatalf("the user was not updated: %+v", got)
+	}
+
+	// empty fields are left untouched, but admin is always set
+	expectNoError(t, "partial update", s.UpdateUser(ctx, u.Id, "", false, ""))
+
+	got, err = s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get", err)
+	if got.Email != "new@example.com" || got.Admin || got.DisplayName != "Alice" {
+		t.Fatalf("unexpected partial update: %+v", got)
+	}
+
+	expectError(t, "unknown user", s.UpdateUser(ctx, "unknown", "", false, ""), userservice.ErrUserNotFound)
+}
+
+func testListUsers(t *testing.T, s userservice.UserService, e *env) {
+	users, err := s.ListUsers(context.Background())
+	expectNoError(t, "list", err)
+	if len(users) != 0 {
+		t.Fatalf("expected no users, got %d", len(users))
+	}
+
+	createUser(t, s, "alice", false)
+	createUser(t, s, "bob", true)
+
+	users, err = s.ListUsers(context.Background())
+	expectNoError(t, "list", err)
+
+	found := make(map[string]bool)
+	for _, u := range users {
+		found[u.Username] = true
+	}
+	if len(users) != 2 || !found["alice"] || !found["bob"] {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+}
+
+func testSetPassword(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	expectNoError(t, "set password", s.SetPassword(ctx, u.Id, "n3w-p4ssw0rd"))
+
+	_, err := s.Authenticate(ctx, "alice", password)
+	expectError(t, "old password", err, userservice.ErrInvalidCredentials)
+
+	_, err = s.Authenticate(ctx, "alice", "n3w-p4ssw0rd")
+	expectNoError(t, "new password", err)
+
+	expectError(t, "unknown user", s.SetPassword(ctx, "unknown", password), userservice.ErrUserNotFound)
+}
+
+func testSetPasswordHash(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("n3w-p4ssw0rd"), bcrypt.MinCost)
+	expectNoError(t, "hash", err)
+
+	expectNoError(t, "set password hash", s.SetPasswordHash(ctx, u.Id, string(hashed)))
+
+	_, err = s.Authenticate(ctx, "alice", password)
+	expectError(t, "old password", err, userservice.ErrInvalidCredentials)
+
+	_, err = s.Authenticate(ctx, "alice", "n3w-p4ssw0rd")
+	expectNoError(t, "new password", err)
+
+	if err := s.SetPasswordHash(ctx, u.Id, "n3w-p4ssw0rd"); err == nil {
+		t.Fatal("expected a clear password to be rejected")
+	}
+	_, err = s.Authenticate(ctx, "alice", "n3w-p4ssw0rd")
+	expectNoError(t, "password kept after a rejected hash", err)
+
+	expectError(t, "unknown user", s.SetPasswordHash(ctx, "unknown", string(hashed)), userservice.ErrUserNotFound)
+}
+
+func testSetActive(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	expectNoError(t, "deactivate", s.SetActive(ctx, u.Id, false))
+
+	got, err := s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get", err)
+	if got.Active {
+		t.Fatal("the user is still active")
+	}
+
+	expectNoError(t, "activate", s.SetActive(ctx, u.Id, true))
+
+	got, err = s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get", err)
+	if !got.Active {
+		t.Fatal("the user is still deactivated")
+	}
+
+	expectError(t, "unknown user", s.SetActive(ctx, "unknown", true), userservice.ErrUserNotFound)
+}
+
+func testDeleteUser(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	other := createUser(t, s, "bob", false)
+	token := login(t, s, u)
+	otherToken := login(t, s, other)
+
+	expectNoError(t, "delete", s.DeleteUser(ctx, u.Id))
+
+	_, err := s.GetUserById(ctx, u.Id)
+	expectError(t, "get deleted user", err, userservice.ErrUserNotFound)
+
+	_, _, err = s.VerifySessionToken(ctx, token)
+	expectError(t, "session of deleted user", err, userservice.ErrInvalidSession)
+
+	_, _, err = s.VerifySessionToken(ctx, otherToken)
+	expectNoError(t, "session of another user", err)
+
+	expectError(t, "delete twice", s.DeleteUser(ctx, u.Id), userservice.ErrUserNotFound)
+}
+
+func testAuthenticate(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	got, err := s.Authenticate(ctx, "alice", password)
+	expectNoError(t, "authenticate", err)
+	if got.Id != u.Id {
+		t.Fatalf("expected user %s, got %s", u.Id, got.Id)
+	}
+
+	_, err = s.Authenticate(ctx, "alice", "wrong")
+	expectError(t, "wrong password", err, userservice.ErrInvalidCredentials)
+
+	_, err = s.Authenticate(ctx, "bob", password)
+	expectError(t, "unknown user", err, userservice.ErrInvalidCredentials)
+
+	_, err = s.CreateUser(ctx, "carol", "carol@example.com", password, string(userservice.UserKindOIDC), false, "")
+	expectNoError(t, "create oidc user", err)
+	_, err = s.Authenticate(ctx, "carol", password)
+	expectError(t, "oidc user", err, userservice.ErrInvalidCredentials)
+
+	expectNoError(t, "deactivate", s.SetActive(ctx, u.Id, false))
+	_, err = s.Authenticate(ctx, "alice", password)
+	expectError(t, "deactivated user", err, userservice.ErrUserDeactivated)
+
+	// the password is checked first, not to leak which users exist
+	_, err = s.Authenticate(ctx, "alice", "wrong")
+	expectError(t, "deactivated user with a wrong password", err, userservice.ErrInvalidCredentials)
+}
+
+func testSessions(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", true)
+
+	before := e.clock.Now()
+	token := login(t, s, u)
+
+	claims, err := userservice.ParseSessionToken(e.key, token, e.clock.Now())
+	expectNoError(t, "parse token", err)
+	if claims.Subject != "alice" || !claims.Admin {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	sess, got, err := s.VerifySessionToken(ctx, token)
+	expectNoError(t, "verify", err)
+	if got.Id != u.Id || sess.Id != claims.SessionId {
+		t.Fatalf("unexpected session %+v of user %+v", sess, got)
+	}
+	if !sameTime(sess.Expires, before.Add(userservice.SessionDuration)) {
+		t.Fatalf("unexpected expiry: %s", sess.Expires)
+	}
+	if !sameTime(got.LastLogin, before) {
+		t.Fatalf("the last login was not updated: %s", got.LastLogin)
+	}
+
+	login(t, s, u)
+	sessions, err := s.ListSessions(ctx, u.Id)
+	expectNoError(t, "list sessions", err)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	if sessions[0].Expires.After(sessions[1].Expires) {
+		t.Fatalf("the sessions are not sorted by expiry: %+v", sessions)
+	}
+
+	sessions, err = s.ListSessions(ctx, "unknown")
+	expectNoError(t, "list sessions of an unknown user", err)
+	if len(sessions) != 0 {
+		t.Fatalf("expected no sessions, got %d", len(sessions))
+	}
+}
+
+func testSessionOfDeactivatedUser(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	token := login(t, s, u)
+
+	expectNoError(t, "deactivate", s.SetActive(ctx, u.Id, false))
+
+	_, _, err := s.VerifySessionToken(ctx, token)
+	expectError(t, "verify", err, userservice.ErrInvalidSession)
+	expectError(t, "verify", err, userservice.ErrUserDeactivated)
+
+	u, err = s.GetUserById(ctx, u.Id)
+	expectNoError(t, "get", err)
+	_, err = s.GenerateSessionToken(ctx, u)
+	expectError(t, "generate", err, userservice.ErrUserDeactivated)
+}
+
+func testInvalidTokens(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	_, _, err := s.VerifySessionToken(ctx, "not-a-token")
+	if err == nil {
+		t.Fatal("a garbage token was accepted")
+	}
+
+	// a token signed by another key
+	forged, err := userservice.SignSessionToken(newKey(t), u, "forged", e.clock.Now())
+	expectNoError(t, "sign", err)
+	if _, _, err := s.VerifySessionToken(ctx, forged); err == nil {
+		t.Fatal("a token signed with another key was accepted")
+	}
+
+	// a valid token of an unknown session
+	unknown, err := userservice.SignSessionToken(e.key, u, "unknown", e.clock.Now())
+	expectNoError(t, "sign", err)
+	_, _, err = s.VerifySessionToken(ctx, unknown)
+	expectError(t, "unknown session", err, userservice.ErrInvalidSession)
+
+	// an expired token
+	expired, err := userservice.SignSessionToken(e.key, u, "expired", e.clock.Now().Add(-2*userservice.SessionDuration))
+	expectNoError(t, "sign", err)
+	if _, _, err := s.VerifySessionToken(ctx, expired); err == nil {
+		t.Fatal("an expired token was accepted")
+	}
+}
+
+func testLogout(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	token := login(t, s, u)
+
+	expectNoError(t, "logout", s.LogoutFromToken(ctx, token))
+
+	_, _, err := s.VerifySessionToken(ctx, token)
+	expectError(t, "verify after logout", err, userservice.ErrInvalidSession)
+
+	expectNoError(t, "logout twice", s.LogoutFromToken(ctx, token))
+
+	if err := s.LogoutFromToken(ctx, "not-a-token"); err == nil {
+		t.Fatal("logging out of a garbage token succeeded")
+	}
+}
+
+func testRevokeSession(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	other := createUser(t, s, "bob", false)
+	token := login(t, s, u)
+
+	claims, err := userservice.ParseSessionToken(e.key, token, e.clock.Now())
+	expectNoError(t, "parse", err)
+
+	expectError(t, "session of another user", s.RevokeSession(ctx, other.Id, claims.SessionId), userservice.ErrSessionNotFound)
+	expectError(t, "unknown session", s.RevokeSession(ctx, u.Id, "unknown"), userservice.ErrSessionNotFound)
+
+	expectNoError(t, "revoke", s.RevokeSession(ctx, u.Id, claims.SessionId))
+
+	_, _, err = s.VerifySessionToken(ctx, token)
+	expectError(t, "verify after revocation", err, userservice.ErrInvalidSession)
+
+	expectError(t, "revoke twice", s.RevokeSession(ctx, u.Id, claims.SessionId), userservice.ErrSessionNotFound)
+}
+
+func testRevokeAllSessions(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	other := createUser(t, s, "bob", false)
+	login(t, s, u)
+	login(t, s, u)
+	otherToken := login(t, s, other)
+
+	count, err := s.RevokeAllSessions(ctx, u.Id)
+	expectNoError(t, "revoke the sessions of a user", err)
+	if count != 2 {
+		t.Fatalf("expected 2 revoked sessions, got %d", count)
+	}
+
+	_, _, err = s.VerifySessionToken(ctx, otherToken)
+	expectNoError(t, "session of another user", err)
+
+	login(t, s, u)
+	count, err = s.RevokeAllSessions(ctx, "")
+	expectNoError(t, "revoke every session", err)
+	if count != 2 {
+		t.Fatalf("expected 2 revoked sessions, got %d", count)
+	}
+
+	_, _, err = s.VerifySessionToken(ctx, otherToken)
+	expectError(t, "verify after revocation", err, userservice.ErrInvalidSession)
+}
+
+func testGeneratedIds(t *testing.T, s userservice.UserService, e *env) {
+	u := createUser(t, s, "alice", false)
+	if u.Id != e.ids.id(1) {
+		t.Fatalf("expected the user id %s, got %s", e.ids.id(1), u.Id)
+	}
+
+	claims, err := userservice.ParseSessionToken(e.key, login(t, s, u), e.clock.Now())
+	expectNoError(t, "parse", err)
+	if claims.SessionId != e.ids.id(2) || claims.ID != e.ids.id(2) {
+		t.Fatalf("expected the session id %s, got %s", e.ids.id(2), claims.SessionId)
+	}
+
+	sessions, err := s.ListSessions(context.Background(), u.Id)
+	expectNoError(t, "list sessions", err)
+	if len(sessions) != 1 || sessions[0].Id != e.ids.id(2) {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+}
+
+func testSessionExpiry(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	token := login(t, s, u)
+
+	listed := func() int {
+		t.Helper()
+
+		sessions, err := s.ListSessions(ctx, u.Id)
+		expectNoError(t, "list sessions", err)
+		return len(sessions)
+	}
+
+	e.clock.Advance(userservice.SessionDuration - time.Second)
+	_, _, err := s.VerifySessionToken(ctx, token)
+	expectNoError(t, "verify before the expiry", err)
+
+	// the expired sessions remain valid for the leeway, its end excluded
+	e.clock.Advance(userservice.ClockSkewLeeway)
+	_, _, err = s.VerifySessionToken(ctx, token)
+	expectNoError(t, "verify within the leeway", err)
+	if listed() != 1 {
+		t.Fatal("the session is no longer listed within the leeway")
+	}
+
+	e.clock.Advance(time.Second)
+	_, _, err = s.VerifySessionToken(ctx, token)
+	expectError(t, "verify at the end of the leeway", err, userservice.ErrInvalidSession)
+	if listed() != 0 {
+		t.Fatal("the expired session is still listed")
+	}
+
+	if err := s.LogoutFromToken(ctx, token); !errors.Is(err, userservice.ErrInvalidSession) {
+		t.Fatalf("logging out of an expired token: expected %q, got %v", userservice.ErrInvalidSession, err)
+	}
+}
+
+func testClockSkew(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+
+	// a token issued by an instance whose clock is ahead
+	e.clock.Advance(userservice.ClockSkewLeeway)
+	ahead := login(t, s, u)
+	e.clock.Advance(-userservice.ClockSkewLeeway)
+
+	_, _, err := s.VerifySessionToken(ctx, ahead)
+	expectNoError(t, "verify a token issued within the leeway", err)
+
+	e.clock.Advance(userservice.ClockSkewLeeway + time.Second)
+	tooFar := login(t, s, u)
+	e.clock.Advance(-userservice.ClockSkewLeeway - time.Second)
+
+	_, _, err = s.VerifySessionToken(ctx, tooFar)
+	expectError(t, "verify a token issued past the leeway", err, userservice.ErrInvalidSession)
+
+	// tokens without an expiry are never accepted
+	unbounded, err := jwt.NewWithClaims(jwt.SigningMethodES512, &userservice.TokenClaims{
+		SessionId: e.ids.id(2),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  u.Username,
+			IssuedAt: jwt.NewNumericDate(e.clock.Now()),
+		},
+	}).SignedString(e.key)
+	expectNoError(t, "sign", err)
+
+	_, _, err = s.VerifySessionToken(ctx, unbounded)
+	expectError(t, "verify a token without expiry", err, userservice.ErrInvalidSession)
+}
+
+func testSessionCleanup(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	u := createUser(t, s, "alice", false)
+	other := createUser(t, s, "bob", false)
+
+	expired, err := userservice.ParseSessionToken(e.key, login(t, s, u), e.clock.Now())
+	expectNoError(t, "parse", err)
+
+	e.clock.Advance(time.Minute)
+	login(t, s, other)
+
+	// the sessions are kept until the end of the leeway
+	e.clock.Advance(userservice.SessionDuration + userservice.ClockSkewLeeway - time.Minute - time.Second)
+	count, err := s.DeleteExpiredSessions(ctx)
+	expectNoError(t, "delete within the leeway", err)
+	if count != 0 {
+		t.Fatalf("%d sessions deleted within the leeway", count)
+	}
+
+	e.clock.Advance(time.Second)
+	count, err = s.DeleteExpiredSessions(ctx)
+	expectNoError(t, "delete", err)
+	if count != 1 {
+		t.Fatalf("expected 1 session deleted, got %d", count)
+	}
+
+	expectError(t, "revoke a deleted session", s.RevokeSession(ctx, u.Id, expired.SessionId), userservice.ErrSessionNotFound)
+
+	sessions, err := s.ListSessions(ctx, other.Id)
+	expectNoError(t, "list", err)
+	if len(sessions) != 1 {
+		t.Fatalf("expected the session of bob to be kept, got %d", len(sessions))
+	}
+}
+
+func testDeactivateStaleUsers(t *testing.T, s userservice.UserService, e *env) {
+	ctx := context.Background()
+	create := func(username string, kind userservice.UserKind) *userservice.User {
+		t.Helper()
+
+		u, err := s.CreateUser(ctx, username, username+"@example.com", "", string(kind), false, "")
+		expectNoError(t, "create "+username, err)
+		return u
+	}
+
+	idle := create("idle", userservice.UserKindOIDC)
+	stale := create("stale", userservice.UserKindOIDC)
+	recent := create("recent", userservice.UserKindOIDC)
+	local := create("local", userservice.UserKindLocal)
+	login(t, s, stale)
+
+	e.clock.Advance(time.Hour)
+	before := e.clock.Now()
+	login(t, s, recent)
+	e.clock.Advance(time.Hour)
+
+	count, err := s.DeactivateStaleUsers(ctx, userservice.UserKindOIDC, before)
+	expectNoError(t, "deactivate", err)
+	if count != 2 {
+		t.Fatalf("expected 2 users deactivated, got %d", count)
+	}
+
+	for _, c := range []struct {
+		user   *userservice.User
+		active bool
+	}{
+		{idle, false},
+		{stale, false},
+		{recent, true},
+		{local, true},
+	} {
+		got, err := s.GetUserById(ctx, c.user.Id)
+		expectNoError(t, "get "+c.user.Username, err)
+		if got.Active != c.active {
+			t.Fatalf("%s: expected active to be %v", c.user.Username, c.active)
+		}
+	}
+
+	count, err = s.DeactivateStaleUsers(ctx, userservice.UserKindOIDC, before)
+	expectNoError(t, "deactivate again", err)
+	if count != 0 {
+		t.Fatalf("the deactivated users were counted again, got %d", count)
+	}
+}
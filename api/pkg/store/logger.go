@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/logging"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// logger sends the logs of gorm to the logger of the context, so that
+// they carry the request id: the failed queries, bar the missing
+// records, and the queries slower than the threshold. Every query is
+// logged in the Info mode, set by gorm.DB.Debug.
+type logger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newLogger(slowThreshold time.Duration) gormlogger.Interface {
+	return &logger{
+		level:         gormlogger.Warn,
+		slowThreshold: slowThreshold,
+	}
+}
+
+func (l *logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &logger{
+		level:         level,
+		slowThreshold: l.slowThreshold,
+	}
+}
+
+func (l *logger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Info {
+		logging.FromContext(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *logger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Warn {
+		logging.FromContext(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *logger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Error {
+		logging.FromContext(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logging.FromContext(ctx).Error("query failed", "sql", sql, "rows", rows, "duration", elapsed, "error", err)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logging.FromContext(ctx).Warn("slow query", "sql", sql, "rows", rows, "duration", elapsed, "threshold", l.slowThreshold)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		logging.FromContext(ctx).Info("query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
+
+// ParamsFilter keeps the parameters, such as password hashes, out of the
+// logged queries, unless every query is logged for debugging
+func (l *logger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	if l.level >= gormlogger.Info {
+		return sql, params
+	}
+
+	return sql, nil
+}
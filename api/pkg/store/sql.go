@@ -1,43 +1,180 @@
 package store
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
-func NewSqlStore(driver string, connString string) (*gorm.DB, error) {
+const (
+	defaultStartupTimeout    = 30 * time.Second
+	defaultSQLiteJournalMode = "WAL"
+	defaultSQLiteBusyTimeout = 5 * time.Second
+
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
+)
+
+// NewSqlStore connects to the database, retrying with an exponential
+// backoff while it is not ready, for instance when it starts along with
+// the application, and configures the connection pool.
+func NewSqlStore(ctx context.Context, cfg config.StorageConfig, logger *slog.Logger) (*gorm.DB, error) {
+	// fail early on configuration errors rather than retrying them
+	if _, err := newDialector(cfg); err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.StartupTimeout
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
 	var db *gorm.DB
-	var err error
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		// a new dialector every time, they hold on to their connection
+		dialector, _ := newDialector(cfg)
 
-	switch driver {
-	case "sqlite", "sqlite3":
-		db, err = gorm.Open(sqlite.Open(connString), &gorm.Config{
+		// the failed attempts are logged below, not by gorm
+		var err error
+		db, err = gorm.Open(dialector, &gorm.Config{
 			NamingStrategy: schema.NamingStrategy{},
+			Logger:         gormlogger.Discard,
+			// turns the unique constraint violations into
+			// gorm.ErrDuplicatedKey, whatever the driver
+			TranslateError: true,
 		})
-		if err != nil {
-			return nil, err
+		if err == nil {
+			db.Logger = newLogger(cfg.SlowQueryThreshold)
+			break
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("could not connect to the database after %d attempts: %w", attempt, err)
 		}
-		if err = db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
-			return nil, err
+
+		logger.Warn("the database is not ready, retrying", "attempt", attempt, "retry_in", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
 		}
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
+
+func newDialector(cfg config.StorageConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "sqlite", "sqlite3":
+		return sqlite.Open(sqliteDSN(cfg)), nil
 	case "mysql":
-		db, err = gorm.Open(mysql.Open(connString), &gorm.Config{})
-		if err != nil {
-			return nil, err
+		dsn := cfg.URL
+		if cfg.StatementTimeout > 0 {
+			// only applies to the reads
+			dsn = withQueryParam(dsn, "max_execution_time", milliseconds(cfg.StatementTimeout))
 		}
+		return mysql.Open(dsn), nil
 	case "postgres":
-		db, err = gorm.Open(postgres.Open(connString), &gorm.Config{})
-		if err != nil {
-			return nil, err
+		dsn := cfg.URL
+		if cfg.StatementTimeout > 0 {
+			dsn = withPostgresParam(dsn, "statement_timeout", milliseconds(cfg.StatementTimeout))
 		}
+		return postgres.Open(dsn), nil
 	default:
-		return nil, fmt.Errorf("invalid driver provided: %s", driver)
+		return nil, fmt.Errorf("invalid driver provided: %s", cfg.Driver)
+	}
+}
+
+// sqliteDSN sets the pragmas through the connection string, so that they
+// apply to every connection of the pool, the ones already in the url
+// win.
+func sqliteDSN(cfg config.StorageConfig) string {
+	journalMode := cfg.SQLite.JournalMode
+	if journalMode == "" {
+		journalMode = defaultSQLiteJournalMode
 	}
 
-	return db, nil
+	busyTimeout := cfg.SQLite.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultSQLiteBusyTimeout
+	}
+
+	dsn := cfg.URL
+	dsn = withQueryParam(dsn, "_foreign_keys", "1")
+	dsn = withQueryParam(dsn, "_journal_mode", journalMode)
+	dsn = withQueryParam(dsn, "_busy_timeout", milliseconds(busyTimeout))
+	if cfg.SQLite.Synchronous != "" {
+		dsn = withQueryParam(dsn, "_synchronous", cfg.SQLite.Synchronous)
+	}
+
+	return dsn
+}
+
+// withQueryParam adds a parameter to the query string of a connection
+// string, unless it is already there
+func withQueryParam(dsn string, key string, value string) string {
+	_, query, found := strings.Cut(dsn, "?")
+	if found {
+		if params, err := url.ParseQuery(query); err == nil && params.Has(key) {
+			return dsn
+		}
+		return dsn + "&" + key + "=" + url.QueryEscape(value)
+	}
+
+	return dsn + "?" + key + "=" + url.QueryEscape(value)
+}
+
+// withPostgresParam adds a parameter to either form of postgres
+// connection strings, urls or key/value pairs
+func withPostgresParam(dsn string, key string, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return withQueryParam(dsn, key, value)
+	}
+
+	for _, field := range strings.Fields(dsn) {
+		if strings.HasPrefix(field, key+"=") {
+			return dsn
+		}
+	}
+
+	return strings.TrimSpace(dsn + " " + key + "=" + value)
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
 }
@@ -0,0 +1,35 @@
+// Package storetest opens the databases the tests run against
+package storetest
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/thomas-maurice/api/go-vue/pkg/config"
+	"github.com/thomas-maurice/api/go-vue/pkg/store"
+	"gorm.io/gorm"
+)
+
+// SQLite opens a new sqlite database in a temporary directory, closed
+// at the end of the test
+func SQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := store.NewSqlStore(context.Background(), config.StorageConfig{
+		Driver: "sqlite3",
+		URL:    filepath.Join(t.TempDir(), "db.sqlite3"),
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("could not open the database: %s", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("could not get the database: %s", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	return db
+}
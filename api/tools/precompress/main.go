@@ -0,0 +1,101 @@
+// precompress writes gzip and brotli variants of the compressible files
+// of the UI build, so that they get embedded alongside the originals and
+// served without compressing them on every request.
+//
+//	go run ./tools/precompress pkg/embeded/ui/dist
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// minSize is the size under which compressing is not worth it
+const minSize = 1024
+
+var compressible = map[string]bool{
+	".js":   true,
+	".mjs":  true,
+	".css":  true,
+	".json": true,
+	".svg":  true,
+	".txt":  true,
+	".map":  true,
+	".ico":  true,
+	".wasm": true,
+}
+
+func compress(path string, content []byte, ext string, newWriter func(io.Writer) io.WriteCloser) error {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	// only keep the variant if it actually saves something
+	if buf.Len() >= len(content) {
+		return os.Remove(path + ext)
+	}
+
+	return os.WriteFile(path+ext, buf.Bytes(), 0644)
+}
+
+func run(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !compressible[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if len(content) < minSize {
+			return nil
+		}
+
+		if err := compress(path, content, ".gz", func(w io.Writer) io.WriteCloser {
+			gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+			return gz
+		}); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := compress(path, content, ".br", func(w io.Writer) io.WriteCloser {
+			return brotli.NewWriterLevel(w, brotli.BestCompression)
+		}); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		fmt.Println("compressed", path)
+		return nil
+	})
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: precompress <directory>")
+		os.Exit(1)
+	}
+
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,24 +1,50 @@
 import { API_BASE_URL } from "@/defaults/client"
+import { runtimeConfig } from "@/defaults/runtime"
 import { ApiClient } from '@/gen/apiclient/src'
 import { useUserStore } from "@/stores/user"
 
+// sessionHeaders are the headers of the cookie session mode, they are
+// read on every request since the runtime configuration and the CSRF
+// token are only known once the client exists
+function sessionHeaders(store) {
+    if (!runtimeConfig.session_cookie) {
+        return {}
+    }
+
+    return {
+        "X-Session-Mode": "cookie",
+        "X-CSRF-Token": store.csrf_token,
+    }
+}
+
+function withSession(client, store) {
+    Object.defineProperty(client, "enableCookies", {
+        get: () => runtimeConfig.session_cookie,
+    })
+    Object.defineProperty(client, "defaultHeaders", {
+        get: () => sessionHeaders(store),
+    })
+
+    return client
+}
+
 var store = useUserStore()
-var client = new ApiClient(API_BASE_URL + "/api")
+var client = new ApiClient(API_BASE_URL + "/api/v1")
 if (store.token !== "") {
     client.authentications["jwt"].apiKey = store.token
 }
-client.defaultHeaders = {}
+withSession(client, store)
 
 function getDefaultClient() {
     var store = useUserStore()
-    var client = new ApiClient(API_BASE_URL + "/api")
+    var client = new ApiClient(API_BASE_URL + "/api/v1")
     if (store.token !== "") {
         client.authentications["jwt"].apiKey = store.token
     }
-    client.defaultHeaders = {}
 
-    return client
+    return withSession(client, store)
 }
 
 export const DefaultClient = client
 export const GetDefaultClient = getDefaultClient
+export const SessionHeaders = sessionHeaders
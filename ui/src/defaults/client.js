@@ -10,4 +10,10 @@ function getApiBaseUrl() {
   return `${protocol}//${hostname}${port ? `:${port}` : ''}`;
 }
 
-export const API_BASE_URL = getApiBaseUrl()
\ No newline at end of file
+export let API_BASE_URL = getApiBaseUrl()
+
+// setApiBaseUrl overrides the base url with the one from the runtime
+// configuration
+export function setApiBaseUrl(url) {
+  API_BASE_URL = url.replace(/\/+$/, '')
+}
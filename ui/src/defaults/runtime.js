@@ -0,0 +1,35 @@
+import { API_BASE_URL, setApiBaseUrl } from '@/defaults/client'
+
+// Defaults used until the configuration is fetched, or when the server
+// does not expose one
+export const runtimeConfig = {
+  api_base_url: '',
+  login_methods: {
+    password: true,
+    oidc: false,
+  },
+  oidc_providers: [],
+  branding: {
+    title: document.title,
+  },
+  session_cookie: false,
+}
+
+// loadRuntimeConfig fetches /config.json from the server, it has to be
+// called before the API client is created.
+export async function loadRuntimeConfig() {
+  try {
+    const resp = await fetch(`${API_BASE_URL}/config.json`, { cache: 'no-cache' })
+    if (!resp.ok) {
+      throw new Error(`unexpected status ${resp.status}`)
+    }
+    Object.assign(runtimeConfig, await resp.json())
+  } catch (error) {
+    console.log(`could not load the runtime configuration: ${error.message}`)
+  }
+
+  if (runtimeConfig.api_base_url) {
+    setApiBaseUrl(runtimeConfig.api_base_url)
+  }
+  document.title = runtimeConfig.branding.title
+}
@@ -11,10 +11,13 @@ pinia.use(piniaPluginPersistedState)
 
 import App from './App.vue'
 import router from './router'
+import { loadRuntimeConfig } from './defaults/runtime'
 
-const app = createApp(App)
+loadRuntimeConfig().then(() => {
+  const app = createApp(App)
 
-app.use(pinia)
-app.use(router)
+  app.use(pinia)
+  app.use(router)
 
-app.mount('#app')
+  app.mount('#app')
+})
@@ -29,6 +29,14 @@ const router = createRouter({
         requiresAuth: false,
       }
     },
+    {
+      path: '/device',
+      name: 'device',
+      component: () => import('../views/DeviceView.vue'),
+      meta: {
+        requiresAuth: true,
+      }
+    },
     {
       path: '/profile',
       name: 'profile-self',
@@ -43,8 +51,17 @@ const router = createRouter({
 router.beforeEach(async (to, from) => {
   const userStore = useUserStore()
   if (!userStore.logged_in && to.meta.requiresAuth) {
-    return { name: 'login' }
+    return { name: 'login', query: { redirect: to.fullPath } }
   }
 })
 
+// loginRedirect returns where to go once logged in, only paths of the UI
+// are honoured
+export function loginRedirect(redirect) {
+  if (typeof redirect === 'string' && redirect.startsWith('/') && !redirect.startsWith('//')) {
+    return redirect
+  }
+  return '/'
+}
+
 export default router
@@ -8,6 +8,7 @@ export const useUserStore = defineStore('user', {
     return { 
         username: "<none>",
         token: "",
+        csrf_token: "",
         logged_in: false,
         decoded: {
           admin: false,
@@ -20,16 +21,23 @@ export const useUserStore = defineStore('user', {
       this.logged_in = false
       this.username = "<none>"
       this.token = ""
+      this.csrf_token = ""
       this.decoded = {
         admin: false,
         exp: -1,
       }
     },
-    log_in(username, token) {
+    log_in(username, token, csrf_token = "") {
         this.logged_in = true
         this.username = username
         this.token = token
-        this.decoded = jwtDecode(this.token)
+        this.csrf_token = csrf_token
+        // the token of a cookie session is not readable, the server
+        // answers 401 once it expires
+        this.decoded = token ? jwtDecode(token) : {
+          admin: false,
+          exp: Math.floor(Date.now() / 1000) + 3600,
+        }
     },
     logged_in() {
       if(this.decoded.exp && this.decoded.exp > Math.floor(Date.now() / 1000)) {
@@ -18,4 +18,8 @@ export default defineConfig({
     },
   },
   base: '/', // Set base path for the app
+  html: {
+    // replaced by a per-request nonce when the Go server serves index.html
+    cspNonce: '__CSP_NONCE__',
+  },
 })
\ No newline at end of file